@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	"github.com/containifyci/temporal-worker/pkg/artifacts"
+	"github.com/containifyci/temporal-worker/pkg/workflows/engineci"
+	"go.temporal.io/sdk/client"
+)
+
+// runDownloadArtifactsMode waits for an Engine-CI job and extracts its artifacts into outputDir
+// The artifact store is configured with the same ARTIFACT_STORE variables as the worker
+func runDownloadArtifactsMode(c client.Client, jobWorkflowID, outputDir string) {
+	ctx := context.Background()
+
+	var details engineci.EngineCIDetails
+	if err := c.GetWorkflow(ctx, jobWorkflowID, "").Get(ctx, &details); err != nil {
+		log.Fatalln("Unable to get Engine-CI job result", err)
+	}
+	if len(details.Artifacts) == 0 {
+		log.Printf("Engine-CI job %s has no artifacts", jobWorkflowID)
+		return
+	}
+
+	store, err := artifacts.NewStoreFromEnv()
+	if err != nil {
+		log.Fatalln("Unable to open artifact store", err)
+	}
+
+	for _, ref := range details.Artifacts {
+		r, err := store.Get(ctx, ref.Key)
+		if err != nil {
+			log.Fatalln("Unable to download artifacts", err)
+		}
+		files, err := artifacts.ExtractArchive(r, outputDir)
+		_ = r.Close()
+		if err != nil {
+			log.Fatalln("Unable to extract artifacts", err)
+		}
+		log.Printf("Downloaded %s: %d files to %s", ref.Key, len(files), outputDir)
+	}
+}
@@ -6,9 +6,15 @@ import (
 	"fmt"
 	"log"
 	"strings"
+	"time"
 
+	"github.com/containifyci/temporal-worker/pkg/codec"
+	"github.com/containifyci/temporal-worker/pkg/connection"
+	"github.com/containifyci/temporal-worker/pkg/tracing"
+	"github.com/containifyci/temporal-worker/pkg/versioning"
 	"github.com/containifyci/temporal-worker/pkg/workflows/engineci"
 	"github.com/containifyci/temporal-worker/pkg/workflows/github"
+	enumspb "go.temporal.io/api/enums/v1"
 	"go.temporal.io/sdk/client"
 )
 
@@ -23,91 +29,171 @@ func (a *arrayFlags) Set(value string) error {
 	return nil
 }
 
+// listFlag is a comma separated list
+type listFlag []string
+
+func (l *listFlag) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *listFlag) Set(value string) error {
+	*l = strings.Split(value, ",")
+	return nil
+}
+
+// envFlags collects repeated key=value flags
+type envFlags map[string]string
+
+func (e envFlags) String() string {
+	pairs := make([]string, 0, len(e))
+	for key, value := range e {
+		pairs = append(pairs, key+"="+value)
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (e envFlags) Set(value string) error {
+	key, val, ok := strings.Cut(value, "=")
+	if !ok {
+		return fmt.Errorf("expected key=value, got %q", value)
+	}
+	e[key] = val
+	return nil
+}
+
 func main() {
 	// Define flags
 	var (
 		githubPR  bool
 		engineCI  bool
-		repo      string
-		ref       string
-		argsStr   string
-		envFlags  arrayFlags
+		download  string
+		outputDir string
+		pipeline  string
+		promote   string
+		deploy    string
 	)
+	// The Engine-CI job is bound to the flags field by field
+	job := engineci.EngineCIWorkflowInput{Env: map[string]string{}}
 
 	flag.BoolVar(&githubPR, "github-pr", false, "Run GitHub PR workflow mode")
 	flag.BoolVar(&engineCI, "engine-ci", false, "Run Engine-CI workflow mode")
-	flag.StringVar(&repo, "repo", "", "Git repository URL (for Engine-CI mode)")
-	flag.StringVar(&ref, "ref", "main", "Git reference/branch (for Engine-CI mode)")
-	flag.StringVar(&argsStr, "args", "run,-t,all", "Comma-separated Engine-CI arguments (for Engine-CI mode)")
-	flag.Var(&envFlags, "env", "Environment variables in key=value format (repeatable, for Engine-CI mode)")
+	flag.StringVar(&job.GitRepoURL, "repo", "", "Git repository URL (for Engine-CI mode)")
+	flag.StringVar(&job.GitRef, "ref", "main", "Git reference/branch (for Engine-CI mode)")
+	flag.Var((*listFlag)(&job.EngineArgs), "args", "Comma-separated runner arguments, defaults to run,-t,all for engine-ci (for Engine-CI mode)")
+	flag.StringVar((*string)(&job.Runner.Kind), "runner", string(engineci.RunnerEngineCI), "Runner: engine-ci, command or go-test (for Engine-CI mode)")
+	flag.StringVar(&job.Runner.Command, "command", "", "Command of the command runner, must be allowed by the worker (for Engine-CI mode)")
+	flag.Var(envFlags(job.Env), "env", "Environment variables in key=value format, values may be secret://NAME references (repeatable, for Engine-CI mode)")
+	flag.StringVar(&job.Cache.Key, "cache-key", "", "Cache key, defaults to the repository (for Engine-CI mode)")
+	flag.BoolVar(&job.Cache.GoModCache, "cache-gomod", false, "Reuse a managed Go module cache (for Engine-CI mode)")
+	flag.BoolVar(&job.Cache.GoBuildCache, "cache-gobuild", false, "Reuse a managed Go build cache (for Engine-CI mode)")
+	flag.Var((*arrayFlags)(&job.Cache.Directories), "cache-dir", "Named cache directory (repeatable, for Engine-CI mode)")
+	flag.StringVar(&job.BaseRef, "base", "", "Base revision for path filtering (for Engine-CI mode)")
+	flag.Var((*arrayFlags)(&job.Paths.Include), "include", "Path glob that triggers the job (repeatable, for Engine-CI mode)")
+	flag.Var((*arrayFlags)(&job.Paths.Exclude), "exclude", "Path glob that never triggers the job (repeatable, for Engine-CI mode)")
+	flag.Var((*arrayFlags)(&job.Reports), "report", "Glob of test report files (JUnit XML, go test -json) in the workspace (repeatable, for Engine-CI mode)")
+	flag.Var((*arrayFlags)(&job.Labels), "label", "Label the worker must advertise, e.g. docker, arm64 or mem-large (repeatable, for Engine-CI mode)")
+	flag.Var((*arrayFlags)(&job.Artifacts), "artifact", "Glob of files kept as job artifacts (repeatable, for Engine-CI mode)")
+	flag.StringVar(&download, "download-artifacts", "", "Download the artifacts of the Engine-CI job with this job workflow ID")
+	flag.StringVar(&pipeline, "pipeline", "", "Run the Engine-CI pipeline defined in this YAML file (with --repo, --ref and --base)")
+	flag.StringVar(&outputDir, "output", ".", "Directory downloaded artifacts are extracted to")
+	flag.StringVar(&job.CallbackURL, "callback-url", "", "URL that receives the job result once the job finished (for Engine-CI mode)")
+	flag.StringVar(&job.CallbackSecret, "callback-secret", "", "secret://NAME reference of the HMAC secret signing the callback, resolved on the worker (for Engine-CI mode)")
+	flag.StringVar(&promote, "promote-build-id", "", "Make this build ID the current version of the worker deployment, new workflows start on it")
+	flag.StringVar(&deploy, "deployment", versioning.DefaultDeploymentName, "Worker deployment of --promote-build-id")
+	flag.BoolVar(&job.Force, "force", false, "Run even if the commit already passed with the same arguments (for Engine-CI mode)")
 
 	flag.Parse()
 
 	// Create Temporal client
-	c, err := client.Dial(client.Options{})
+	clientOptions := client.Options{}
+	if err := connection.ConfigureClient(&clientOptions); err != nil {
+		log.Fatalln("Invalid Temporal connection config", err)
+	}
+	if _, err := codec.ConfigureClient(&clientOptions); err != nil {
+		log.Fatalln("Invalid payload encryption config", err)
+	}
+	// OTEL_TRACES_EXPORTER enables tracing, the workflow spans continue the span of the client call
+	traceSettings := tracing.SettingsFromEnv()
+	shutdownTracing, err := tracing.Setup(context.Background(), traceSettings)
+	if err != nil {
+		log.Fatalln("Invalid tracing config", err)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			log.Println("Failed to flush spans", err)
+		}
+	}()
+	if traceSettings.Enabled() {
+		if err := tracing.Configure(&clientOptions); err != nil {
+			log.Fatalln("Invalid tracing config", err)
+		}
+	}
+	c, err := client.Dial(clientOptions)
 	if err != nil {
 		log.Fatalln("Unable to create client", err)
 	}
 	defer c.Close()
 
 	// Determine mode
-	if engineCI {
-		runEngineCIMode(c, repo, ref, argsStr, envFlags)
+	if promote != "" {
+		runPromoteMode(c, deploy, promote)
+	} else if download != "" {
+		runDownloadArtifactsMode(c, download, outputDir)
+	} else if pipeline != "" {
+		runPipelineMode(c, pipeline, job.GitRepoURL, job.GitRef, job.BaseRef)
+	} else if engineCI {
+		runEngineCIMode(c, job)
 	} else if githubPR {
 		runGitHubPRMode(c)
 	} else {
-		log.Println("No mode specified. Use --github-pr or --engine-ci")
+		log.Println("No mode specified. Use --github-pr, --engine-ci, --pipeline, --download-artifacts or --promote-build-id")
 		flag.Usage()
 	}
 }
 
-func runEngineCIMode(c client.Client, repo, ref, argsStr string, envFlags arrayFlags) {
-	if repo == "" {
+func runEngineCIMode(c client.Client, input engineci.EngineCIWorkflowInput) {
+	if input.GitRepoURL == "" {
 		log.Fatalln("--repo is required for Engine-CI mode")
 	}
-
-	// Parse args
-	args := strings.Split(argsStr, ",")
-
-	// Parse environment variables
-	env := make(map[string]string)
-	for _, e := range envFlags {
-		parts := strings.SplitN(e, "=", 2)
-		if len(parts) == 2 {
-			env[parts[0]] = parts[1]
-		}
+	if len(input.EngineArgs) == 0 && input.Runner.Kind == engineci.RunnerEngineCI {
+		input.EngineArgs = []string{"run", "-t", "all"}
 	}
 
-	// Extract repo name for workflow ID
-	repoName := engineci.SanitizeRepoName(repo)
-	workflowID := fmt.Sprintf("engine-ci-%s", repoName)
-
-	// Create workflow input
-	input := engineci.EngineCIWorkflowInput{
-		GitRepoURL: repo,
-		GitRef:     ref,
-		RepoName:   repoName,
-		EngineArgs: args,
-		Env:        env,
-	}
+	// Host, owner and repo identify the queue, so same-named repositories of different owners don't share it
+	input.RepoName = engineci.ParseRepoIdentity(input.GitRepoURL).DisplayName()
+	workflowID := engineci.RepoWorkflowID(input.GitRepoURL)
 
-	// Start or signal workflow
-	we, err := c.SignalWithStartWorkflow(
-		context.Background(),
-		workflowID,
-		engineci.EngineCISignal,
-		input,
+	// Start the queue workflow if needed and submit the job; invalid jobs are rejected synchronously
+	startOp := c.NewWithStartWorkflowOperation(
 		client.StartWorkflowOptions{
-			ID:        workflowID,
-			TaskQueue: "engine-ci-queue",
+			ID:                       workflowID,
+			TaskQueue:                engineci.TaskQueue,
+			WorkflowIDConflictPolicy: enumspb.WORKFLOW_ID_CONFLICT_POLICY_USE_EXISTING,
 		},
 		engineci.EngineCIRepoWorkflow,
 	)
+	handle, err := c.UpdateWithStartWorkflow(context.Background(), client.UpdateWithStartWorkflowOptions{
+		StartWorkflowOperation: startOp,
+		UpdateOptions: client.UpdateWorkflowOptions{
+			WorkflowID:   workflowID,
+			UpdateName:   engineci.EngineCISubmitUpdate,
+			Args:         []interface{}{input},
+			WaitForStage: client.WorkflowUpdateStageCompleted,
+		},
+	})
 	if err != nil {
-		log.Fatalln("Unable to start or signal workflow", err)
+		log.Fatalln("Unable to submit Engine-CI job", err)
+	}
+
+	var submission engineci.EngineCISubmission
+	if err := handle.Get(context.Background(), &submission); err != nil {
+		log.Fatalln("Engine-CI job rejected", err)
 	}
 
-	log.Printf("Engine-CI workflow started/signaled: WorkflowID=%s, RunID=%s", we.GetID(), we.GetRunID())
+	log.Printf("Engine-CI job submitted: WorkflowID=%s, JobID=%s, JobWorkflowID=%s, QueuePosition=%d",
+		workflowID, submission.JobID, submission.WorkflowID, submission.QueuePosition)
 }
 
 func runGitHubPRMode(c client.Client) {
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/containifyci/temporal-worker/pkg/workflows/engineci"
+	"go.temporal.io/sdk/client"
+	"gopkg.in/yaml.v3"
+)
+
+// pipelineFile is the YAML definition of an Engine-CI pipeline
+//
+//	fail-fast: true
+//	stages:
+//	  - name: lint
+//	    args: [run, -t, lint]
+//	  - name: test
+//	    runner: go-test
+//	    runs-on: [docker, arm64]
+//	  - name: publish
+//	    needs: [lint, test]
+//	    when: {refs: [main, "release/*"]}
+type pipelineFile struct {
+	Env         map[string]string `yaml:"env"`
+	FailFast    bool              `yaml:"fail-fast"`    //nolint:tagliatelle
+	MaxParallel int               `yaml:"max-parallel"` //nolint:tagliatelle
+	Stages      []pipelineStage   `yaml:"stages"`
+}
+
+type pipelineStage struct {
+	Name            string            `yaml:"name"`
+	Needs           []string          `yaml:"needs"`
+	When            pipelineCondition `yaml:"when"`
+	ContinueOnError bool              `yaml:"continue-on-error"` //nolint:tagliatelle
+	RunsOn          []string          `yaml:"runs-on"`           //nolint:tagliatelle
+	Runner          string            `yaml:"runner"`
+	Command         string            `yaml:"command"`
+	Args            []string          `yaml:"args"`
+	Env             map[string]string `yaml:"env"`
+	Include         []string          `yaml:"include"`
+	Exclude         []string          `yaml:"exclude"`
+	Reports         []string          `yaml:"reports"`
+	Artifacts       []string          `yaml:"artifacts"`
+	Force           bool              `yaml:"force"`
+}
+
+type pipelineCondition struct {
+	Refs []string `yaml:"refs"`
+	If   string   `yaml:"if"`
+}
+
+// loadPipeline reads a pipeline definition and converts it into the workflow input
+func loadPipeline(path, repo, ref, baseRef string) (engineci.PipelineInput, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return engineci.PipelineInput{}, err
+	}
+	var file pipelineFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return engineci.PipelineInput{}, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	input := engineci.PipelineInput{
+		GitRepoURL:  repo,
+		GitRef:      ref,
+		BaseRef:     baseRef,
+		Env:         file.Env,
+		FailFast:    file.FailFast,
+		MaxParallel: file.MaxParallel,
+	}
+	for _, s := range file.Stages {
+		runner := engineci.RunnerSpec{Kind: engineci.RunnerKind(s.Runner), Command: s.Command}
+		args := s.Args
+		if len(args) == 0 && (runner.Kind == "" || runner.Kind == engineci.RunnerEngineCI) {
+			args = []string{"run", "-t", "all"}
+		}
+		input.Stages = append(input.Stages, engineci.PipelineStage{
+			Name:            s.Name,
+			Needs:           s.Needs,
+			When:            engineci.StageCondition{Refs: s.When.Refs, If: engineci.StageTrigger(s.When.If)},
+			ContinueOnError: s.ContinueOnError,
+			Job: engineci.EngineCIWorkflowInput{
+				EngineArgs: args,
+				Runner:     runner,
+				Env:        s.Env,
+				Paths:      engineci.PathFilter{Include: s.Include, Exclude: s.Exclude},
+				Reports:    s.Reports,
+				Artifacts:  s.Artifacts,
+				Force:      s.Force,
+				Labels:     s.RunsOn,
+			},
+		})
+	}
+	return input, engineci.ValidatePipeline(input)
+}
+
+// runPipelineMode starts an Engine-CI pipeline and prints the stage results once it finished
+func runPipelineMode(c client.Client, path, repo, ref, baseRef string) {
+	if repo == "" {
+		log.Fatalln("--repo is required for pipeline mode")
+	}
+	input, err := loadPipeline(path, repo, ref, baseRef)
+	if err != nil {
+		log.Fatalln("Invalid pipeline", err)
+	}
+
+	input.PipelineID = strconv.FormatInt(time.Now().Unix(), 10)
+	workflowID := engineci.PipelineWorkflowID(repo, input.PipelineID)
+
+	ctx := context.Background()
+	run, err := c.ExecuteWorkflow(ctx, client.StartWorkflowOptions{
+		ID:        workflowID,
+		TaskQueue: engineci.TaskQueue,
+	}, engineci.EngineCIPipelineWorkflow, input)
+	if err != nil {
+		log.Fatalln("Unable to start Engine-CI pipeline", err)
+	}
+	log.Printf("Engine-CI pipeline started: WorkflowID=%s, RunID=%s", run.GetID(), run.GetRunID())
+
+	var result engineci.PipelineResult
+	if err := run.Get(ctx, &result); err != nil {
+		log.Fatalln("Engine-CI pipeline failed", err)
+	}
+	for _, stage := range result.Stages {
+		log.Printf("Stage %s: %s %s", stage.Name, stage.Status, stage.Reason)
+	}
+	log.Printf("Engine-CI pipeline %s: %s", result.PipelineID, result.Status)
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	"github.com/containifyci/temporal-worker/pkg/versioning"
+	"go.temporal.io/sdk/client"
+)
+
+// runPromoteMode makes the build ID the current version of the worker deployment, new workflows start on that build
+// while pinned ones finish on the build they started on
+func runPromoteMode(c client.Client, deployment, buildID string) {
+	previous, err := versioning.Promote(context.Background(), c, deployment, buildID)
+	if err != nil {
+		log.Fatalln("Unable to promote build ID", err)
+	}
+	if previous == buildID {
+		log.Printf("Build ID %s is already the current version of deployment %s", buildID, deployment)
+		return
+	}
+	if previous == "" {
+		previous = "unversioned workers"
+	}
+	log.Printf("Promoted build ID %s of deployment %s, previously %s", buildID, deployment, previous)
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/containifyci/temporal-worker/pkg/codec"
+	"github.com/containifyci/temporal-worker/pkg/logging"
+)
+
+var (
+	version = "dev"
+	commit  = "none"
+	date    = "unknown"
+)
+
+// The codec server decrypts payloads for the Temporal UI and CLI, it shares the TEMPORAL_CODEC_* keys
+// with the workers. Configure the UI's codec endpoint as http(s)://<host>:<port> with passAccessToken and OIDC
+// validation, or pass the static token
+func main() {
+	fmt.Printf("temporal-codec-server %s, commit %s, built at %s\n", version, commit, date)
+
+	logSettings := logging.Settings{Format: os.Getenv("LOG_FORMAT"), Level: os.Getenv("LOG_LEVEL")}
+	if logSettings.Format == "" {
+		logSettings.Format = logging.FormatPretty
+	}
+	if logSettings.Level == "" {
+		logSettings.Level = "info"
+	}
+	logger, _, err := logging.New(os.Stdout, logSettings)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	slog.SetDefault(logger)
+
+	c, err := codec.FromEnv()
+	if err != nil {
+		logger.Error("Invalid payload encryption config", "error", err)
+		os.Exit(1)
+	}
+	if c == nil {
+		logger.Error("No encryption key configured, set TEMPORAL_CODEC_KEYS or TEMPORAL_CODEC_KEYS_FILE")
+		os.Exit(1)
+	}
+
+	var origins []string
+	for _, origin := range strings.Split(os.Getenv("TEMPORAL_CODEC_ALLOWED_ORIGINS"), ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			origins = append(origins, origin)
+		}
+	}
+	opts := codec.HandlerOptions{
+		AuthToken:      os.Getenv("TEMPORAL_CODEC_AUTH_TOKEN"),
+		AllowedOrigins: origins,
+	}
+	// The Temporal UI forwards the user's access token, validate it against the identity provider
+	if issuer := os.Getenv("TEMPORAL_CODEC_OIDC_ISSUER"); issuer != "" {
+		opts.OIDC = &codec.OIDCOptions{Issuer: issuer, Audience: os.Getenv("TEMPORAL_CODEC_OIDC_AUDIENCE")}
+	}
+	handler, err := codec.NewHandler(c, opts)
+	if err != nil {
+		logger.Error("Unable to create codec server", "error", err)
+		os.Exit(1)
+	}
+
+	addr := os.Getenv("TEMPORAL_CODEC_LISTEN")
+	if addr == "" {
+		addr = ":8081"
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.Handle("/", handler)
+
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	logger.Info("Codec server listening", "addr", addr, "keyID", c.KeyID(), "allowedOrigins", origins)
+	if err := server.ListenAndServe(); err != nil {
+		logger.Error("Codec server stopped", "error", err)
+		os.Exit(1)
+	}
+}
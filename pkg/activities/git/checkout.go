@@ -8,6 +8,8 @@ import (
 	"strings"
 
 	"go.temporal.io/sdk/activity"
+
+	"github.com/containifyci/temporal-worker/pkg/tracing"
 )
 
 // GitCommitError is a custom error type for git commit failures
@@ -40,7 +42,7 @@ func GitCheckoutBranch(ctx context.Context, i GitCheckoutBranchInputs) error {
 	cmd := exec.Command("git", args...)
 	cmd.Dir = i.RepoPath
 
-	output, err := cmd.CombinedOutput()
+	output, err := tracing.CombinedOutput(ctx, cmd)
 	logger.Info("git checkout command", "command", cmd.String(), "output", string(output))
 	if err != nil {
 		return fmt.Errorf("git checkout failed: %w\nOutput: %s", err, string(output))
@@ -61,7 +63,7 @@ func GitResetToMain(ctx context.Context, i GitResetToMainInputs) error {
 	// Checkout main branch
 	cmd := exec.Command("git", "checkout", "main")
 	cmd.Dir = i.RepoPath
-	output, err := cmd.CombinedOutput()
+	output, err := tracing.CombinedOutput(ctx, cmd)
 	logger.Info("git checkout main", "output", string(output))
 	if err != nil {
 		return fmt.Errorf("failed to checkout main: %w\nOutput: %s", err, string(output))
@@ -70,7 +72,7 @@ func GitResetToMain(ctx context.Context, i GitResetToMainInputs) error {
 	// Hard reset to clean state
 	cmd = exec.Command("git", "reset", "--hard", "HEAD")
 	cmd.Dir = i.RepoPath
-	output, err = cmd.CombinedOutput()
+	output, err = tracing.CombinedOutput(ctx, cmd)
 	logger.Info("git reset", "output", string(output))
 	if err != nil {
 		return fmt.Errorf("git reset failed: %w\nOutput: %s", err, string(output))
@@ -79,7 +81,7 @@ func GitResetToMain(ctx context.Context, i GitResetToMainInputs) error {
 	// Clean untracked files
 	cmd = exec.Command("git", "clean", "-fd")
 	cmd.Dir = i.RepoPath
-	output, err = cmd.CombinedOutput()
+	output, err = tracing.CombinedOutput(ctx, cmd)
 	logger.Info("git clean", "output", string(output))
 	if err != nil {
 		return fmt.Errorf("git clean failed: %w\nOutput: %s", err, string(output))
@@ -103,7 +105,7 @@ func CommitAndPush(ctx context.Context, i CommitAndPushInputs) (string, error) {
 	// git add --all
 	cmd := exec.Command("git", "add", "--all")
 	cmd.Dir = i.RepoPath
-	output, err := cmd.CombinedOutput()
+	output, err := tracing.CombinedOutput(ctx, cmd)
 	if err != nil {
 		return "", fmt.Errorf("failed to run git add: %w %s", err, string(output))
 	}
@@ -111,13 +113,13 @@ func CommitAndPush(ctx context.Context, i CommitAndPushInputs) (string, error) {
 	// git status (for logging)
 	cmd = exec.Command("git", "status")
 	cmd.Dir = i.RepoPath
-	statusOutput, _ := cmd.CombinedOutput()
+	statusOutput, _ := tracing.CombinedOutput(ctx, cmd)
 	logger.Info("git status", "output", string(statusOutput))
 
 	// git commit -m
 	cmd = exec.Command("git", "commit", "-m", i.CommitMsg)
 	cmd.Dir = i.RepoPath
-	output, err = cmd.CombinedOutput()
+	output, err = tracing.CombinedOutput(ctx, cmd)
 	logger.Info("running git commit", "command", cmd.String(), "output", string(output))
 	if err != nil {
 		return "", &GitCommitError{Err: fmt.Errorf("failed to run git commit. error: %s %s", err, string(output))}
@@ -126,7 +128,7 @@ func CommitAndPush(ctx context.Context, i CommitAndPushInputs) (string, error) {
 	// git push
 	cmd = exec.Command("git", "push", "origin", i.BranchName)
 	cmd.Dir = i.RepoPath
-	output, err = cmd.CombinedOutput()
+	output, err = tracing.CombinedOutput(ctx, cmd)
 	logger.Info("running git push", "command", cmd.String(), "output", string(output))
 	if err != nil {
 		return "", fmt.Errorf("failed to run git push. error: %s %s", err, string(output))
@@ -166,7 +168,7 @@ func CloneRepoForUpgrade(ctx context.Context, organization, repository, branchNa
 	}
 
 	cmd := exec.Command("git", args...)
-	output, err := cmd.CombinedOutput()
+	output, err := tracing.CombinedOutput(ctx, cmd)
 	if err != nil {
 		_ = os.RemoveAll(tempDir)
 		return "", fmt.Errorf("failed to clone repository: %w\nOutput: %s", err, string(output))
@@ -183,7 +185,7 @@ func CloneRepoForUpgrade(ctx context.Context, organization, repository, branchNa
 		for _, command := range gitConfigCommands {
 			cmd := exec.Command("git", command...)
 			cmd.Dir = tempDir
-			output, err := cmd.CombinedOutput()
+			output, err := tracing.CombinedOutput(ctx, cmd)
 			if err != nil {
 				_ = os.RemoveAll(tempDir)
 				return "", fmt.Errorf("failed to run git config. error: %s", string(output))
@@ -198,7 +200,7 @@ func CloneRepoForUpgrade(ctx context.Context, organization, repository, branchNa
 func CheckGitHasChanges(ctx context.Context, workDir string) bool {
 	cmd := exec.Command("git", "status", "--porcelain")
 	cmd.Dir = workDir
-	output, err := cmd.Output()
+	output, err := tracing.Output(ctx, cmd)
 	if err != nil {
 		return false
 	}
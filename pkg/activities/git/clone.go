@@ -7,6 +7,8 @@ import (
 	"os/exec"
 
 	"go.temporal.io/sdk/activity"
+
+	"github.com/containifyci/temporal-worker/pkg/tracing"
 )
 
 // CloneRepo clones a git repository to the specified directory
@@ -31,7 +33,7 @@ func CloneRepo(ctx context.Context, repoURL, ref, workDir string) (string, error
 	// cause the command to hang in non-interactive environments.
 	cmd := exec.Command("git", "clone", "--branch", ref, repoURL, workDir)
 	cmd.Env = append(os.Environ(), "GIT_CONFIG_GLOBAL=/dev/null", "GIT_TERMINAL_PROMPT=0")
-	output, err := cmd.CombinedOutput()
+	output, err := tracing.CombinedOutput(ctx, cmd)
 	if err != nil {
 		return "", fmt.Errorf("git clone failed: %v: %s", err, string(output))
 	}
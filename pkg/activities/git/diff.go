@@ -0,0 +1,74 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"go.temporal.io/sdk/activity"
+
+	"github.com/containifyci/temporal-worker/pkg/tracing"
+)
+
+// ChangedFilesInputs contains parameters for listing the files changed between two revisions
+type ChangedFilesInputs struct {
+	RepoPath string
+	Base     string
+	Head     string // defaults to HEAD
+}
+
+// ChangedFiles lists the files changed between the merge base of Base and Head and Head
+// Base may be a commit SHA, a tag or a branch name; it is fetched from origin when it is
+// not part of the local clone
+func ChangedFiles(ctx context.Context, i ChangedFilesInputs) ([]string, error) {
+	logger := activity.GetLogger(ctx)
+	logger.Info("listing changed files", "repoPath", i.RepoPath, "base", i.Base, "head", i.Head)
+
+	head := i.Head
+	if head == "" {
+		head = "HEAD"
+	}
+
+	base, err := resolveBase(ctx, i.RepoPath, i.Base)
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := exec.CommandContext(ctx, "git", "diff", "--name-only", base+"..."+head)
+	cmd.Dir = i.RepoPath
+	output, err := tracing.CombinedOutput(ctx, cmd)
+	if err != nil {
+		return nil, fmt.Errorf("git diff failed: %w\nOutput: %s", err, string(output))
+	}
+
+	var files []string
+	for _, line := range strings.Split(string(output), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			files = append(files, line)
+		}
+	}
+
+	logger.Info("changed files listed", "count", len(files))
+	return files, nil
+}
+
+// resolveBase returns a revision for base that exists in the local clone
+func resolveBase(ctx context.Context, repoPath, base string) (string, error) {
+	for _, candidate := range []string{base, "origin/" + base} {
+		cmd := exec.CommandContext(ctx, "git", "rev-parse", "--verify", "--quiet", candidate+"^{commit}")
+		cmd.Dir = repoPath
+		if err := tracing.Run(ctx, cmd); err == nil {
+			return candidate, nil
+		}
+	}
+
+	// A base starting with "-" is never read as an option
+	cmd := exec.CommandContext(ctx, "git", "fetch", "--end-of-options", "origin", base)
+	cmd.Dir = repoPath
+	output, err := tracing.CombinedOutput(ctx, cmd)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch base revision %s: %w\nOutput: %s", base, err, string(output))
+	}
+	return "FETCH_HEAD", nil
+}
@@ -0,0 +1,98 @@
+package git
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/testsuite"
+)
+
+// initTestRepo creates a local repository with an initial commit and returns its path
+func initTestRepo(t *testing.T) string {
+	dir := t.TempDir()
+	runGit(t, dir, "init", "--initial-branch", "main")
+	runGit(t, dir, "config", "user.email", "test@example.com")
+	runGit(t, dir, "config", "user.name", "Test")
+	writeFile(t, dir, "README.md", "readme")
+	writeFile(t, dir, "cmd/main.go", "package main")
+	runGit(t, dir, "add", "--all")
+	runGit(t, dir, "commit", "-m", "initial")
+	return dir
+}
+
+func runGit(t *testing.T, dir string, args ...string) string {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GIT_CONFIG_GLOBAL=/dev/null")
+	output, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(output))
+	return string(output)
+}
+
+func writeFile(t *testing.T, dir, name, content string) {
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+}
+
+func TestChangedFiles(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found in PATH, skipping test")
+	}
+
+	repo := initTestRepo(t)
+	runGit(t, repo, "tag", "base")
+
+	writeFile(t, repo, "docs/guide.md", "guide")
+	writeFile(t, repo, "cmd/main.go", "package main // changed")
+	runGit(t, repo, "add", "--all")
+	runGit(t, repo, "commit", "-m", "change")
+
+	env := (&testsuite.WorkflowTestSuite{}).NewTestActivityEnvironment()
+	env.RegisterActivity(ChangedFiles)
+
+	val, err := env.ExecuteActivity(ChangedFiles, ChangedFilesInputs{RepoPath: repo, Base: "base"})
+	require.NoError(t, err)
+
+	var files []string
+	require.NoError(t, val.Get(&files))
+	assert.Equal(t, []string{"cmd/main.go", "docs/guide.md"}, files)
+}
+
+func TestChangedFiles_UnknownBase(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found in PATH, skipping test")
+	}
+
+	repo := initTestRepo(t)
+
+	env := (&testsuite.WorkflowTestSuite{}).NewTestActivityEnvironment()
+	env.RegisterActivity(ChangedFiles)
+
+	_, err := env.ExecuteActivity(ChangedFiles, ChangedFilesInputs{RepoPath: repo, Base: "does-not-exist"})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to fetch base revision")
+}
+
+func TestChangedFiles_BaseReadAsOption(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found in PATH, skipping test")
+	}
+
+	origin := initTestRepo(t)
+	repo := t.TempDir()
+	runGit(t, repo, "clone", origin, ".")
+
+	env := (&testsuite.WorkflowTestSuite{}).NewTestActivityEnvironment()
+	env.RegisterActivity(ChangedFiles)
+
+	// The base is fetched as a refspec, not run as an option
+	marker := filepath.Join(t.TempDir(), "pwned")
+	_, err := env.ExecuteActivity(ChangedFiles, ChangedFilesInputs{RepoPath: repo, Base: "--upload-pack=touch " + marker})
+	assert.ErrorContains(t, err, "failed to fetch base revision")
+	assert.NoFileExists(t, marker)
+}
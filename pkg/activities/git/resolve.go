@@ -0,0 +1,80 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	"go.temporal.io/sdk/activity"
+
+	"github.com/containifyci/temporal-worker/pkg/tracing"
+)
+
+var commitSHAPattern = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+// ResolveRef resolves a branch or tag of a remote repository to its commit SHA without cloning it
+// A ref that already is a full commit SHA is returned as is
+func ResolveRef(ctx context.Context, repoURL, ref string) (string, error) {
+	logger := activity.GetLogger(ctx)
+	logger.Info("ResolveRef started", "repo", repoURL, "ref", ref)
+
+	if commitSHAPattern.MatchString(ref) {
+		return ref, nil
+	}
+
+	// Same environment as CloneRepo so HTTPS URLs are not rewritten and git never prompts, a ref starting
+	// with "-" is never read as an option
+	cmd := exec.CommandContext(ctx, "git", "ls-remote", "--end-of-options", repoURL, ref, "refs/tags/"+ref+"^{}")
+	cmd.Env = append(os.Environ(), "GIT_CONFIG_GLOBAL=/dev/null", "GIT_TERMINAL_PROMPT=0")
+	output, err := tracing.CombinedOutput(ctx, cmd)
+	if err != nil {
+		return "", fmt.Errorf("git ls-remote failed: %v: %s", err, string(output))
+	}
+
+	sha := parseLsRemote(string(output), ref)
+	if sha == "" {
+		return "", fmt.Errorf("ref %s not found in %s", ref, repoURL)
+	}
+
+	logger.Info("Ref resolved", "ref", ref, "sha", sha)
+	return sha, nil
+}
+
+// parseLsRemote picks the commit for ref from git ls-remote output
+// Peeled tags (refs/tags/x^{}) win over the tag object, branches win over other refs
+func parseLsRemote(output, ref string) string {
+	candidates := map[string]string{}
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		candidates[fields[1]] = fields[0]
+	}
+
+	for _, name := range []string{
+		"refs/tags/" + ref + "^{}",
+		"refs/heads/" + ref,
+		"refs/tags/" + ref,
+		ref,
+	} {
+		if sha, ok := candidates[name]; ok {
+			return sha
+		}
+	}
+	return ""
+}
+
+// HeadCommit returns the commit SHA checked out in the repository
+func HeadCommit(ctx context.Context, repoPath string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", "rev-parse", "HEAD")
+	cmd.Dir = repoPath
+	output, err := tracing.Output(ctx, cmd)
+	if err != nil {
+		return "", fmt.Errorf("git rev-parse failed: %w", err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
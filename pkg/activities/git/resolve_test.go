@@ -0,0 +1,61 @@
+package git
+
+import (
+	"context"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/testsuite"
+)
+
+func TestParseLsRemote(t *testing.T) {
+	output := strings.Join([]string{
+		"1111111111111111111111111111111111111111\trefs/heads/main",
+		"2222222222222222222222222222222222222222\trefs/tags/v1.0.0",
+		"3333333333333333333333333333333333333333\trefs/tags/v1.0.0^{}",
+		"4444444444444444444444444444444444444444\trefs/heads/feature/main",
+	}, "\n")
+
+	assert.Equal(t, "1111111111111111111111111111111111111111", parseLsRemote(output, "main"))
+	assert.Equal(t, "3333333333333333333333333333333333333333", parseLsRemote(output, "v1.0.0"))
+	assert.Equal(t, "", parseLsRemote(output, "missing"))
+}
+
+func TestResolveRef(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found in PATH, skipping test")
+	}
+
+	repo := initTestRepo(t)
+	runGit(t, repo, "tag", "-a", "v1.0.0", "-m", "release")
+	head := strings.TrimSpace(runGit(t, repo, "rev-parse", "HEAD"))
+
+	env := (&testsuite.WorkflowTestSuite{}).NewTestActivityEnvironment()
+	env.RegisterActivity(ResolveRef)
+
+	for _, ref := range []string{"main", "v1.0.0", head} {
+		val, err := env.ExecuteActivity(ResolveRef, repo, ref)
+		require.NoError(t, err)
+
+		var sha string
+		require.NoError(t, val.Get(&sha))
+		assert.Equal(t, head, sha, ref)
+	}
+
+	_, err := env.ExecuteActivity(ResolveRef, repo, "missing")
+	assert.Error(t, err)
+
+	// A ref starting with "-" is looked up, not run as an option
+	marker := filepath.Join(t.TempDir(), "pwned")
+	_, err = env.ExecuteActivity(ResolveRef, repo, "--upload-pack=touch "+marker)
+	assert.ErrorContains(t, err, "not found")
+	assert.NoFileExists(t, marker)
+
+	sha, err := HeadCommit(context.Background(), repo)
+	require.NoError(t, err)
+	assert.Equal(t, head, sha)
+}
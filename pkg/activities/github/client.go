@@ -5,11 +5,14 @@ import (
 
 	github "github.com/google/go-github/v89/github"
 	"golang.org/x/oauth2"
+
+	"github.com/containifyci/temporal-worker/pkg/metrics"
+	"github.com/containifyci/temporal-worker/pkg/tracing"
 )
 
 // NewGitHubClient creates an authenticated GitHub client using a personal access token
 func NewGitHubClient(token string) *github.Client {
-	client, err := github.NewClient(github.WithAuthToken(token))
+	client, err := github.NewClient(github.WithAuthToken(token), github.WithTransport(metrics.GitHubTransport(tracing.Transport(nil))))
 	if err != nil {
 		// WithAuthToken should never return an error, but handle it defensively
 		panic("unexpected error creating GitHub client: " + err.Error())
@@ -25,7 +28,7 @@ func NewGitHubClientWithHTTP(token string, httpClient *http.Client) *github.Clie
 	// Create a transport that wraps the provided HTTP client's transport with OAuth2
 	transport := &oauth2.Transport{
 		Source: ts,
-		Base:   httpClient.Transport,
+		Base:   metrics.GitHubTransport(tracing.Transport(httpClient.Transport)),
 	}
 	oauthClient := &http.Client{
 		Transport: transport,
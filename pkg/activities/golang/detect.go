@@ -12,6 +12,9 @@ import (
 
 	"go.temporal.io/sdk/activity"
 	"go.temporal.io/sdk/log"
+
+	"github.com/containifyci/temporal-worker/pkg/metrics"
+	"github.com/containifyci/temporal-worker/pkg/tracing"
 )
 
 // DetectMajorUpgradesInputs contains the parameters for detecting major upgrades
@@ -100,7 +103,7 @@ func DetectMajorUpgrades(ctx context.Context, i DetectMajorUpgradesInputs) (Dete
 
 	cmd.Env = env
 
-	output, err := cmd.CombinedOutput()
+	output, err := tracing.CombinedOutput(ctx, cmd)
 	if err != nil {
 		if len(env) > 0 {
 			logger.Error("Detecting major upgrades", "env", env)
@@ -136,6 +139,7 @@ func DetectMajorUpgrades(ctx context.Context, i DetectMajorUpgradesInputs) (Dete
 	}
 
 	logger.Info("Major upgrades detected", "count", len(upgrades), "moduleErrors", len(moduleErrors))
+	metrics.UpgradesDetected.Add(float64(len(upgrades)))
 
 	return DetectMajorUpgradesOutputs{
 		Upgrades:     upgrades,
@@ -244,7 +248,7 @@ func goList(ctx context.Context, module string) (*goListModule, error) {
 	cmd.Stdout = &out
 	cmd.Stderr = nil
 
-	if err := cmd.Run(); err != nil {
+	if err := tracing.Run(ctx, cmd); err != nil {
 		return nil, fmt.Errorf("failed to run go list: %w", err)
 	}
 
@@ -11,6 +11,7 @@ import (
 	"go.uber.org/zap"
 
 	githubactivity "github.com/containifyci/temporal-worker/pkg/activities/github"
+	"github.com/containifyci/temporal-worker/pkg/metrics"
 )
 
 // CountOpenMajorUpgradePRsInputs contains parameters for counting open major upgrade PRs
@@ -196,6 +197,7 @@ func PRCreate(ctx context.Context, i PRCreateInputs) (PRCreateOutputs, error) {
 	// Get PR number/ID
 	prID := p.GetNumber()
 	logger.Info("created PR", "pr", prID)
+	metrics.PullRequestsCreated.WithLabelValues(i.Org).Inc()
 
 	out := PRCreateOutputs{
 		Branch:   i.Branch,
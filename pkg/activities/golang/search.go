@@ -9,8 +9,14 @@ import (
 	"os"
 
 	"go.temporal.io/sdk/activity"
+
+	"github.com/containifyci/temporal-worker/pkg/metrics"
+	"github.com/containifyci/temporal-worker/pkg/tracing"
 )
 
+// githubHTTPClient sends the search requests, counted as GitHub API calls and traced
+var githubHTTPClient = &http.Client{Transport: metrics.GitHubTransport(tracing.Transport(nil))}
+
 // SearchGoRepositoriesInputs contains the parameters for searching Go repositories
 type SearchGoRepositoriesInputs struct {
 	Organization string
@@ -62,7 +68,7 @@ func SearchGoRepositories(ctx context.Context, i SearchGoRepositoriesInputs) (Se
 	query := fmt.Sprintf("org:%s+language:%s", i.Organization, i.Language)
 
 	for {
-		searchResp, err := fetchRepositoryPage(ctx, query, page, i.PerPage, githubToken, http.DefaultClient)
+		searchResp, err := fetchRepositoryPage(ctx, query, page, i.PerPage, githubToken, githubHTTPClient)
 		if err != nil {
 			return SearchGoRepositoriesOutputs{}, err
 		}
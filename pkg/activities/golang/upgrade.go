@@ -10,6 +10,8 @@ import (
 	"strings"
 
 	"go.temporal.io/sdk/activity"
+
+	"github.com/containifyci/temporal-worker/pkg/tracing"
 )
 
 // UpgradeDependencyInputs contains the parameters for upgrading a dependency
@@ -79,7 +81,7 @@ func UpgradeDependency(ctx context.Context, i UpgradeDependencyInputs) (UpgradeD
 	}
 	modCmd.Env = env
 	modCmd.Dir = workDir
-	modOutput, err := modCmd.CombinedOutput()
+	modOutput, err := tracing.CombinedOutput(ctx, modCmd)
 
 	modError := ""
 	if err != nil {
@@ -172,7 +174,7 @@ func goGet(ctx context.Context, workDir string, modulePath ModulePath) (string,
 	}
 	getCmd.Env = env
 	getCmd.Dir = workDir
-	getOutput, err := getCmd.CombinedOutput()
+	getOutput, err := tracing.CombinedOutput(ctx, getCmd)
 	if err != nil {
 		return string(getOutput), fmt.Errorf("go get failed: %w\nOutput: %s", err, string(getOutput))
 	}
@@ -184,7 +186,7 @@ func goGet(ctx context.Context, workDir string, modulePath ModulePath) (string,
 func checkGitHasChanges(ctx context.Context, workDir string) bool {
 	cmd := exec.CommandContext(ctx, "git", "status", "--porcelain")
 	cmd.Dir = workDir
-	output, err := cmd.Output()
+	output, err := tracing.Output(ctx, cmd)
 	if err != nil {
 		return false
 	}
@@ -0,0 +1,52 @@
+package artifacts
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArchiveRoundTrip(t *testing.T) {
+	src := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(src, "bin"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(src, "bin", "app"), []byte("binary"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(src, "coverage.out"), []byte("mode: set"), 0o644))
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteArchive(&buf, src, []string{"bin/app", "coverage.out"}))
+
+	dst := t.TempDir()
+	files, err := ExtractArchive(&buf, dst)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"bin/app", "coverage.out"}, files)
+
+	data, err := os.ReadFile(filepath.Join(dst, "bin", "app"))
+	require.NoError(t, err)
+	assert.Equal(t, "binary", string(data))
+	info, err := os.Stat(filepath.Join(dst, "bin", "app"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o755), info.Mode().Perm())
+}
+
+func TestExtractArchive_RejectsTraversal(t *testing.T) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "../evil", Mode: 0o644, Size: 1, Typeflag: tar.TypeReg}))
+	_, err := tw.Write([]byte("x"))
+	require.NoError(t, err)
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+
+	dst := t.TempDir()
+	_, err = ExtractArchive(&buf, dst)
+	assert.ErrorContains(t, err, "escapes")
+	_, err = os.Stat(filepath.Join(filepath.Dir(dst), "evil"))
+	assert.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,221 @@
+package artifacts
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testCredentials = Credentials{AccessKeyID: "test-key", SecretAccessKey: "test-secret"}
+
+// fakeS3 is a minimal in-memory stand-in for an S3-compatible service
+// It verifies the signature of every request by signing a copy with the shared secret
+type fakeS3 struct {
+	t        *testing.T
+	mu       sync.Mutex
+	objects  map[string][]byte
+	modified map[string]time.Time
+	pageSize int
+}
+
+func newFakeS3(t *testing.T) (*fakeS3, *httptest.Server) {
+	f := &fakeS3{t: t, objects: map[string][]byte{}, modified: map[string]time.Time{}, pageSize: 1000}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	return f, srv
+}
+
+func (f *fakeS3) verify(r *http.Request) bool {
+	date, err := time.Parse(sigV4TimeFormat, r.Header.Get("X-Amz-Date"))
+	if err != nil {
+		return false
+	}
+	clone := r.Clone(context.Background())
+	clone.Header = http.Header{}
+	for name, values := range r.Header {
+		if lower := strings.ToLower(name); lower == "range" || lower == "content-type" || strings.HasPrefix(lower, "x-amz-") {
+			clone.Header[name] = values
+		}
+	}
+	signV4(clone, testCredentials, "us-east-1", "s3", r.Header.Get("X-Amz-Content-Sha256"), date)
+	return clone.Header.Get("Authorization") == r.Header.Get("Authorization")
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !f.verify(r) {
+		http.Error(w, "<Error><Code>SignatureDoesNotMatch</Code></Error>", http.StatusForbidden)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if bucket != "artifacts" {
+		http.Error(w, "<Error><Code>NoSuchBucket</Code></Error>", http.StatusNotFound)
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodPut:
+		data, _ := io.ReadAll(r.Body)
+		f.objects[key] = data
+		f.modified[key] = time.Now()
+	case r.Method == http.MethodGet && key == "":
+		f.list(w, r)
+	case r.Method == http.MethodGet:
+		data, ok := f.objects[key]
+		if !ok {
+			http.Error(w, "<Error><Code>NoSuchKey</Code></Error>", http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write(data)
+	case r.Method == http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (f *fakeS3) list(w http.ResponseWriter, r *http.Request) {
+	prefix := r.URL.Query().Get("prefix")
+	var keys []string
+	for key := range f.objects {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	start := 0
+	if token := r.URL.Query().Get("continuation-token"); token != "" {
+		_, _ = fmt.Sscanf(token, "%d", &start)
+	}
+	end := min(start+f.pageSize, len(keys))
+
+	var b bytes.Buffer
+	b.WriteString("<ListBucketResult>")
+	for _, key := range keys[start:end] {
+		fmt.Fprintf(&b, "<Contents><Key>%s</Key><Size>%d</Size><LastModified>%s</LastModified></Contents>",
+			xmlEscape(key), len(f.objects[key]), f.modified[key].UTC().Format(time.RFC3339))
+	}
+	if end < len(keys) {
+		fmt.Fprintf(&b, "<IsTruncated>true</IsTruncated><NextContinuationToken>%d</NextContinuationToken>", end)
+	}
+	b.WriteString("</ListBucketResult>")
+	_, _ = w.Write(b.Bytes())
+}
+
+func xmlEscape(s string) string {
+	var b bytes.Buffer
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+func newTestS3Store(t *testing.T, endpoint, prefix string) *S3Store {
+	store, err := NewS3Store(S3Config{
+		Endpoint:    endpoint,
+		Region:      "us-east-1",
+		Bucket:      "artifacts",
+		Prefix:      prefix,
+		Credentials: testCredentials,
+	})
+	require.NoError(t, err)
+	return store
+}
+
+func TestS3Store_RoundTrip(t *testing.T) {
+	fake, srv := newFakeS3(t)
+	store := newTestS3Store(t, srv.URL, "ci")
+	ctx := context.Background()
+
+	data := []byte("binary content")
+	require.NoError(t, store.Put(ctx, "engine-ci/github.com/a/api/1/artifacts.tar.gz", bytes.NewReader(data), int64(len(data))))
+	assert.Contains(t, fake.objects, "ci/engine-ci/github.com/a/api/1/artifacts.tar.gz")
+
+	r, err := store.Get(ctx, "engine-ci/github.com/a/api/1/artifacts.tar.gz")
+	require.NoError(t, err)
+	got, err := io.ReadAll(r)
+	require.NoError(t, err)
+	require.NoError(t, r.Close())
+	assert.Equal(t, data, got)
+
+	objects, err := store.List(ctx, "engine-ci/")
+	require.NoError(t, err)
+	require.Len(t, objects, 1)
+	assert.Equal(t, "engine-ci/github.com/a/api/1/artifacts.tar.gz", objects[0].Key)
+	assert.Equal(t, int64(len(data)), objects[0].Size)
+
+	require.NoError(t, store.Delete(ctx, "engine-ci/github.com/a/api/1/artifacts.tar.gz"))
+	_, err = store.Get(ctx, "engine-ci/github.com/a/api/1/artifacts.tar.gz")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestS3Store_ListPaginates(t *testing.T) {
+	fake, srv := newFakeS3(t)
+	fake.pageSize = 2
+	store := newTestS3Store(t, srv.URL, "")
+	ctx := context.Background()
+
+	for i := 0; i < 5; i++ {
+		require.NoError(t, store.Put(ctx, fmt.Sprintf("engine-ci/job-%d/artifacts.tar.gz", i), strings.NewReader("x"), 1))
+	}
+
+	objects, err := store.List(ctx, "engine-ci/")
+	require.NoError(t, err)
+	assert.Len(t, objects, 5)
+}
+
+func TestS3Store_RejectsBadSignature(t *testing.T) {
+	_, srv := newFakeS3(t)
+	store, err := NewS3Store(S3Config{
+		Endpoint:    srv.URL,
+		Bucket:      "artifacts",
+		Region:      "us-east-1",
+		Credentials: Credentials{AccessKeyID: "test-key", SecretAccessKey: "wrong"},
+	})
+	require.NoError(t, err)
+
+	err = store.Put(context.Background(), "a/b", strings.NewReader("x"), 1)
+	assert.ErrorContains(t, err, "HTTP 403")
+}
+
+func TestS3Store_Prune(t *testing.T) {
+	fake, srv := newFakeS3(t)
+	store := newTestS3Store(t, srv.URL, "")
+	ctx := context.Background()
+
+	require.NoError(t, store.Put(ctx, "engine-ci/old/artifacts.tar.gz", strings.NewReader("x"), 1))
+	require.NoError(t, store.Put(ctx, "engine-ci/new/artifacts.tar.gz", strings.NewReader("x"), 1))
+	fake.modified["engine-ci/old/artifacts.tar.gz"] = time.Now().Add(-48 * time.Hour)
+
+	deleted, err := Prune(ctx, store, "engine-ci/", 24*time.Hour, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"engine-ci/old/artifacts.tar.gz"}, deleted)
+	assert.NotContains(t, fake.objects, "engine-ci/old/artifacts.tar.gz")
+	assert.Contains(t, fake.objects, "engine-ci/new/artifacts.tar.gz")
+}
+
+func TestNewS3Store_Validation(t *testing.T) {
+	_, err := NewS3Store(S3Config{Endpoint: "http://localhost:9000", Credentials: testCredentials})
+	assert.ErrorContains(t, err, "bucket")
+
+	_, err = NewS3Store(S3Config{Endpoint: "http://localhost:9000", Bucket: "b"})
+	assert.ErrorContains(t, err, "credentials")
+
+	_, err = NewS3Store(S3Config{Endpoint: "localhost", Bucket: "b", Credentials: testCredentials})
+	assert.ErrorContains(t, err, "endpoint")
+}
@@ -0,0 +1,111 @@
+// Package artifacts stores build outputs of CI jobs outside of the job workspace
+package artifacts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ErrNotFound is returned when an object does not exist in the store
+var ErrNotFound = errors.New("artifact not found")
+
+// Object describes a stored artifact
+type Object struct {
+	Key      string
+	Size     int64
+	Modified time.Time
+}
+
+// Store is a key/value store for artifact archives
+// Keys are slash separated paths without `.` or `..` segments
+type Store interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+	List(ctx context.Context, prefix string) ([]Object, error)
+}
+
+// DefaultRetention is how long artifacts are kept when ARTIFACT_RETENTION is not set
+const DefaultRetention = 7 * 24 * time.Hour
+
+// RetentionFromEnv returns the retention configured in ARTIFACT_RETENTION (a Go duration)
+func RetentionFromEnv() time.Duration {
+	if d, err := time.ParseDuration(os.Getenv("ARTIFACT_RETENTION")); err == nil && d > 0 {
+		return d
+	}
+	return DefaultRetention
+}
+
+// NewStoreFromEnv creates the store configured in ARTIFACT_STORE
+//   - empty: filesystem store in $TMPDIR/engine-ci-artifacts
+//   - `file:///var/lib/artifacts` or a plain path: filesystem store
+//   - `s3://bucket/prefix`: S3-compatible store, see S3ConfigFromEnv
+func NewStoreFromEnv() (Store, error) {
+	return NewStore(os.Getenv("ARTIFACT_STORE"))
+}
+
+// NewStore creates the store at location, see NewStoreFromEnv for the supported locations
+func NewStore(location string) (Store, error) {
+	if location == "" {
+		return NewFilesystemStore(filepath.Join(os.TempDir(), "engine-ci-artifacts")), nil
+	}
+
+	u, err := url.Parse(location)
+	if err != nil {
+		return nil, fmt.Errorf("invalid artifact store %q: %w", location, err)
+	}
+	switch u.Scheme {
+	case "", "file":
+		return NewFilesystemStore(u.Path), nil
+	case "s3":
+		cfg := S3ConfigFromEnv()
+		cfg.Bucket = u.Host
+		cfg.Prefix = strings.Trim(u.Path, "/")
+		return NewS3Store(cfg)
+	default:
+		return nil, fmt.Errorf("unsupported artifact store scheme %q", u.Scheme)
+	}
+}
+
+// Prune deletes all objects below prefix that are older than retention and returns their keys
+func Prune(ctx context.Context, store Store, prefix string, retention time.Duration, now time.Time) ([]string, error) {
+	objects, err := store.List(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	var deleted []string
+	var errs []error
+	for _, obj := range objects {
+		if now.Sub(obj.Modified) <= retention {
+			continue
+		}
+		if err := store.Delete(ctx, obj.Key); err != nil {
+			errs = append(errs, fmt.Errorf("failed to delete %s: %w", obj.Key, err))
+			continue
+		}
+		deleted = append(deleted, obj.Key)
+	}
+	return deleted, errors.Join(errs...)
+}
+
+// validateKey rejects keys that could escape the store root
+func validateKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || path.Clean(key) != key {
+		return fmt.Errorf("invalid artifact key %q", key)
+	}
+	for _, segment := range strings.Split(key, "/") {
+		if segment == ".." || segment == "." {
+			return fmt.Errorf("invalid artifact key %q", key)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package artifacts
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilesystemStore_RoundTrip(t *testing.T) {
+	root := t.TempDir()
+	store := NewFilesystemStore(root)
+	ctx := context.Background()
+
+	require.NoError(t, store.Put(ctx, "engine-ci/job-1/artifacts.tar.gz", strings.NewReader("content"), 7))
+
+	r, err := store.Get(ctx, "engine-ci/job-1/artifacts.tar.gz")
+	require.NoError(t, err)
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+	require.NoError(t, r.Close())
+	assert.Equal(t, "content", string(data))
+
+	objects, err := store.List(ctx, "engine-ci/")
+	require.NoError(t, err)
+	require.Len(t, objects, 1)
+	assert.Equal(t, "engine-ci/job-1/artifacts.tar.gz", objects[0].Key)
+	assert.Equal(t, int64(7), objects[0].Size)
+
+	require.NoError(t, store.Delete(ctx, "engine-ci/job-1/artifacts.tar.gz"))
+	_, err = store.Get(ctx, "engine-ci/job-1/artifacts.tar.gz")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	// Empty directories are removed with the last object, the root is kept
+	_, err = os.Stat(filepath.Join(root, "engine-ci"))
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(root)
+	assert.NoError(t, err)
+}
+
+func TestFilesystemStore_ListMissingRoot(t *testing.T) {
+	store := NewFilesystemStore(filepath.Join(t.TempDir(), "missing"))
+
+	objects, err := store.List(context.Background(), "")
+	assert.NoError(t, err)
+	assert.Empty(t, objects)
+}
+
+func TestFilesystemStore_Prune(t *testing.T) {
+	root := t.TempDir()
+	store := NewFilesystemStore(root)
+	ctx := context.Background()
+
+	require.NoError(t, store.Put(ctx, "engine-ci/old/artifacts.tar.gz", strings.NewReader("x"), 1))
+	require.NoError(t, store.Put(ctx, "engine-ci/new/artifacts.tar.gz", strings.NewReader("x"), 1))
+	past := time.Now().Add(-48 * time.Hour)
+	require.NoError(t, os.Chtimes(filepath.Join(root, "engine-ci", "old", "artifacts.tar.gz"), past, past))
+
+	deleted, err := Prune(ctx, store, "engine-ci/", 24*time.Hour, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"engine-ci/old/artifacts.tar.gz"}, deleted)
+
+	objects, err := store.List(ctx, "")
+	require.NoError(t, err)
+	require.Len(t, objects, 1)
+	assert.Equal(t, "engine-ci/new/artifacts.tar.gz", objects[0].Key)
+}
+
+func TestValidateKey(t *testing.T) {
+	for _, key := range []string{"", "/abs", "a/../../etc/passwd", "../x", "a//b", "a/./b"} {
+		assert.Error(t, validateKey(key), key)
+	}
+	for _, key := range []string{"a", "engine-ci/github.com/a/api/1/artifacts.tar.gz"} {
+		assert.NoError(t, validateKey(key), key)
+	}
+}
+
+func TestNewStoreFromEnv(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("ARTIFACT_STORE", dir)
+	store, err := NewStoreFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, dir, store.(*FilesystemStore).Root)
+
+	t.Setenv("ARTIFACT_STORE", "s3://bucket/ci/artifacts")
+	t.Setenv("ARTIFACT_S3_ENDPOINT", "http://localhost:9000")
+	t.Setenv("AWS_ACCESS_KEY_ID", "key")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	store, err = NewStoreFromEnv()
+	require.NoError(t, err)
+	s3 := store.(*S3Store)
+	assert.Equal(t, "bucket", s3.cfg.Bucket)
+	assert.Equal(t, "ci/artifacts", s3.cfg.Prefix)
+
+	t.Setenv("ARTIFACT_STORE", "gs://bucket")
+	_, err = NewStoreFromEnv()
+	assert.ErrorContains(t, err, "unsupported")
+}
@@ -0,0 +1,111 @@
+// Package autoupdate installs new releases of the worker in the background
+// A release is downloaded and verified next to the executable, installed once the worker drained and started again
+// by the Supervisor running the worker. The previous binary is kept and the supervisor restores it when the release is
+// not ready within its grace period, also when it crashes or can't be executed, so a broken release never stays
+// installed
+package autoupdate
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// VerifyTimeout bounds the run of a downloaded binary that checks it starts on this host
+var VerifyTimeout = 30 * time.Second
+
+// Updater checks for new releases of the running version
+type Updater struct {
+	Source      Source
+	Version     string // running version
+	Executable  string
+	Interval    time.Duration // between release checks
+	GracePeriod time.Duration // a new release has to be ready within
+	Logger      *slog.Logger
+}
+
+// Update is a downloaded and verified release waiting to be installed
+type Update struct {
+	Release Release
+	updater *Updater
+}
+
+// Run checks for a newer release every interval, failed checks are logged and retried at the next one
+// It returns the first update found or the error of ctx
+func (u *Updater) Run(ctx context.Context) (*Update, error) {
+	ticker := time.NewTicker(u.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+		update, err := u.Check(ctx)
+		if err != nil {
+			u.Logger.Warn("Release check failed", "error", err)
+			continue
+		}
+		if update != nil {
+			return update, nil
+		}
+	}
+}
+
+// Check downloads and verifies the latest release when it is newer than the running version and was not rolled back
+// before, it returns nil when there is nothing to install
+func (u *Updater) Check(ctx context.Context) (*Update, error) {
+	release, err := u.Source.Latest(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !Newer(release.Version, u.Version) {
+		return nil, nil
+	}
+	state, err := LoadState(u.Executable)
+	if err != nil {
+		return nil, err
+	}
+	if state != nil && state.Failed && state.Version == strings.TrimPrefix(release.Version, "v") {
+		u.Logger.Debug("Skipping release that was rolled back", "version", release.Version)
+		return nil, nil
+	}
+
+	u.Logger.Info("Downloading release", "version", release.Version, "url", release.URL)
+	staged := stagedPath(u.Executable)
+	if err := u.Source.Download(ctx, release, staged); err != nil {
+		return nil, err
+	}
+	if err := verify(ctx, staged, release.Version); err != nil {
+		return nil, err
+	}
+	return &Update{Release: release, updater: u}, nil
+}
+
+// Install replaces the executable by the release and starts its grace period, exit with ExitRestart afterwards
+func (up *Update) Install() error {
+	u := up.updater
+	return install(u.Executable, State{
+		Version:  strings.TrimPrefix(up.Release.Version, "v"),
+		Previous: u.Version,
+		Deadline: time.Now().Add(u.GracePeriod),
+	})
+}
+
+// verify runs `<binary> version`, which must report version, so a binary that doesn't start on this host, e.g. one
+// built for another architecture, is never installed
+func verify(ctx context.Context, binary, version string) error {
+	ctx, cancel := context.WithTimeout(ctx, VerifyTimeout)
+	defer cancel()
+	output, err := exec.CommandContext(ctx, binary, "version").CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("release %s does not start: %w: %s", version, err, strings.TrimSpace(string(output)))
+	}
+	if !strings.Contains(string(output), strings.TrimPrefix(version, "v")) {
+		return fmt.Errorf("release %s reports another version: %s", version, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
@@ -0,0 +1,211 @@
+package autoupdate
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeReleases serves the latest release like the GitHub API, the asset is a script printing the version it reports
+type fakeReleases struct {
+	tag      string
+	asset    string // name of the asset
+	binary   string // content of the asset
+	checksum string // SHA-256 listed for the asset, the one of binary when empty
+}
+
+func (f *fakeReleases) start(t *testing.T) Source {
+	t.Helper()
+	mux := http.NewServeMux()
+	var server *httptest.Server
+	mux.HandleFunc("/repos/containifyci/temporal-worker/releases/latest", func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"tag_name": f.tag,
+			"assets": []map[string]string{
+				{"name": "temporal-worker_checksums.txt", "browser_download_url": server.URL + "/download/checksums"},
+				{"name": f.asset, "browser_download_url": server.URL + "/download/" + f.asset},
+			},
+		})
+	})
+	mux.HandleFunc("/download/checksums", func(w http.ResponseWriter, _ *http.Request) {
+		checksum := f.checksum
+		if checksum == "" {
+			checksum = fmt.Sprintf("%x", sha256.Sum256([]byte(f.binary)))
+		}
+		_, _ = fmt.Fprintf(w, "%x  temporal-worker_plan9_mips\n%s  %s\n", sha256.Sum256(nil), checksum, f.asset)
+	})
+	mux.HandleFunc("/download/", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(f.binary))
+	})
+	server = httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return Source{BaseURL: server.URL, Owner: "containifyci", Repo: "temporal-worker", Binary: "temporal-worker", Client: server.Client()}
+}
+
+// script is a release binary printing the banner of version
+func script(version string) string {
+	return fmt.Sprintf("#!/bin/sh\necho 'temporal-worker %s, commit none, built at unknown'\n", version)
+}
+
+// newUpdater installs the running version 1.2.0 in a temporary directory
+func newUpdater(t *testing.T, source Source) *Updater {
+	t.Helper()
+	exe := filepath.Join(t.TempDir(), "temporal-worker")
+	require.NoError(t, os.WriteFile(exe, []byte(script("1.2.0")), 0o755))
+	return &Updater{
+		Source:      source,
+		Version:     "1.2.0",
+		Executable:  exe,
+		Interval:    10 * time.Millisecond,
+		GracePeriod: time.Minute,
+		Logger:      slog.New(slog.DiscardHandler),
+	}
+}
+
+func TestCheck(t *testing.T) {
+	platform := fmt.Sprintf("temporal-worker_%s_%s", runtime.GOOS, runtime.GOARCH)
+	tests := []struct {
+		name    string
+		release fakeReleases
+		version string // release found, empty when there is nothing to install
+		err     string
+	}{
+		{name: "newer release", release: fakeReleases{tag: "v1.3.0", asset: platform, binary: script("1.3.0")}, version: "v1.3.0"},
+		{name: "same release", release: fakeReleases{tag: "v1.2.0", asset: platform, binary: script("1.2.0")}},
+		{name: "older release", release: fakeReleases{tag: "v1.1.0", asset: platform, binary: script("1.1.0")}},
+		{name: "no asset for this platform", release: fakeReleases{tag: "v1.3.0", asset: "temporal-worker_plan9_mips"}, err: "release v1.3.0 has no asset " + platform},
+		{name: "checksum mismatch", release: fakeReleases{tag: "v1.3.0", asset: platform, binary: script("1.3.0"), checksum: fmt.Sprintf("%x", sha256.Sum256([]byte(script("1.2.0"))))}, err: "release v1.3.0: checksum mismatch of " + platform},
+		{name: "binary does not start", release: fakeReleases{tag: "v1.3.0", asset: platform, binary: "#!/bin/sh\nexit 3\n"}, err: "release v1.3.0 does not start"},
+		{name: "binary reports another version", release: fakeReleases{tag: "v1.3.0", asset: platform, binary: script("1.2.0")}, err: "release v1.3.0 reports another version"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := newUpdater(t, tt.release.start(t))
+			update, err := u.Check(context.Background())
+			if tt.err != "" {
+				assert.ErrorContains(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			if tt.version == "" {
+				assert.Nil(t, update)
+				return
+			}
+			require.NotNil(t, update)
+			assert.Equal(t, tt.version, update.Release.Version)
+		})
+	}
+}
+
+func TestCheck_DevelopmentBuild(t *testing.T) {
+	release := fakeReleases{tag: "v1.3.0", asset: fmt.Sprintf("temporal-worker_%s_%s", runtime.GOOS, runtime.GOARCH), binary: script("1.3.0")}
+	u := newUpdater(t, release.start(t))
+	u.Version = "dev"
+	update, err := u.Check(context.Background())
+	require.NoError(t, err)
+	assert.Nil(t, update)
+}
+
+func TestUpdateAndRollback(t *testing.T) {
+	release := fakeReleases{tag: "v1.3.0", asset: fmt.Sprintf("temporal-worker_%s_%s", runtime.GOOS, runtime.GOARCH), binary: script("1.3.0")}
+	u := newUpdater(t, release.start(t))
+
+	update, err := u.Run(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, update.Install())
+	assertBinary(t, u.Executable, "1.3.0")
+	assertBinary(t, previousPath(u.Executable), "1.2.0")
+
+	// Its supervisor rolls it back
+	require.NoError(t, Rollback(u.Executable))
+	assertBinary(t, u.Executable, "1.2.0")
+
+	// The previous version doesn't install the failed release again
+	update, err = u.Check(context.Background())
+	require.NoError(t, err)
+	assert.Nil(t, update)
+
+	// but the next one
+	release.tag, release.binary = "v1.3.1", script("1.3.1")
+	update, err = u.Check(context.Background())
+	require.NoError(t, err)
+	require.NotNil(t, update)
+	assert.Equal(t, "v1.3.1", update.Release.Version)
+}
+
+func TestConfirm(t *testing.T) {
+	previous := ConfirmInterval
+	ConfirmInterval = time.Millisecond
+	t.Cleanup(func() { ConfirmInterval = previous })
+
+	release := fakeReleases{tag: "v1.3.0", asset: fmt.Sprintf("temporal-worker_%s_%s", runtime.GOOS, runtime.GOARCH), binary: script("1.3.0")}
+	source := release.start(t)
+
+	t.Run("ready within the grace period", func(t *testing.T) {
+		u := newUpdater(t, source)
+		update, err := u.Check(context.Background())
+		require.NoError(t, err)
+		require.NoError(t, update.Install())
+
+		var checks atomic.Int32
+		err = Confirm(context.Background(), u.Executable, "1.3.0", func(context.Context) bool { return checks.Add(1) == 3 })
+		require.NoError(t, err)
+		state, err := LoadState(u.Executable)
+		require.NoError(t, err)
+		assert.Nil(t, state, "the release is confirmed")
+		assertBinary(t, u.Executable, "1.3.0")
+		assertBinary(t, previousPath(u.Executable), "1.2.0")
+	})
+
+	t.Run("not ready within the grace period", func(t *testing.T) {
+		u := newUpdater(t, source)
+		u.GracePeriod = 20 * time.Millisecond
+		update, err := u.Check(context.Background())
+		require.NoError(t, err)
+		require.NoError(t, update.Install())
+
+		err = Confirm(context.Background(), u.Executable, "1.3.0", func(context.Context) bool { return false })
+		assert.ErrorIs(t, err, ErrNotConfirmed)
+		assertBinary(t, u.Executable, "1.3.0")
+		state, err := LoadState(u.Executable)
+		require.NoError(t, err)
+		assert.False(t, state.Failed, "the release leaves the rollback to its supervisor")
+	})
+
+	t.Run("release replaced by hand", func(t *testing.T) {
+		u := newUpdater(t, source)
+		update, err := u.Check(context.Background())
+		require.NoError(t, err)
+		require.NoError(t, update.Install())
+
+		require.NoError(t, Confirm(context.Background(), u.Executable, "1.4.0", func(context.Context) bool { return false }))
+		state, err := LoadState(u.Executable)
+		require.NoError(t, err)
+		assert.Nil(t, state)
+	})
+
+	t.Run("nothing to confirm", func(t *testing.T) {
+		u := newUpdater(t, source)
+		require.NoError(t, Confirm(context.Background(), u.Executable, "1.2.0", func(context.Context) bool { return false }))
+	})
+}
+
+func assertBinary(t *testing.T, path, version string) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, script(version), string(data))
+}
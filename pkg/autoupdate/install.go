@@ -0,0 +1,123 @@
+package autoupdate
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ConfirmInterval is how often Confirm evaluates the readiness of a new release
+var ConfirmInterval = 5 * time.Second
+
+// ErrNotConfirmed is returned when a release was not ready within its grace period, its Supervisor rolls it back
+var ErrNotConfirmed = errors.New("release not confirmed")
+
+// State is the last release the updater installed, it is kept next to the executable
+type State struct {
+	Version  string    `json:"version"`
+	Previous string    `json:"previous"` // the version a rollback restores
+	Deadline time.Time `json:"deadline"` // the release must be ready before
+	Failed   bool      `json:"failed"`   // the release was rolled back and is not installed again
+}
+
+// Paths of the files next to the executable
+func stagedPath(exe string) string   { return exe + ".new" }
+func previousPath(exe string) string { return exe + ".previous" }
+func statePath(exe string) string    { return exe + ".update.json" }
+
+// Executable returns the path of the running binary with symlinks resolved
+func Executable() (string, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+	return filepath.EvalSymlinks(exe)
+}
+
+// LoadState returns the state of the last update, nil when there is none
+func LoadState(exe string) (*State, error) {
+	data, err := os.ReadFile(statePath(exe))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var state State
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("invalid update state %s: %w", statePath(exe), err)
+	}
+	return &state, nil
+}
+
+func saveState(exe string, state State) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(statePath(exe), data, 0o644)
+}
+
+// install replaces exe by the staged binary, the replaced binary is kept for a rollback
+func install(exe string, state State) error {
+	if err := saveState(exe, state); err != nil {
+		return fmt.Errorf("failed to save update state: %w", err)
+	}
+	if err := os.Rename(exe, previousPath(exe)); err != nil {
+		return fmt.Errorf("failed to keep previous binary: %w", err)
+	}
+	if err := os.Rename(stagedPath(exe), exe); err != nil {
+		_ = os.Rename(previousPath(exe), exe)
+		return fmt.Errorf("failed to install release %s: %w", state.Version, err)
+	}
+	return nil
+}
+
+// Rollback restores the previous binary and marks the installed release as failed
+func Rollback(exe string) error {
+	state, err := LoadState(exe)
+	if err != nil {
+		return err
+	}
+	if state == nil || state.Failed {
+		return errors.New("no release to roll back")
+	}
+	if err := os.Rename(previousPath(exe), exe); err != nil {
+		return fmt.Errorf("failed to restore version %s: %w", state.Previous, err)
+	}
+	state.Failed = true
+	return saveState(exe, *state)
+}
+
+// Confirm waits until the running version, when it is a release still in its grace period, is ready and ends its
+// grace period; it returns ErrNotConfirmed when the grace period ends first
+// The release doesn't roll itself back, the Supervisor that started it does
+func Confirm(ctx context.Context, exe, version string, ready func(ctx context.Context) bool) error {
+	state, err := LoadState(exe)
+	if err != nil || state == nil || state.Failed {
+		return err
+	}
+	if state.Version != version {
+		// The release was replaced, e.g. by a manual update
+		return os.Remove(statePath(exe))
+	}
+	ticker := time.NewTicker(ConfirmInterval)
+	defer ticker.Stop()
+	for {
+		if ready(ctx) {
+			return os.Remove(statePath(exe))
+		}
+		if !time.Now().Before(state.Deadline) {
+			return fmt.Errorf("%w: version %s was not ready within the grace period", ErrNotConfirmed, version)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
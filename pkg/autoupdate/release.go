@@ -0,0 +1,181 @@
+package autoupdate
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"runtime"
+	"strings"
+
+	"golang.org/x/mod/semver"
+)
+
+// Source is the GitHub repository publishing the releases
+// Every release must publish the SHA-256 checksums of its assets, e.g. goreleaser's <binary>_checksums.txt
+type Source struct {
+	BaseURL string // GitHub API, https://api.github.com when empty
+	Owner   string
+	Repo    string
+	Binary  string            // the release assets are named <binary>_<os>_<arch>
+	Arch    map[string]string // the <arch> of GOARCH values when it differs, e.g. amd64: x86_64
+	Client  *http.Client
+}
+
+// Release is the asset of the latest release built for this platform
+type Release struct {
+	Version      string
+	Asset        string
+	URL          string
+	ChecksumsURL string
+}
+
+// Latest returns the latest release
+func (s Source) Latest(ctx context.Context) (Release, error) {
+	base := s.BaseURL
+	if base == "" {
+		base = "https://api.github.com"
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		fmt.Sprintf("%s/repos/%s/%s/releases/latest", strings.TrimSuffix(base, "/"), s.Owner, s.Repo), nil)
+	if err != nil {
+		return Release{}, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	resp, err := s.client().Do(req)
+	if err != nil {
+		return Release{}, fmt.Errorf("failed to fetch latest release: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return Release{}, fmt.Errorf("failed to fetch latest release: %s", resp.Status)
+	}
+
+	var latest struct {
+		TagName string `json:"tag_name"`
+		Assets  []struct {
+			Name string `json:"name"`
+			URL  string `json:"browser_download_url"`
+		} `json:"assets"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&latest); err != nil {
+		return Release{}, fmt.Errorf("failed to decode latest release: %w", err)
+	}
+	arch := runtime.GOARCH
+	if a, ok := s.Arch[arch]; ok {
+		arch = a
+	}
+	release := Release{Version: latest.TagName, Asset: fmt.Sprintf("%s_%s_%s", s.Binary, runtime.GOOS, arch)}
+	for _, a := range latest.Assets {
+		switch {
+		case a.Name == release.Asset:
+			release.URL = a.URL
+		case strings.HasSuffix(a.Name, "checksums.txt"):
+			release.ChecksumsURL = a.URL
+		}
+	}
+	if release.URL == "" {
+		return Release{}, fmt.Errorf("release %s has no asset %s", latest.TagName, release.Asset)
+	}
+	if release.ChecksumsURL == "" {
+		return Release{}, fmt.Errorf("release %s has no checksums", latest.TagName)
+	}
+	return release, nil
+}
+
+// checksum returns the SHA-256 the checksums file of the release lists for its asset
+func (s Source) checksum(ctx context.Context, release Release) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, release.ChecksumsURL, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := s.client().Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to download checksums of release %s: %w", release.Version, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to download checksums of release %s: %s", release.Version, resp.Status)
+	}
+	// Lines of `sha256sum`: <hex digest>  <file name>, binary mode marks the name with *
+	scanner := bufio.NewScanner(io.LimitReader(resp.Body, 1<<20))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == release.Asset {
+			return strings.ToLower(fields[0]), nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("failed to read checksums of release %s: %w", release.Version, err)
+	}
+	return "", fmt.Errorf("checksums of release %s don't list %s", release.Version, release.Asset)
+}
+
+// Download writes the release binary to path once its SHA-256 matches the checksums of the release, a binary that
+// doesn't match is removed
+func (s Source) Download(ctx context.Context, release Release, path string) error {
+	want, err := s.checksum(ctx, release)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, release.URL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := s.client().Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to download release %s: %w", release.Version, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to download release %s: %s", release.Version, resp.Status)
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o755)
+	if err != nil {
+		return err
+	}
+	hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(f, hash), resp.Body); err != nil {
+		_ = f.Close()
+		_ = os.Remove(path)
+		return fmt.Errorf("failed to download release %s: %w", release.Version, err)
+	}
+	if got := hex.EncodeToString(hash.Sum(nil)); got != want {
+		_ = f.Close()
+		_ = os.Remove(path)
+		return fmt.Errorf("release %s: checksum mismatch of %s, got %s, want %s", release.Version, release.Asset, got, want)
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func (s Source) client() *http.Client {
+	if s.Client != nil {
+		return s.Client
+	}
+	return http.DefaultClient
+}
+
+// Newer reports whether version is newer than current, development builds without a semantic version never update
+func Newer(version, current string) bool {
+	v, c := canonical(version), canonical(current)
+	return semver.IsValid(v) && semver.IsValid(c) && semver.Compare(v, c) > 0
+}
+
+// canonical adds the v prefix semver expects, release tags have it but the versions baked into the binary do not
+func canonical(version string) string {
+	if strings.HasPrefix(version, "v") {
+		return version
+	}
+	return "v" + version
+}
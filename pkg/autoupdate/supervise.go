@@ -0,0 +1,146 @@
+package autoupdate
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"os/signal"
+	"time"
+)
+
+// SupervisedEnv is set for the worker a Supervisor runs
+const SupervisedEnv = "TEMPORAL_WORKER_SUPERVISED"
+
+// ExitRestart is the exit code of a supervised worker that installed a release, the supervisor starts it again
+const ExitRestart = 75
+
+// Supervised reports whether a Supervisor runs this process
+func Supervised() bool {
+	return os.Getenv(SupervisedEnv) == "1"
+}
+
+// Supervisor runs the worker as a child process and decides on the rollback of a new release, so a release that
+// crashes during its initialization or doesn't start at all is rolled back by the version that installed it
+// A release is in its grace period until it confirms with Confirm; a child that exits or is still unconfirmed at the
+// deadline is stopped, the previous binary is restored and started instead
+type Supervisor struct {
+	Executable  string
+	Args        []string
+	StopTimeout time.Duration // how long a child that is rolled back may drain before it is killed
+	Logger      *slog.Logger
+}
+
+// Run runs the worker until it exits other than with ExitRestart or the supervisor is stopped, it returns the exit
+// code of the worker; stop signals are forwarded to the worker, so it drains as if it ran alone
+func (s *Supervisor) Run() (int, error) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, forwardedSignals...)
+	defer signal.Stop(signals)
+
+	for {
+		// A release that missed its grace period, e.g. one the supervisor was stopped during, is not started again
+		if state, err := s.trial(); err != nil {
+			return 1, err
+		} else if state != nil && !time.Now().Before(state.Deadline) {
+			if err := s.rollback(state, "it was not ready within its grace period"); err != nil {
+				return 1, err
+			}
+		}
+
+		code, restart, err := s.runChild(signals)
+		if err != nil || !restart {
+			return code, err
+		}
+	}
+}
+
+// trial returns the state of the installed release while it is in its grace period, nil once it was confirmed
+func (s *Supervisor) trial() (*State, error) {
+	state, err := LoadState(s.Executable)
+	if err != nil || state == nil || state.Failed {
+		return nil, err
+	}
+	return state, nil
+}
+
+func (s *Supervisor) rollback(state *State, reason string) error {
+	s.Logger.Error("Rolling back release", "version", state.Version, "previous", state.Previous, "reason", reason)
+	if err := Rollback(s.Executable); err != nil {
+		return fmt.Errorf("failed to roll back release %s: %w", state.Version, err)
+	}
+	return nil
+}
+
+// runChild runs the worker once, it returns true when the worker must be started again, after an update or a rollback
+func (s *Supervisor) runChild(signals <-chan os.Signal) (int, bool, error) {
+	cmd := exec.Command(s.Executable, s.Args...)
+	cmd.Env = append(os.Environ(), SupervisedEnv+"=1")
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	cmd.SysProcAttr = childAttr()
+	if err := cmd.Start(); err != nil {
+		// A release that can't be executed is rolled back like one that crashes
+		state, trialErr := s.trial()
+		if trialErr != nil || state == nil {
+			return 1, false, errors.Join(err, trialErr)
+		}
+		return 1, true, s.rollback(state, err.Error())
+	}
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+
+	ticker := time.NewTicker(ConfirmInterval)
+	defer ticker.Stop()
+	stopping, rollingBack := false, false
+	var kill <-chan time.Time
+	for {
+		select {
+		case sig := <-signals:
+			stopping = stopping || isStop(sig)
+			_ = cmd.Process.Signal(sig)
+			continue
+		case <-kill:
+			_ = cmd.Process.Kill()
+			continue
+		case <-ticker.C:
+			state, err := s.trial()
+			if err != nil || state == nil || stopping || rollingBack || time.Now().Before(state.Deadline) {
+				continue
+			}
+			s.Logger.Warn("Release not ready within its grace period, stopping it", "version", state.Version)
+			rollingBack = true
+			_ = stopChild(cmd.Process)
+			kill = time.After(s.StopTimeout)
+			continue
+		case err := <-exited:
+			code := exitCode(err)
+			if stopping {
+				return code, false, nil
+			}
+			state, trialErr := s.trial()
+			if trialErr != nil {
+				return code, false, trialErr
+			}
+			if state != nil {
+				return code, true, s.rollback(state, fmt.Sprintf("it exited with %d before it was ready", code))
+			}
+			return code, code == ExitRestart, nil
+		}
+	}
+}
+
+// exitCode returns the exit code of the worker, 1 when it was killed by a signal
+func exitCode(err error) int {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		if code := exitErr.ExitCode(); code >= 0 {
+			return code
+		}
+		return 1
+	}
+	if err != nil {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,26 @@
+//go:build !unix
+
+package autoupdate
+
+import (
+	"os"
+	"syscall"
+)
+
+// forwardedSignals are passed on to the worker
+var forwardedSignals = []os.Signal{os.Interrupt}
+
+// isStop reports whether the signal stops the worker
+func isStop(os.Signal) bool {
+	return true
+}
+
+// childAttr has nothing to set on this platform
+func childAttr() *syscall.SysProcAttr {
+	return nil
+}
+
+// stopChild kills the worker, this platform can't ask it to drain
+func stopChild(p *os.Process) error {
+	return p.Kill()
+}
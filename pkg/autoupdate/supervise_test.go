@@ -0,0 +1,116 @@
+//go:build unix
+
+package autoupdate
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSupervisor(t *testing.T) {
+	previous := ConfirmInterval
+	ConfirmInterval = time.Millisecond
+	t.Cleanup(func() { ConfirmInterval = previous })
+
+	tests := []struct {
+		name     string
+		release  string        // script of the installed release, it runs after recording itself
+		mode     os.FileMode   // of the release, 0o755 when zero
+		deadline time.Duration // of the grace period, from now
+		restarts bool          // the release installs an update on its first run
+		runs     []string
+		binary   string // installed afterwards
+		failed   bool   // the release was rolled back
+	}{
+		{
+			name:     "release confirms",
+			release:  `rm "$0.update.json"`,
+			deadline: time.Minute,
+			runs:     []string{"release"},
+			binary:   "release",
+		},
+		{
+			name:     "release crashes on start",
+			release:  "exit 1",
+			deadline: time.Minute,
+			runs:     []string{"release", "previous"},
+			binary:   "previous",
+			failed:   true,
+		},
+		{
+			name:     "release can't be executed",
+			release:  "exit 0",
+			mode:     0o644,
+			deadline: time.Minute,
+			runs:     []string{"previous"},
+			binary:   "previous",
+			failed:   true,
+		},
+		{
+			name:     "release not ready within its grace period",
+			release:  "exec sleep 10",
+			deadline: 50 * time.Millisecond,
+			runs:     []string{"release", "previous"},
+			binary:   "previous",
+			failed:   true,
+		},
+		{
+			name:     "grace period ended before the start",
+			release:  "exit 0",
+			deadline: -time.Second,
+			runs:     []string{"previous"},
+			binary:   "previous",
+			failed:   true,
+		},
+		{
+			name:     "release installed the next update",
+			release:  `rm "$0.update.json"; exit 75`,
+			deadline: time.Minute,
+			restarts: true,
+			runs:     []string{"release", "release"},
+			binary:   "release",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			runs := filepath.Join(dir, "runs")
+			t.Setenv("RUNS", runs)
+			exe := filepath.Join(dir, "temporal-worker")
+			mode := tt.mode
+			if mode == 0 {
+				mode = 0o755
+			}
+			// A release installing an update exits with ExitRestart once, the second run stays
+			release := "#!/bin/sh\necho release >> \"$RUNS\"\n"
+			if tt.restarts {
+				release += "[ -f \"$0.updated\" ] && exit 0\ntouch \"$0.updated\"\n"
+			}
+			require.NoError(t, os.WriteFile(exe, []byte(release+tt.release+"\n"), mode))
+			require.NoError(t, os.WriteFile(previousPath(exe), []byte("#!/bin/sh\necho previous >> \"$RUNS\"\n"), 0o755))
+			require.NoError(t, saveState(exe, State{Version: "1.3.0", Previous: "1.2.0", Deadline: time.Now().Add(tt.deadline)}))
+
+			s := &Supervisor{Executable: exe, Args: []string{"start"}, StopTimeout: time.Second, Logger: slog.New(slog.DiscardHandler)}
+			code, err := s.Run()
+			require.NoError(t, err)
+			assert.Equal(t, 0, code)
+
+			data, err := os.ReadFile(runs)
+			require.NoError(t, err)
+			assert.Equal(t, tt.runs, strings.Fields(string(data)))
+			data, err = os.ReadFile(exe)
+			require.NoError(t, err)
+			assert.Contains(t, string(data), "echo "+tt.binary)
+			state, err := LoadState(exe)
+			require.NoError(t, err)
+			assert.Equal(t, tt.failed, state != nil && state.Failed)
+		})
+	}
+}
@@ -0,0 +1,27 @@
+//go:build unix
+
+package autoupdate
+
+import (
+	"os"
+	"syscall"
+)
+
+// forwardedSignals are passed on to the worker: the stop signals and SIGUSR1, which toggles debug logging
+var forwardedSignals = []os.Signal{syscall.SIGTERM, syscall.SIGINT, syscall.SIGUSR1}
+
+// isStop reports whether the signal stops the worker
+func isStop(sig os.Signal) bool {
+	return sig != syscall.SIGUSR1
+}
+
+// childAttr starts the worker in a process group of its own, so a Ctrl-C of the terminal reaches it only once,
+// through the supervisor
+func childAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{Setpgid: true}
+}
+
+// stopChild asks the worker to drain and exit
+func stopChild(p *os.Process) error {
+	return p.Signal(syscall.SIGTERM)
+}
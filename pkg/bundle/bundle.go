@@ -0,0 +1,215 @@
+// Package bundle groups workflows and activities into bundles a worker enables by name, each bundle declares the
+// tools and secrets it needs, so a worker checks them before it starts polling
+package bundle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/worker"
+	"go.temporal.io/sdk/workflow"
+
+	"github.com/containifyci/temporal-worker/pkg/preflight"
+)
+
+// Bundle is a set of workflows and activities run on one task queue
+type Bundle struct {
+	Name        string
+	Description string
+	TaskQueue   string // default task queue, overridden by worker.taskQueues
+	Workflows   []any
+	Activities  []any
+	Tools       []string          // commands that must be in PATH
+	MinVersions map[string]string // minimum versions of the tools, e.g. go: 1.21
+	Secrets     []string          // environment variables that must be set
+	Sessions    bool              // the workflows run activities in sessions
+
+	// UpgradingWorkflows run until they are stopped, e.g. queues, they move to the current version of the Worker
+	// Deployment instead of staying on the version they started on; their changes must be gated with
+	// workflow.GetVersion
+	UpgradingWorkflows []any
+
+	// Setup prepares the bundle after the secrets were checked and before the tools are, e.g. downloads a tool,
+	// and returns the activities that need the preparation, e.g. clients built from the secrets
+	Setup func() ([]any, error)
+
+	// Preflight returns further checks of the requirements, e.g. the validity of a token, they run after the setup
+	Preflight func() []preflight.Check
+
+	// ActivityQueues returns further task queues that only run activities, e.g. the Engine-CI label queues, each
+	// runs QueueActivities
+	ActivityQueues  func() []string
+	QueueActivities []any
+}
+
+// All returns every bundle a worker can enable
+func All() []Bundle {
+	return []Bundle{EngineCI(), GoMajor(), PRReview(), Diagnostics()}
+}
+
+// Names returns the names of all bundles
+func Names() []string {
+	var names []string
+	for _, b := range All() {
+		names = append(names, b.Name)
+	}
+	return names
+}
+
+// Select returns the enabled bundles bound to their task queues
+// taskQueues maps bundle names to the task queue replacing the default one of the bundle
+func Select(enabled []string, taskQueues map[string]string) ([]Bundle, error) {
+	var errs []error
+	if len(enabled) == 0 {
+		errs = append(errs, fmt.Errorf("no bundle enabled, choose from %s", strings.Join(Names(), ", ")))
+	}
+	for name := range taskQueues {
+		if !slices.Contains(Names(), name) {
+			errs = append(errs, fmt.Errorf("task queue of unknown bundle %q", name))
+		}
+	}
+
+	var bundles []Bundle
+	for _, name := range enabled {
+		i := slices.IndexFunc(All(), func(b Bundle) bool { return b.Name == name })
+		switch {
+		case i < 0:
+			errs = append(errs, fmt.Errorf("unknown bundle %q, choose from %s", name, strings.Join(Names(), ", ")))
+		case slices.ContainsFunc(bundles, func(b Bundle) bool { return b.Name == name }):
+			errs = append(errs, fmt.Errorf("bundle %q enabled twice", name))
+		default:
+			b := All()[i]
+			if queue := taskQueues[name]; queue != "" {
+				b.TaskQueue = queue
+			}
+			bundles = append(bundles, b)
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return bundles, nil
+}
+
+// Tools returns the tools the bundles need, without duplicates
+func Tools(bundles []Bundle) []string {
+	var tools []string
+	for _, b := range bundles {
+		for _, tool := range b.Tools {
+			if !slices.Contains(tools, tool) {
+				tools = append(tools, tool)
+			}
+		}
+	}
+	return tools
+}
+
+// Checks returns the preflight checks run after the setup: the tools with their minimum versions and the further
+// checks of the bundle
+func (b Bundle) Checks() []preflight.Check {
+	var checks []preflight.Check
+	for _, tool := range b.Tools {
+		checks = append(checks, preflight.Tool(tool, b.MinVersions[tool]))
+	}
+	if b.Preflight != nil {
+		checks = append(checks, b.Preflight()...)
+	}
+	return checks
+}
+
+// Prepare checks the secrets, runs the setup of the bundles and runs their preflight checks, a bundle with missing
+// secrets is not set up; the report holds every check, the worker must not start when one failed
+// The activities returned by the setups are added to the bundles
+func Prepare(ctx context.Context, bundles []Bundle) preflight.Report {
+	reports := make([]preflight.Report, len(bundles))
+	for i, b := range bundles {
+		var secrets []preflight.Check
+		for _, secret := range b.Secrets {
+			secrets = append(secrets, preflight.Secret(secret))
+		}
+		results := preflight.Run(ctx, b.Name, secrets...)
+		reports[i] = results
+		if b.Setup == nil {
+			continue
+		}
+		setup := preflight.Check{Name: "setup", Run: func(context.Context) (string, error) {
+			if results.Err() != nil {
+				return "", errors.New("skipped, secrets are missing")
+			}
+			activities, err := b.Setup()
+			if err != nil {
+				return "", err
+			}
+			bundles[i].Activities = append(slices.Clip(b.Activities), activities...)
+			return "done", nil
+		}}
+		reports[i] = append(reports[i], preflight.Run(ctx, b.Name, setup)...)
+	}
+	for i, b := range bundles {
+		reports[i] = append(reports[i], preflight.Run(ctx, b.Name, b.Checks()...)...)
+	}
+	return slices.Concat(reports...)
+}
+
+// Queue is a task queue the worker polls and the bundles it runs there
+type Queue struct {
+	Name         string
+	Bundles      []string
+	ActivityOnly bool
+	Worker       worker.Worker
+}
+
+// NewWorkers creates one worker per task queue of the bundles and registers the bundles with it
+// Bundles sharing a task queue share its worker, which runs a session worker when one of them uses sessions
+func NewWorkers(c client.Client, bundles []Bundle, options worker.Options) []Queue {
+	byQueue := map[string][]Bundle{}
+	activityQueues := map[string][]Bundle{}
+	for _, b := range bundles {
+		byQueue[b.TaskQueue] = append(byQueue[b.TaskQueue], b)
+		if b.ActivityQueues != nil {
+			for _, queue := range b.ActivityQueues() {
+				activityQueues[queue] = append(activityQueues[queue], b)
+			}
+		}
+	}
+
+	var queues []Queue
+	for _, name := range slices.Sorted(maps.Keys(byQueue)) {
+		opts := options
+		opts.EnableSessionWorker = slices.ContainsFunc(byQueue[name], func(b Bundle) bool { return b.Sessions })
+		w := worker.New(c, name, opts)
+		q := Queue{Name: name, Worker: w}
+		for _, b := range byQueue[name] {
+			for _, wf := range b.Workflows {
+				w.RegisterWorkflow(wf)
+			}
+			for _, wf := range b.UpgradingWorkflows {
+				w.RegisterWorkflowWithOptions(wf, workflow.RegisterOptions{VersioningBehavior: workflow.VersioningBehaviorAutoUpgrade})
+			}
+			for _, a := range b.Activities {
+				w.RegisterActivity(a)
+			}
+			q.Bundles = append(q.Bundles, b.Name)
+		}
+		queues = append(queues, q)
+	}
+	for _, name := range slices.Sorted(maps.Keys(activityQueues)) {
+		opts := options
+		opts.DisableWorkflowWorker = true
+		w := worker.New(c, name, opts)
+		q := Queue{Name: name, ActivityOnly: true, Worker: w}
+		for _, b := range activityQueues[name] {
+			for _, a := range b.QueueActivities {
+				w.RegisterActivity(a)
+			}
+			q.Bundles = append(q.Bundles, b.Name)
+		}
+		queues = append(queues, q)
+	}
+	return queues
+}
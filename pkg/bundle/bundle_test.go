@@ -0,0 +1,175 @@
+package bundle
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/worker"
+	"go.temporal.io/sdk/workflow"
+
+	"github.com/containifyci/temporal-worker/pkg/preflight"
+	"github.com/containifyci/temporal-worker/pkg/workflows/engineci"
+)
+
+func TestSelect(t *testing.T) {
+	tests := []struct {
+		name       string
+		enabled    []string
+		taskQueues map[string]string
+		queues     map[string]string
+		errs       []string
+	}{
+		{
+			name:    "default task queues",
+			enabled: []string{"engineci", "golangmajor"},
+			queues:  map[string]string{"engineci": engineci.TaskQueue, "golangmajor": DuneBotTaskQueue},
+		},
+		{
+			name:       "configured task queue",
+			enabled:    []string{"diagnostics"},
+			taskQueues: map[string]string{"diagnostics": "diagnostics-eu", "engineci": "engine-ci-large"},
+			queues:     map[string]string{"diagnostics": "diagnostics-eu"},
+		},
+		{
+			name: "nothing enabled",
+			errs: []string{"no bundle enabled, choose from engineci, golangmajor, prreview, diagnostics"},
+		},
+		{
+			name:       "unknown and duplicate bundles",
+			enabled:    []string{"engineci", "hello", "engineci"},
+			taskQueues: map[string]string{"review": "x"},
+			errs:       []string{`unknown bundle "hello"`, `bundle "engineci" enabled twice`, `task queue of unknown bundle "review"`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bundles, err := Select(tt.enabled, tt.taskQueues)
+			if len(tt.errs) > 0 {
+				for _, msg := range tt.errs {
+					assert.ErrorContains(t, err, msg)
+				}
+				return
+			}
+			require.NoError(t, err)
+			queues := map[string]string{}
+			for _, b := range bundles {
+				queues[b.Name] = b.TaskQueue
+			}
+			assert.Equal(t, tt.queues, queues)
+		})
+	}
+}
+
+func TestPrepare(t *testing.T) {
+	activity := func(context.Context) error { return nil }
+	setupActivity := func(context.Context) error { return nil }
+	check := func(name string, err error) preflight.Check {
+		return preflight.Check{Name: name, Run: func(context.Context) (string, error) { return "", err }}
+	}
+
+	t.Run("missing secrets skip the setup", func(t *testing.T) {
+		t.Setenv("BUNDLE_TEST_TOKEN", "")
+		setup := false
+		report := Prepare(context.Background(), []Bundle{{Name: "a", Secrets: []string{"BUNDLE_TEST_TOKEN"}, Setup: func() ([]any, error) {
+			setup = true
+			return nil, nil
+		}}})
+		assert.EqualError(t, report.Err(), "a: secret BUNDLE_TEST_TOKEN: not set\na: setup: skipped, secrets are missing")
+		assert.False(t, setup)
+	})
+
+	t.Run("setup adds activities", func(t *testing.T) {
+		t.Setenv("BUNDLE_TEST_TOKEN", "secret")
+		bundles := []Bundle{{
+			Name:        "a",
+			Activities:  []any{activity},
+			Tools:       []string{"go"},
+			MinVersions: map[string]string{"go": "1.21"},
+			Secrets:     []string{"BUNDLE_TEST_TOKEN"},
+			Setup:       func() ([]any, error) { return []any{setupActivity}, nil },
+			Preflight:   func() []preflight.Check { return []preflight.Check{check("token", nil)} },
+		}}
+		report := Prepare(context.Background(), bundles)
+		require.NoError(t, report.Err())
+		var checks []string
+		for _, result := range report {
+			checks = append(checks, result.Check)
+		}
+		assert.Equal(t, []string{"secret BUNDLE_TEST_TOKEN", "setup", "tool go >= 1.21", "token"}, checks)
+		assert.Len(t, bundles[0].Activities, 2)
+	})
+
+	t.Run("every failure is reported", func(t *testing.T) {
+		report := Prepare(context.Background(), []Bundle{
+			{Name: "a", Tools: []string{"go", "bundle-test-missing-tool"}, Setup: func() ([]any, error) { return nil, errors.New("no app key") }},
+			{Name: "b", Preflight: func() []preflight.Check { return []preflight.Check{check("token", errors.New("expired"))} }},
+		})
+		assert.EqualError(t, report.Err(), "a: setup: no app key\na: tool bundle-test-missing-tool: bundle-test-missing-tool not found in PATH\nb: token: expired")
+	})
+}
+
+func TestTools(t *testing.T) {
+	assert.Equal(t, []string{"git", "engine-ci", "go", "mod"}, Tools([]Bundle{EngineCI(), GoMajor(), Diagnostics()}))
+}
+
+func TestNewWorkers(t *testing.T) {
+	defer func(labels []string) { engineci.Labels = labels }(engineci.Labels)
+	engineci.Labels = []string{"docker"}
+
+	c, err := client.NewLazyClient(client.Options{})
+	require.NoError(t, err)
+	defer c.Close()
+
+	// Registering every bundle, also on shared task queues, must not clash
+	bundles, err := Select(Names(), map[string]string{"diagnostics": "diagnostics"})
+	require.NoError(t, err)
+	queues := NewWorkers(c, bundles, worker.Options{})
+
+	var names []string
+	byName := map[string]Queue{}
+	for _, q := range queues {
+		names = append(names, q.Name)
+		byName[q.Name] = q
+	}
+	assert.Equal(t, []string{"diagnostics", DuneBotTaskQueue, engineci.TaskQueue, engineci.TaskQueue + "@docker"}, names)
+	assert.Equal(t, []string{"golangmajor", "prreview"}, byName[DuneBotTaskQueue].Bundles)
+	assert.Equal(t, []string{"engineci"}, byName[engineci.TaskQueue+"@docker"].Bundles)
+	assert.True(t, byName[engineci.TaskQueue+"@docker"].ActivityOnly)
+	assert.False(t, byName[engineci.TaskQueue].ActivityOnly)
+}
+
+// funcNames returns the names of the workflow functions without their package
+func funcNames(fns []any) []string {
+	var names []string
+	for _, fn := range fns {
+		name := runtime.FuncForPC(reflect.ValueOf(fn).Pointer()).Name()
+		names = append(names, name[strings.LastIndex(name, ".")+1:])
+	}
+	return names
+}
+
+func TestUpgradingWorkflows(t *testing.T) {
+	// The queues run for good, pinned to a version they would stop once the workers of that version are replaced
+	assert.Equal(t, []string{"EngineCIRepoWorkflow"}, funcNames(EngineCI().UpgradingWorkflows))
+	assert.NotContains(t, funcNames(EngineCI().Workflows), "EngineCIRepoWorkflow")
+	assert.Equal(t, []string{"PullRequestQueueWorkflow"}, funcNames(PRReview().UpgradingWorkflows))
+
+	c, err := client.NewLazyClient(client.Options{})
+	require.NoError(t, err)
+	defer c.Close()
+
+	// Pinned workers register the queues with their own behavior
+	options := worker.Options{DeploymentOptions: worker.DeploymentOptions{
+		UseVersioning:             true,
+		Version:                   worker.WorkerDeploymentVersion{DeploymentName: "temporal-worker", BuildID: "1.4.0"},
+		DefaultVersioningBehavior: workflow.VersioningBehaviorPinned,
+	}}
+	assert.NotPanics(t, func() { NewWorkers(c, []Bundle{EngineCI(), PRReview()}, options) })
+}
@@ -0,0 +1,147 @@
+package bundle
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/containifyci/dunebot/pkg/config"
+
+	"github.com/containifyci/temporal-worker/pkg/activities/filesystem"
+	gitactivity "github.com/containifyci/temporal-worker/pkg/activities/git"
+	golangactivity "github.com/containifyci/temporal-worker/pkg/activities/golang"
+	"github.com/containifyci/temporal-worker/pkg/helloworld"
+	"github.com/containifyci/temporal-worker/pkg/preflight"
+	"github.com/containifyci/temporal-worker/pkg/workflows/engineci"
+	"github.com/containifyci/temporal-worker/pkg/workflows/github"
+	"github.com/containifyci/temporal-worker/pkg/workflows/golangmajor"
+)
+
+// DuneBotTaskQueue is the default task queue of the Go major upgrades, the pull request reviews and the diagnostics
+const DuneBotTaskQueue = "dunebot"
+
+// MinFreeSpace is the space the workspaces in the temp dir need at least, e.g. for a clone and its build
+var MinFreeSpace uint64 = 2 << 30
+
+// workspaceChecks check the temp dir the activities clone repositories into
+func workspaceChecks() []preflight.Check {
+	return []preflight.Check{preflight.WritableDir(os.TempDir()), preflight.FreeSpace(os.TempDir(), MinFreeSpace)}
+}
+
+// EngineCI runs CI jobs with engine-ci, jobs requiring labels run on the label queues of this worker's labels
+func EngineCI() Bundle {
+	// Activities that run inside a job's workspace
+	jobActivities := []any{
+		gitactivity.CloneRepo,
+		gitactivity.ChangedFiles,
+		gitactivity.ResolveRef,
+		engineci.RunEngineCIJob,
+		engineci.LookupJobResult,
+		engineci.StoreJobResult,
+		engineci.CollectArtifacts,
+		filesystem.CleanupDirectory,
+	}
+	return Bundle{
+		Name:        "engineci",
+		Description: "Engine-CI repository, job and pipeline workflows",
+		TaskQueue:   engineci.TaskQueue,
+		Workflows: []any{
+			engineci.EngineCIJobWorkflow,
+			engineci.EngineCIPipelineWorkflow,
+		},
+		Activities:      append([]any{engineci.NotifyCallback, engineci.RunEngineCI}, jobActivities...),
+		Tools:           []string{"git", "engine-ci"},
+		Setup:           installEngineCI,
+		Preflight:       workspaceChecks,
+		ActivityQueues:  func() []string { return engineci.WorkerTaskQueues(engineci.WorkerLabels(), engineci.LabelSets) },
+		QueueActivities: jobActivities,
+
+		// The repository queue runs as long as jobs arrive, so it must not wait for workers of a replaced version
+		UpgradingWorkflows: []any{engineci.EngineCIRepoWorkflow},
+	}
+}
+
+// GoMajor opens pull requests for major upgrades of Go dependencies, a repository is upgraded in a session
+func GoMajor() Bundle {
+	return Bundle{
+		Name:        "golangmajor",
+		Description: "Go major dependency upgrade sweeps",
+		TaskQueue:   DuneBotTaskQueue,
+		Workflows: []any{
+			golangmajor.GoMajorSweepWorkflow,
+			golangmajor.GoMajorUpgradeRepoWorkflow,
+		},
+		Activities: []any{
+			golangactivity.SearchGoRepositories,
+			golangactivity.FetchDependabotConfigFromGitHub,
+			golangactivity.DetectMajorUpgrades,
+			golangactivity.UpgradeDependency,
+			golangactivity.CountOpenMajorUpgradePRs,
+			golangactivity.CheckPRExistsForBranch,
+			golangactivity.PRCreate,
+			golangactivity.PRAddLabels,
+			golangactivity.PRComment,
+			gitactivity.CloneRepoForUpgrade,
+			gitactivity.GitCheckoutBranch,
+			gitactivity.CommitAndPush,
+			gitactivity.GitResetToMain,
+		},
+		Tools: []string{"git", "go", "mod"},
+		// The toolchain switches to the go version a repository requires since 1.21
+		MinVersions: map[string]string{"go": "1.21"},
+		Secrets:     []string{"GITHUB_TOKEN"},
+		Sessions:    true,
+		Preflight: func() []preflight.Check {
+			// The activities search, clone and push repositories and open pull requests, public modules are
+			// always fetched from proxy.golang.org, see golangactivity.UpgradeDependency
+			return append(workspaceChecks(),
+				preflight.GitHubToken("GITHUB_TOKEN", "repo"),
+				preflight.ModuleProxy("https://proxy.golang.org"),
+			)
+		},
+	}
+}
+
+// PRReview reviews pull requests as the DuneBot GitHub App
+func PRReview() Bundle {
+	return Bundle{
+		Name:        "prreview",
+		Description: "DuneBot pull request reviews",
+		TaskQueue:   DuneBotTaskQueue,
+		Workflows: []any{
+			github.PullRequestReviewWorkflow,
+		},
+		Secrets: []string{"DUNEBOT_GITHUB_APP_INTEGRATION_ID", "DUNEBOT_GITHUB_APP_PRIVATE_KEY"},
+		Setup:   reviewActivities,
+
+		// The review queue runs as long as pull requests arrive
+		UpgradingWorkflows: []any{github.PullRequestQueueWorkflow},
+	}
+}
+
+// reviewActivities returns the review activity with a GitHub client of the DuneBot app
+func reviewActivities() ([]any, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, fmt.Errorf("failed loading DuneBot config: %w", err)
+	}
+	cfg.AppConfig = config.ApplicationConfig{
+		ReviewerConfig: config.ReviewerConfig{
+			Type: "direct",
+		},
+	}
+	return []any{github.PullRequestReviewActivities{
+		CC:     github.NewClientCreator(cfg),
+		Config: *cfg,
+	}.PullRequestReviewActivity}, nil
+}
+
+// Diagnostics runs the hello world workflow to check a worker end to end
+func Diagnostics() Bundle {
+	return Bundle{
+		Name:        "diagnostics",
+		Description: "Hello world workflow checking the worker end to end",
+		TaskQueue:   DuneBotTaskQueue,
+		Workflows:   []any{helloworld.Workflow},
+		Activities:  []any{helloworld.Activity},
+	}
+}
@@ -0,0 +1,89 @@
+package bundle
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"github.com/containifyci/temporal-worker/pkg/autoupdate"
+	"github.com/containifyci/temporal-worker/pkg/preflight"
+)
+
+// EngineCIRelease publishes the engine-ci binaries, named like engine-ci_linux_x86_64
+var EngineCIRelease = autoupdate.Source{
+	Owner:  "containifyci",
+	Repo:   "engine-ci",
+	Binary: "engine-ci",
+	Arch:   map[string]string{"amd64": "x86_64", "386": "i386"},
+}
+
+// EngineCIDownloadTimeout bounds the download of engine-ci
+var EngineCIDownloadTimeout = 5 * time.Minute
+
+// installEngineCI downloads engine-ci from GitHub releases if it's not in PATH
+func installEngineCI() ([]any, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), EngineCIDownloadTimeout)
+	defer cancel()
+	if err := downloadEngineCIIfNeeded(ctx, slog.Default()); err != nil {
+		// Continue anyway, the tool check fails if it's truly missing
+		slog.Warn("Failed to auto-download engine-ci", "error", err)
+	}
+	return nil, nil
+}
+
+// engineCIDir is the directory of the worker the downloaded engine-ci is installed in
+func engineCIDir() (string, error) {
+	cache, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find the cache directory: %w", err)
+	}
+	return filepath.Join(cache, "temporal-worker", "bin"), nil
+}
+
+// downloadEngineCIIfNeeded downloads the latest engine-ci release if it's neither in PATH nor installed yet
+// The release must match its published checksum, it is installed in the worker's cache directory and recorded with
+// preflight.Install instead of changing PATH
+func downloadEngineCIIfNeeded(ctx context.Context, logger *slog.Logger) error {
+	// Check if engine-ci is already available
+	if _, err := exec.LookPath("engine-ci"); err == nil {
+		return nil // Already available
+	}
+
+	dir, err := engineCIDir()
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(dir, "engine-ci")
+	// Only verified downloads are renamed to path
+	if _, err := os.Stat(path); err == nil {
+		preflight.Install("engine-ci", path)
+		return nil
+	}
+
+	logger.Info("engine-ci not found in PATH, attempting to download from GitHub releases")
+	release, err := EngineCIRelease.Latest(ctx)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+
+	logger.Info("Downloading engine-ci", "version", release.Version, "url", release.URL)
+	staged := path + ".new"
+	if err := EngineCIRelease.Download(ctx, release, staged); err != nil {
+		return err
+	}
+	if err := os.Rename(staged, path); err != nil {
+		_ = os.Remove(staged)
+		return fmt.Errorf("failed to install engine-ci: %w", err)
+	}
+	preflight.Install("engine-ci", path)
+
+	logger.Info("Successfully downloaded engine-ci", "version", release.Version, "path", path)
+	return nil
+}
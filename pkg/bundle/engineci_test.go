@@ -0,0 +1,89 @@
+package bundle
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/containifyci/temporal-worker/pkg/preflight"
+)
+
+// fakeEngineCIRelease serves an engine-ci release, listing checksum for its binary
+func fakeEngineCIRelease(t *testing.T, binary, checksum string) {
+	t.Helper()
+	arch := runtime.GOARCH
+	if a, ok := EngineCIRelease.Arch[arch]; ok {
+		arch = a
+	}
+	asset := fmt.Sprintf("engine-ci_%s_%s", runtime.GOOS, arch)
+
+	mux := http.NewServeMux()
+	var server *httptest.Server
+	mux.HandleFunc("/repos/containifyci/engine-ci/releases/latest", func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"tag_name": "v0.40.0",
+			"assets": []map[string]string{
+				{"name": "engine-ci_checksums.txt", "browser_download_url": server.URL + "/checksums"},
+				{"name": asset, "browser_download_url": server.URL + "/binary"},
+			},
+		})
+	})
+	mux.HandleFunc("/checksums", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = fmt.Fprintf(w, "%s  %s\n", checksum, asset)
+	})
+	mux.HandleFunc("/binary", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(binary))
+	})
+	server = httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	orig := EngineCIRelease.BaseURL
+	t.Cleanup(func() { EngineCIRelease.BaseURL = orig })
+	EngineCIRelease.BaseURL = server.URL
+	// engine-ci is neither in PATH nor installed yet
+	t.Setenv("PATH", t.TempDir())
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+}
+
+func TestDownloadEngineCIIfNeeded(t *testing.T) {
+	binary := "#!/bin/sh\necho 'engine-ci version 0.40.0'\n"
+
+	t.Run("verified release", func(t *testing.T) {
+		fakeEngineCIRelease(t, binary, fmt.Sprintf("%x", sha256.Sum256([]byte(binary))))
+		path := os.Getenv("PATH")
+		require.NoError(t, downloadEngineCIIfNeeded(context.Background(), slog.New(slog.DiscardHandler)))
+
+		dir, err := engineCIDir()
+		require.NoError(t, err)
+		installed, err := preflight.LookPath("engine-ci")
+		require.NoError(t, err)
+		assert.Equal(t, filepath.Join(dir, "engine-ci"), installed)
+		assert.Equal(t, path, os.Getenv("PATH"), "PATH is not changed")
+		_, err = os.Stat(installed + ".new")
+		assert.ErrorIs(t, err, os.ErrNotExist)
+	})
+
+	t.Run("checksum mismatch", func(t *testing.T) {
+		fakeEngineCIRelease(t, binary, fmt.Sprintf("%x", sha256.Sum256([]byte("tampered"))))
+		err := downloadEngineCIIfNeeded(context.Background(), slog.New(slog.DiscardHandler))
+		assert.ErrorContains(t, err, "checksum mismatch")
+
+		dir, err := engineCIDir()
+		require.NoError(t, err)
+		entries, err := os.ReadDir(dir)
+		require.NoError(t, err)
+		assert.Empty(t, entries, "nothing is installed")
+	})
+}
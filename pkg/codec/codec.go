@@ -0,0 +1,194 @@
+// Package codec encrypts Temporal payloads so workflow inputs, results and failures are not stored
+// in plaintext in the workflow history
+package codec
+
+import (
+	"bytes"
+	"compress/zlib"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+
+	commonpb "go.temporal.io/api/common/v1"
+	"go.temporal.io/sdk/converter"
+	"google.golang.org/protobuf/proto"
+)
+
+// Metadata of encrypted payloads
+const (
+	// EncodingEncrypted marks payloads encrypted by the Codec
+	EncodingEncrypted = "binary/encrypted"
+	// MetadataKeyID names the key a payload was encrypted with
+	MetadataKeyID = "encryption-key-id"
+	// MetadataCipher names the cipher, always CipherAESGCM
+	MetadataCipher = "encryption-cipher"
+	// MetadataCompression is set to CompressionZlib when the payload was compressed before encryption
+	MetadataCompression = "encryption-compression"
+
+	CipherAESGCM    = "AES-GCM"
+	CompressionZlib = "zlib"
+)
+
+// ErrUnknownKey is returned when a payload was encrypted with a key the codec doesn't have
+var ErrUnknownKey = errors.New("unknown encryption key")
+
+// Codec is a converter.PayloadCodec encrypting payloads with AES-GCM
+// Payloads are encrypted with the active key, every configured key can decrypt, which allows rotating
+// keys: add the new key, make it active and remove the old one once no history references it
+type Codec struct {
+	keyID    string
+	ciphers  map[string]cipher.AEAD
+	compress bool
+}
+
+// Options configure a Codec
+type Options struct {
+	// Keys maps key IDs to AES keys of 16, 24 or 32 bytes
+	Keys map[string][]byte
+	// KeyID is the key new payloads are encrypted with
+	KeyID string
+	// Compress compresses payloads with zlib before encryption when that makes them smaller
+	Compress bool
+}
+
+// New returns a codec for the options
+func New(opts Options) (*Codec, error) {
+	if _, ok := opts.Keys[opts.KeyID]; !ok {
+		return nil, fmt.Errorf("active key %q is not configured", opts.KeyID)
+	}
+	c := &Codec{keyID: opts.KeyID, ciphers: make(map[string]cipher.AEAD, len(opts.Keys)), compress: opts.Compress}
+	for id, key := range opts.Keys {
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", id, err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", id, err)
+		}
+		c.ciphers[id] = aead
+	}
+	return c, nil
+}
+
+// KeyID returns the ID of the key new payloads are encrypted with
+func (c *Codec) KeyID() string {
+	return c.keyID
+}
+
+// Encode encrypts the payloads with the active key
+func (c *Codec) Encode(payloads []*commonpb.Payload) ([]*commonpb.Payload, error) {
+	result := make([]*commonpb.Payload, len(payloads))
+	for i, p := range payloads {
+		encoded, err := c.encode(p)
+		if err != nil {
+			return nil, err
+		}
+		result[i] = encoded
+	}
+	return result, nil
+}
+
+// Decode decrypts encrypted payloads, other payloads are returned unchanged
+func (c *Codec) Decode(payloads []*commonpb.Payload) ([]*commonpb.Payload, error) {
+	result := make([]*commonpb.Payload, len(payloads))
+	for i, p := range payloads {
+		decoded, err := c.decode(p)
+		if err != nil {
+			return nil, err
+		}
+		result[i] = decoded
+	}
+	return result, nil
+}
+
+func (c *Codec) encode(p *commonpb.Payload) (*commonpb.Payload, error) {
+	plain, err := proto.Marshal(p)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	metadata := map[string][]byte{
+		converter.MetadataEncoding: []byte(EncodingEncrypted),
+		MetadataKeyID:              []byte(c.keyID),
+		MetadataCipher:             []byte(CipherAESGCM),
+	}
+	if c.compress {
+		if compressed, err := compress(plain); err == nil && len(compressed) < len(plain) {
+			plain = compressed
+			metadata[MetadataCompression] = []byte(CompressionZlib)
+		}
+	}
+
+	aead := c.ciphers[c.keyID]
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plain)+aead.Overhead())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	// The key ID is authenticated, so a payload can't be passed off as encrypted with another key
+	data := aead.Seal(nonce, nonce, plain, []byte(c.keyID))
+
+	return &commonpb.Payload{Metadata: metadata, Data: data}, nil
+}
+
+func (c *Codec) decode(p *commonpb.Payload) (*commonpb.Payload, error) {
+	if string(p.GetMetadata()[converter.MetadataEncoding]) != EncodingEncrypted {
+		return p, nil
+	}
+
+	keyID := string(p.Metadata[MetadataKeyID])
+	aead, ok := c.ciphers[keyID]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownKey, keyID)
+	}
+	if cipherName := string(p.Metadata[MetadataCipher]); cipherName != CipherAESGCM {
+		return nil, fmt.Errorf("unsupported cipher %q", cipherName)
+	}
+	if len(p.Data) < aead.NonceSize() {
+		return nil, errors.New("encrypted payload is too short")
+	}
+
+	nonce, sealed := p.Data[:aead.NonceSize()], p.Data[aead.NonceSize():]
+	plain, err := aead.Open(nil, nonce, sealed, []byte(keyID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt payload with key %q: %w", keyID, err)
+	}
+	if compression := string(p.Metadata[MetadataCompression]); compression != "" {
+		if compression != CompressionZlib {
+			return nil, fmt.Errorf("unsupported compression %q", compression)
+		}
+		if plain, err = decompress(plain); err != nil {
+			return nil, fmt.Errorf("failed to decompress payload: %w", err)
+		}
+	}
+
+	var decoded commonpb.Payload
+	if err := proto.Unmarshal(plain, &decoded); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal payload: %w", err)
+	}
+	return &decoded, nil
+}
+
+func compress(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := zlib.NewWriter(&buf)
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func decompress(data []byte) ([]byte, error) {
+	r, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
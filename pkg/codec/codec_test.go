@@ -0,0 +1,193 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	commonpb "go.temporal.io/api/common/v1"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/converter"
+)
+
+var (
+	key1 = bytes.Repeat([]byte{1}, 32)
+	key2 = bytes.Repeat([]byte{2}, 32)
+)
+
+func newTestCodec(t *testing.T, keyID string, compress bool) *Codec {
+	t.Helper()
+	c, err := New(Options{Keys: map[string][]byte{"k1": key1, "k2": key2}, KeyID: keyID, Compress: compress})
+	require.NoError(t, err)
+	return c
+}
+
+func jsonPayload(t *testing.T, value interface{}) *commonpb.Payload {
+	t.Helper()
+	p, err := converter.GetDefaultDataConverter().ToPayload(value)
+	require.NoError(t, err)
+	return p
+}
+
+func TestCodec_RoundTrip(t *testing.T) {
+	secret := map[string]string{"GITHUB_TOKEN": "ghp_secret"}
+
+	for _, compress := range []bool{false, true} {
+		c := newTestCodec(t, "k1", compress)
+		original := jsonPayload(t, secret)
+
+		encoded, err := c.Encode([]*commonpb.Payload{original})
+		require.NoError(t, err)
+		require.Len(t, encoded, 1)
+		assert.Equal(t, EncodingEncrypted, string(encoded[0].Metadata[converter.MetadataEncoding]))
+		assert.Equal(t, "k1", string(encoded[0].Metadata[MetadataKeyID]))
+		assert.NotContains(t, string(encoded[0].Data), "ghp_secret")
+
+		decoded, err := c.Decode(encoded)
+		require.NoError(t, err)
+		var value map[string]string
+		require.NoError(t, converter.GetDefaultDataConverter().FromPayload(decoded[0], &value))
+		assert.Equal(t, secret, value)
+	}
+}
+
+func TestCodec_Compression(t *testing.T) {
+	output := strings.Repeat("engine-ci build output line\n", 500)
+
+	plain, err := newTestCodec(t, "k1", false).Encode([]*commonpb.Payload{jsonPayload(t, output)})
+	require.NoError(t, err)
+	compressed, err := newTestCodec(t, "k1", true).Encode([]*commonpb.Payload{jsonPayload(t, output)})
+	require.NoError(t, err)
+
+	assert.Equal(t, CompressionZlib, string(compressed[0].Metadata[MetadataCompression]))
+	assert.Less(t, len(compressed[0].Data), len(plain[0].Data)/4)
+
+	// Payloads that don't shrink are not compressed
+	small, err := newTestCodec(t, "k1", true).Encode([]*commonpb.Payload{jsonPayload(t, "x")})
+	require.NoError(t, err)
+	assert.NotContains(t, small[0].Metadata, MetadataCompression)
+}
+
+func TestCodec_KeyRotation(t *testing.T) {
+	old := newTestCodec(t, "k1", false)
+	encoded, err := old.Encode([]*commonpb.Payload{jsonPayload(t, "history")})
+	require.NoError(t, err)
+
+	// After rotating to k2 payloads written with k1 still decode
+	rotated := newTestCodec(t, "k2", false)
+	decoded, err := rotated.Decode(encoded)
+	require.NoError(t, err)
+	var value string
+	require.NoError(t, converter.GetDefaultDataConverter().FromPayload(decoded[0], &value))
+	assert.Equal(t, "history", value)
+
+	// Once k1 is removed they don't
+	k2Only, err := New(Options{Keys: map[string][]byte{"k2": key2}, KeyID: "k2"})
+	require.NoError(t, err)
+	_, err = k2Only.Decode(encoded)
+	assert.True(t, errors.Is(err, ErrUnknownKey))
+}
+
+func TestCodec_RejectsTamperedPayload(t *testing.T) {
+	c := newTestCodec(t, "k1", false)
+	encoded, err := c.Encode([]*commonpb.Payload{jsonPayload(t, "value")})
+	require.NoError(t, err)
+
+	tampered := &commonpb.Payload{Metadata: encoded[0].Metadata, Data: append([]byte(nil), encoded[0].Data...)}
+	tampered.Data[len(tampered.Data)-1] ^= 0xff
+	_, err = c.Decode([]*commonpb.Payload{tampered})
+	assert.Error(t, err)
+
+	// The key ID is authenticated
+	relabeled := &commonpb.Payload{Metadata: map[string][]byte{}, Data: encoded[0].Data}
+	for k, v := range encoded[0].Metadata {
+		relabeled.Metadata[k] = v
+	}
+	relabeled.Metadata[MetadataKeyID] = []byte("k2")
+	_, err = c.Decode([]*commonpb.Payload{relabeled})
+	assert.Error(t, err)
+}
+
+func TestCodec_PassesThroughPlainPayloads(t *testing.T) {
+	c := newTestCodec(t, "k1", false)
+	plain := jsonPayload(t, "unencrypted history")
+
+	decoded, err := c.Decode([]*commonpb.Payload{plain})
+	require.NoError(t, err)
+	assert.Same(t, plain, decoded[0])
+}
+
+func TestNew_InvalidOptions(t *testing.T) {
+	_, err := New(Options{Keys: map[string][]byte{"k1": key1}, KeyID: "k2"})
+	assert.ErrorContains(t, err, `active key "k2" is not configured`)
+
+	_, err = New(Options{Keys: map[string][]byte{"k1": []byte("short")}, KeyID: "k1"})
+	assert.ErrorContains(t, err, `key "k1"`)
+}
+
+func TestFromEnv(t *testing.T) {
+	b64 := base64.StdEncoding.EncodeToString
+
+	t.Run("not configured", func(t *testing.T) {
+		t.Setenv("TEMPORAL_CODEC_KEYS", "")
+		c, err := FromEnv()
+		assert.NoError(t, err)
+		assert.Nil(t, c)
+	})
+
+	t.Run("single key", func(t *testing.T) {
+		t.Setenv("TEMPORAL_CODEC_KEYS", "2024="+b64(key1))
+		c, err := FromEnv()
+		require.NoError(t, err)
+		assert.Equal(t, "2024", c.KeyID())
+	})
+
+	t.Run("several keys need an active key", func(t *testing.T) {
+		t.Setenv("TEMPORAL_CODEC_KEYS", "2024="+b64(key1)+",2025="+b64(key2))
+		_, err := FromEnv()
+		assert.ErrorContains(t, err, "TEMPORAL_CODEC_KEY_ID")
+
+		t.Setenv("TEMPORAL_CODEC_KEY_ID", "2025")
+		c, err := FromEnv()
+		require.NoError(t, err)
+		assert.Equal(t, "2025", c.KeyID())
+	})
+
+	t.Run("keys file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "keys")
+		require.NoError(t, os.WriteFile(path, []byte("# rotated 2025-01\n2025="+b64(key2)+"\n"), 0o600))
+		t.Setenv("TEMPORAL_CODEC_KEYS", "")
+		t.Setenv("TEMPORAL_CODEC_KEYS_FILE", path)
+		t.Setenv("TEMPORAL_CODEC_KEY_ID", "")
+		c, err := FromEnv()
+		require.NoError(t, err)
+		assert.Equal(t, "2025", c.KeyID())
+	})
+
+	t.Run("invalid key", func(t *testing.T) {
+		t.Setenv("TEMPORAL_CODEC_KEYS", "2024=not-base64!")
+		_, err := FromEnv()
+		assert.ErrorContains(t, err, `key "2024" is not valid base64`)
+	})
+}
+
+func TestConfigureClient(t *testing.T) {
+	t.Setenv("TEMPORAL_CODEC_KEYS", "k1="+base64.StdEncoding.EncodeToString(key1))
+
+	var opts client.Options
+	c, err := ConfigureClient(&opts)
+	require.NoError(t, err)
+	require.NotNil(t, c)
+	require.NotNil(t, opts.DataConverter)
+	require.NotNil(t, opts.FailureConverter)
+
+	p, err := opts.DataConverter.ToPayload("secret")
+	require.NoError(t, err)
+	assert.Equal(t, EncodingEncrypted, string(p.Metadata[converter.MetadataEncoding]))
+}
@@ -0,0 +1,153 @@
+package codec
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/converter"
+	"go.temporal.io/sdk/temporal"
+)
+
+// Settings select the keys of a codec, usually from the environment or the worker configuration
+type Settings struct {
+	Keys     string // comma separated `id=base64key` pairs
+	KeysFile string // file with one `id=base64key` pair per line, e.g. a mounted secret
+	KeyID    string // key new payloads are encrypted with, required with more than one key
+	Compress bool   // compress payloads with zlib before encryption
+}
+
+// SettingsFromEnv reads TEMPORAL_CODEC_KEYS, TEMPORAL_CODEC_KEYS_FILE, TEMPORAL_CODEC_KEY_ID and TEMPORAL_CODEC_COMPRESS
+func SettingsFromEnv() (Settings, error) {
+	s := Settings{
+		Keys:     os.Getenv("TEMPORAL_CODEC_KEYS"),
+		KeysFile: os.Getenv("TEMPORAL_CODEC_KEYS_FILE"),
+		KeyID:    os.Getenv("TEMPORAL_CODEC_KEY_ID"),
+	}
+	if value := os.Getenv("TEMPORAL_CODEC_COMPRESS"); value != "" {
+		var err error
+		if s.Compress, err = strconv.ParseBool(value); err != nil {
+			return Settings{}, fmt.Errorf("TEMPORAL_CODEC_COMPRESS: %w", err)
+		}
+	}
+	return s, nil
+}
+
+// FromEnv returns the codec configured by the environment, nil when no key is configured
+//
+//	TEMPORAL_CODEC_KEYS       comma separated `id=base64key` pairs
+//	TEMPORAL_CODEC_KEYS_FILE  file with one `id=base64key` pair per line, e.g. a mounted secret
+//	TEMPORAL_CODEC_KEY_ID     key new payloads are encrypted with, required with more than one key
+//	TEMPORAL_CODEC_COMPRESS   compress payloads with zlib before encryption
+func FromEnv() (*Codec, error) {
+	s, err := SettingsFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	return Load(s)
+}
+
+// Load returns the codec of the settings, nil when no key is configured
+func Load(s Settings) (*Codec, error) {
+	keys := map[string][]byte{}
+	if err := parseKeys(keys, strings.Split(s.Keys, ",")); err != nil {
+		return nil, fmt.Errorf("TEMPORAL_CODEC_KEYS: %w", err)
+	}
+	if s.KeysFile != "" {
+		lines, err := readLines(s.KeysFile)
+		if err != nil {
+			return nil, fmt.Errorf("TEMPORAL_CODEC_KEYS_FILE: %w", err)
+		}
+		if err := parseKeys(keys, lines); err != nil {
+			return nil, fmt.Errorf("TEMPORAL_CODEC_KEYS_FILE: %w", err)
+		}
+	}
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	keyID := s.KeyID
+	if keyID == "" {
+		if len(keys) > 1 {
+			ids := make([]string, 0, len(keys))
+			for id := range keys {
+				ids = append(ids, id)
+			}
+			sort.Strings(ids)
+			return nil, fmt.Errorf("TEMPORAL_CODEC_KEY_ID must select one of the keys %v", ids)
+		}
+		for id := range keys {
+			keyID = id
+		}
+	}
+
+	return New(Options{Keys: keys, KeyID: keyID, Compress: s.Compress})
+}
+
+// ConfigureClient encrypts payloads and failure messages of the client when a codec is configured
+// The workers and the client of a namespace must share the keys
+func ConfigureClient(opts *client.Options) (*Codec, error) {
+	s, err := SettingsFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	return Configure(opts, s)
+}
+
+// Configure is ConfigureClient with explicit settings
+func Configure(opts *client.Options, s Settings) (*Codec, error) {
+	c, err := Load(s)
+	if err != nil || c == nil {
+		return nil, err
+	}
+	opts.DataConverter = converter.NewCodecDataConverter(converter.GetDefaultDataConverter(), c)
+	// Error messages and stack traces can contain the same secrets as the payloads
+	opts.FailureConverter = temporal.NewDefaultFailureConverter(temporal.DefaultFailureConverterOptions{
+		DataConverter:          opts.DataConverter,
+		EncodeCommonAttributes: true,
+	})
+	return c, nil
+}
+
+func parseKeys(keys map[string][]byte, entries []string) error {
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+		id, encoded, ok := strings.Cut(entry, "=")
+		id = strings.TrimSpace(id)
+		if !ok || id == "" {
+			return fmt.Errorf("expected id=base64key, got an entry without id")
+		}
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if err != nil {
+			return fmt.Errorf("key %q is not valid base64", id)
+		}
+		if _, dup := keys[id]; dup {
+			return fmt.Errorf("duplicate key %q", id)
+		}
+		keys[id] = key
+	}
+	return nil
+}
+
+func readLines(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines, scanner.Err()
+}
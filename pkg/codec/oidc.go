@@ -0,0 +1,190 @@
+package codec
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// OIDCOptions validate the access tokens the Temporal UI forwards with its `passAccessToken` setting
+type OIDCOptions struct {
+	// Issuer of the tokens, its signing keys are discovered from <Issuer>/.well-known/openid-configuration
+	Issuer string
+	// Audience must be one of the token's audiences, e.g. the client ID or API of the Temporal UI
+	Audience string
+}
+
+// Key refresh settings of the OIDC verifier
+const (
+	// oidcKeysTTL bounds how long discovered signing keys are used before they are fetched again
+	oidcKeysTTL = time.Hour
+	// oidcRefreshInterval rate limits refreshes caused by tokens signed with unknown keys
+	oidcRefreshInterval = time.Minute
+)
+
+// oidcSigningMethods are the accepted token algorithms, symmetric and unsigned tokens are refused
+var oidcSigningMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}
+
+// oidcVerifier checks the signature, issuer, audience and expiry of access tokens
+type oidcVerifier struct {
+	opts   OIDCOptions
+	client *http.Client
+
+	mu      sync.Mutex
+	keys    map[string]any
+	fetched time.Time
+}
+
+func newOIDCVerifier(opts OIDCOptions) (*oidcVerifier, error) {
+	if opts.Issuer == "" || opts.Audience == "" {
+		return nil, errors.New("OIDC validation requires an issuer and an audience")
+	}
+	return &oidcVerifier{opts: opts, client: &http.Client{Timeout: 10 * time.Second}}, nil
+}
+
+// verify returns an error unless the token is valid
+func (v *oidcVerifier) verify(ctx context.Context, token string) error {
+	_, err := jwt.Parse(token, func(t *jwt.Token) (any, error) {
+		kid, _ := t.Header["kid"].(string)
+		return v.key(ctx, kid)
+	},
+		jwt.WithValidMethods(oidcSigningMethods),
+		jwt.WithIssuer(v.opts.Issuer),
+		jwt.WithAudience(v.opts.Audience),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(time.Minute),
+	)
+	return err
+}
+
+// key returns the signing key with the ID, keys are fetched again when they are stale or the ID is unknown
+func (v *oidcVerifier) key(ctx context.Context, kid string) (any, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	key, ok := v.keys[kid]
+	stale := time.Since(v.fetched) > oidcKeysTTL
+	if ok && !stale {
+		return key, nil
+	}
+	if stale || time.Since(v.fetched) > oidcRefreshInterval {
+		keys, err := v.fetchKeys(ctx)
+		if err != nil {
+			return nil, err
+		}
+		v.keys, v.fetched = keys, time.Now()
+		key, ok = v.keys[kid]
+	}
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	return key, nil
+}
+
+// fetchKeys discovers the JWKS of the issuer and parses its RSA and EC keys
+func (v *oidcVerifier) fetchKeys(ctx context.Context) (map[string]any, error) {
+	var discovery struct {
+		Issuer  string `json:"issuer"`
+		JWKSURI string `json:"jwks_uri"`
+	}
+	if err := v.getJSON(ctx, strings.TrimRight(v.opts.Issuer, "/")+"/.well-known/openid-configuration", &discovery); err != nil {
+		return nil, fmt.Errorf("OIDC discovery: %w", err)
+	}
+	if discovery.Issuer != v.opts.Issuer || discovery.JWKSURI == "" {
+		return nil, fmt.Errorf("OIDC discovery: issuer %q doesn't match %q or has no jwks_uri", discovery.Issuer, v.opts.Issuer)
+	}
+
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := v.getJSON(ctx, discovery.JWKSURI, &set); err != nil {
+		return nil, fmt.Errorf("OIDC keys: %w", err)
+	}
+	keys := make(map[string]any, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		// Keys of unsupported types are skipped, tokens signed with them are rejected as unknown
+		if key, err := jwk.publicKey(); err == nil {
+			keys[jwk.Kid] = key
+		}
+	}
+	return keys, nil
+}
+
+func (v *oidcVerifier) getJSON(ctx context.Context, url string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// jsonWebKey is the subset of RFC 7517 the verifier understands
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (k jsonWebKey) publicKey() (any, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		curves := map[string]elliptic.Curve{"P-256": elliptic.P256(), "P-384": elliptic.P384(), "P-521": elliptic.P521()}
+		curve, ok := curves[k.Crv]
+		if !ok {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		size := (curve.Params().BitSize + 7) / 8
+		if len(x) != size || len(y) != size {
+			return nil, fmt.Errorf("invalid %s key", k.Crv)
+		}
+		return ecdsa.ParseUncompressedPublicKey(curve, append(append([]byte{4}, x...), y...))
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
@@ -0,0 +1,115 @@
+package codec
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	commonpb "go.temporal.io/api/common/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// newTestIssuer serves the discovery document and the JWKS of an identity provider with one RSA key
+func newTestIssuer(t *testing.T, kid string) (*httptest.Server, *rsa.PrivateKey) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	var issuer *httptest.Server
+	issuer = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/.well-known/openid-configuration":
+			_ = json.NewEncoder(w).Encode(map[string]string{"issuer": issuer.URL, "jwks_uri": issuer.URL + "/keys"})
+		case "/keys":
+			_ = json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": kid,
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}}})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(issuer.Close)
+	return issuer, key
+}
+
+func signToken(t *testing.T, key *rsa.PrivateKey, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	require.NoError(t, err)
+	return signed
+}
+
+func TestHandler_OIDC(t *testing.T) {
+	issuer, key := newTestIssuer(t, "k1")
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	c := newTestCodec(t, "k1", false)
+	handler, err := NewHandler(c, HandlerOptions{
+		OIDC:           &OIDCOptions{Issuer: issuer.URL, Audience: "temporal-ui"},
+		AllowedOrigins: []string{"https://temporal.example.com"},
+	})
+	require.NoError(t, err)
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	encoded, err := c.Encode([]*commonpb.Payload{jsonPayload(t, "secret value")})
+	require.NoError(t, err)
+	body, err := protojson.Marshal(&commonpb.Payloads{Payloads: encoded})
+	require.NoError(t, err)
+
+	claims := func(mutate func(jwt.MapClaims)) jwt.MapClaims {
+		c := jwt.MapClaims{"iss": issuer.URL, "aud": "temporal-ui", "sub": "alice", "exp": time.Now().Add(time.Hour).Unix()}
+		if mutate != nil {
+			mutate(c)
+		}
+		return c
+	}
+	tests := []struct {
+		name   string
+		token  string
+		status int
+	}{
+		{name: "valid access token", token: signToken(t, key, "k1", claims(nil)), status: http.StatusOK},
+		{name: "other audience", token: signToken(t, key, "k1", claims(func(c jwt.MapClaims) { c["aud"] = "other" })), status: http.StatusUnauthorized},
+		{name: "other issuer", token: signToken(t, key, "k1", claims(func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" })), status: http.StatusUnauthorized},
+		{name: "expired", token: signToken(t, key, "k1", claims(func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Hour).Unix() })), status: http.StatusUnauthorized},
+		{name: "without expiry", token: signToken(t, key, "k1", claims(func(c jwt.MapClaims) { delete(c, "exp") })), status: http.StatusUnauthorized},
+		{name: "unknown key", token: signToken(t, other, "k2", claims(nil)), status: http.StatusUnauthorized},
+		{name: "forged signature", token: signToken(t, other, "k1", claims(nil)), status: http.StatusUnauthorized},
+		{name: "not a token", token: "token", status: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, server.URL+"/decode", bytes.NewReader(body))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			_ = resp.Body.Close()
+			assert.Equal(t, tt.status, resp.StatusCode)
+		})
+	}
+}
+
+func TestNewOIDCVerifier_RequiresAudience(t *testing.T) {
+	_, err := NewHandler(newTestCodec(t, "k1", false), HandlerOptions{OIDC: &OIDCOptions{Issuer: "https://idp.example.com"}})
+	assert.EqualError(t, err, "OIDC validation requires an issuer and an audience")
+}
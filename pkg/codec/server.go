@@ -0,0 +1,94 @@
+package codec
+
+import (
+	"crypto/subtle"
+	"errors"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+
+	"go.temporal.io/sdk/converter"
+)
+
+// HandlerOptions configure the codec server endpoint
+// At least one of AuthToken and OIDC is required, a request passes with either
+type HandlerOptions struct {
+	// AuthToken may be sent as `Authorization: Bearer <token>`, e.g. by the CLI
+	AuthToken string
+	// OIDC validates the access tokens of the users the Temporal UI forwards with `passAccessToken`
+	OIDC *OIDCOptions
+	// AllowedOrigins may call the endpoint from a browser, e.g. the Temporal UI at https://temporal.example.com
+	// Responses allow credentials, so every origin must be listed, `*` is refused
+	AllowedOrigins []string
+}
+
+// NewHandler returns the codec server endpoint for the Temporal UI and CLI
+// It serves POST /encode and POST /decode below any prefix, requests without a valid token are rejected
+func NewHandler(c *Codec, opts HandlerOptions) (http.Handler, error) {
+	if c == nil {
+		return nil, errors.New("codec server requires a codec")
+	}
+	if opts.AuthToken == "" && opts.OIDC == nil {
+		return nil, errors.New("codec server requires an auth token or OIDC validation")
+	}
+	if slices.Contains(opts.AllowedOrigins, "*") {
+		return nil, errors.New("codec server can't allow every origin, list the origins of the Temporal UI")
+	}
+	var verifier *oidcVerifier
+	if opts.OIDC != nil {
+		var err error
+		if verifier, err = newOIDCVerifier(*opts.OIDC); err != nil {
+			return nil, err
+		}
+	}
+
+	codecHandler := converter.NewPayloadCodecHTTPHandler(c)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if origin := r.Header.Get("Origin"); origin != "" && allowedOrigin(opts.AllowedOrigins, origin) {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Access-Control-Allow-Credentials", "true")
+			w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type, X-Namespace")
+			w.Header().Add("Vary", "Origin")
+		}
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		if !authorized(r, opts.AuthToken, verifier) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		codecHandler.ServeHTTP(w, r)
+	}), nil
+}
+
+func authorized(r *http.Request, token string, verifier *oidcVerifier) bool {
+	auth := r.Header.Get("Authorization")
+	provided, ok := strings.CutPrefix(auth, "Bearer ")
+	if !ok || provided == "" {
+		return false
+	}
+	if token != "" && subtle.ConstantTimeCompare([]byte(provided), []byte(token)) == 1 {
+		return true
+	}
+	if verifier == nil {
+		return false
+	}
+	if err := verifier.verify(r.Context(), provided); err != nil {
+		slog.Debug("Rejected codec server token", "error", err)
+		return false
+	}
+	return true
+}
+
+func allowedOrigin(allowed []string, origin string) bool {
+	for _, o := range allowed {
+		if strings.EqualFold(strings.TrimRight(o, "/"), origin) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,95 @@
+package codec
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	commonpb "go.temporal.io/api/common/v1"
+	"go.temporal.io/sdk/converter"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+func TestHandler(t *testing.T) {
+	c := newTestCodec(t, "k1", false)
+	handler, err := NewHandler(c, HandlerOptions{AuthToken: "token", AllowedOrigins: []string{"https://temporal.example.com"}})
+	require.NoError(t, err)
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	encoded, err := c.Encode([]*commonpb.Payload{jsonPayload(t, "secret value")})
+	require.NoError(t, err)
+	body, err := protojson.Marshal(&commonpb.Payloads{Payloads: encoded})
+	require.NoError(t, err)
+
+	post := func(token string) *http.Response {
+		req, err := http.NewRequest(http.MethodPost, server.URL+"/decode", bytes.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Origin", "https://temporal.example.com")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = resp.Body.Close() })
+		return resp
+	}
+
+	t.Run("decodes with token", func(t *testing.T) {
+		resp := post("token")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "https://temporal.example.com", resp.Header.Get("Access-Control-Allow-Origin"))
+
+		var buf bytes.Buffer
+		_, err := buf.ReadFrom(resp.Body)
+		require.NoError(t, err)
+		var decoded commonpb.Payloads
+		require.NoError(t, protojson.Unmarshal(buf.Bytes(), &decoded))
+		var value string
+		require.NoError(t, converter.GetDefaultDataConverter().FromPayload(decoded.Payloads[0], &value))
+		assert.Equal(t, "secret value", value)
+	})
+
+	t.Run("rejects missing token", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnauthorized, post("").StatusCode)
+	})
+
+	t.Run("rejects wrong token", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnauthorized, post("other").StatusCode)
+	})
+
+	t.Run("answers preflight", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodOptions, server.URL+"/decode", nil)
+		require.NoError(t, err)
+		req.Header.Set("Origin", "https://temporal.example.com")
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+		assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+		assert.Contains(t, resp.Header.Get("Access-Control-Allow-Headers"), "Authorization")
+	})
+
+	t.Run("ignores unknown origins", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodOptions, server.URL+"/decode", nil)
+		require.NoError(t, err)
+		req.Header.Set("Origin", "https://evil.example.com")
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+		assert.Empty(t, resp.Header.Get("Access-Control-Allow-Origin"))
+	})
+}
+
+func TestNewHandler_RequiresToken(t *testing.T) {
+	_, err := NewHandler(newTestCodec(t, "k1", false), HandlerOptions{})
+	assert.ErrorContains(t, err, "auth token")
+}
+
+func TestNewHandler_RefusesWildcardOrigin(t *testing.T) {
+	_, err := NewHandler(newTestCodec(t, "k1", false), HandlerOptions{AuthToken: "token", AllowedOrigins: []string{"*"}})
+	assert.EqualError(t, err, "codec server can't allow every origin, list the origins of the Temporal UI")
+}
@@ -0,0 +1,136 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+
+	"go.temporal.io/sdk/worker"
+	"gopkg.in/yaml.v3"
+
+	"github.com/containifyci/temporal-worker/pkg/artifacts"
+	"github.com/containifyci/temporal-worker/pkg/codec"
+	"github.com/containifyci/temporal-worker/pkg/connection"
+	"github.com/containifyci/temporal-worker/pkg/logging"
+	"github.com/containifyci/temporal-worker/pkg/tracing"
+	"github.com/containifyci/temporal-worker/pkg/versioning"
+	"github.com/containifyci/temporal-worker/pkg/workflows/engineci"
+	"github.com/containifyci/temporal-worker/pkg/workflows/golangmajor"
+)
+
+// maskedValue replaces secret settings in the printed configuration
+const maskedValue = "********"
+
+// Apply sets the defaults of the workflows and activities from the configuration, call it before the worker starts
+func (c Config) Apply() error {
+	secrets, err := engineci.NewSecretProvider(c.EngineCI.SecretsProvider, c.EngineCI.SecretsFile)
+	if err != nil {
+		return fmt.Errorf("engineCI.secretsProvider: %w", err)
+	}
+	engineci.Secrets = secrets
+	engineci.AllowedCommands = c.EngineCI.AllowedCommands
+	engineci.AllowedEnvKeys = c.EngineCI.AllowedEnvKeys
+	engineci.CacheRoot = c.EngineCI.CacheDir
+	engineci.CacheMaxSize = c.EngineCI.CacheMaxSize
+	engineci.ResultCacheTTL = c.EngineCI.ResultCacheTTL
+	engineci.ArtifactRetention = c.EngineCI.ArtifactRetention
+	location := c.EngineCI.ArtifactStore
+	engineci.NewArtifactStore = func() (artifacts.Store, error) { return artifacts.NewStore(location) }
+	engineci.IdleTimeout = c.EngineCI.IdleTimeout
+	engineci.MaxInfraRetries = c.EngineCI.MaxInfraRetries
+	engineci.InfraRetryBackoff = c.EngineCI.InfraRetryBackoff
+	engineci.LabelScheduleToStartTimeout = c.EngineCI.LabelScheduleToStartTimeout
+	engineci.CallbackDeliveryTimeout = c.EngineCI.CallbackDeliveryTimeout
+	engineci.CallbackAllowedHosts = c.EngineCI.CallbackAllowedHosts
+	engineci.Labels = c.EngineCI.Labels
+	engineci.LabelDetection = c.EngineCI.DetectLabels
+	engineci.LabelSets = c.EngineCI.LabelSets
+
+	golangmajor.DefaultOrganization = c.GoMajor.Organization
+	golangmajor.DefaultMaxConcurrency = c.GoMajor.MaxConcurrency
+	golangmajor.DefaultOpenPullRequestsLimit = c.GoMajor.OpenPullRequestsLimit
+	return nil
+}
+
+// Options returns the options of the Temporal worker
+func (w Worker) Options() worker.Options {
+	return worker.Options{
+		MaxConcurrentWorkflowTaskExecutionSize: w.MaxConcurrentWorkflows,
+		MaxConcurrentActivityExecutionSize:     w.MaxConcurrentActivities,
+		StickyScheduleToStartTimeout:           w.StickyScheduleToStartTimeout,
+		WorkerStopTimeout:                      w.DrainTimeout,
+	}
+}
+
+// Settings returns the versioning settings of the workers, the build ID defaults to version, the release version of
+// the binary
+func (v Versioning) Settings(version string) versioning.Settings {
+	buildID := v.BuildID
+	if buildID == "" {
+		buildID = version
+	}
+	return versioning.Settings{
+		Enabled:         v.Enabled,
+		DeploymentName:  v.DeploymentName,
+		BuildID:         buildID,
+		DefaultBehavior: v.DefaultBehavior,
+	}
+}
+
+// Settings returns the settings of the logger
+func (l Logging) Settings() logging.Settings {
+	return logging.Settings{Format: l.Format, Level: l.Level, AddSource: l.AddSource}
+}
+
+// Settings returns the settings of the Temporal connection
+func (t Temporal) Settings() connection.Settings {
+	return connection.Settings{
+		HostPort:   t.HostPort,
+		Namespace:  t.Namespace,
+		TLS:        t.TLS.Enabled,
+		CAFile:     t.TLS.CAFile,
+		CertFile:   t.TLS.CertFile,
+		KeyFile:    t.TLS.KeyFile,
+		ServerName: t.TLS.ServerName,
+		APIKey:     t.APIKey,
+		APIKeyFile: t.APIKeyFile,
+	}
+}
+
+// Settings returns the settings of the payload codec
+func (c Codec) Settings() codec.Settings {
+	return codec.Settings{Keys: c.Keys, KeysFile: c.KeysFile, KeyID: c.KeyID, Compress: c.Compress}
+}
+
+// Settings returns the settings of the span exporter, version is the service version of the spans
+func (t Tracing) Settings(version string) tracing.Settings {
+	return tracing.Settings{
+		Exporter:       t.Exporter,
+		Endpoint:       t.Endpoint,
+		Protocol:       t.Protocol,
+		File:           t.File,
+		ServiceName:    t.ServiceName,
+		ServiceVersion: version,
+	}
+}
+
+// Masked returns a copy with the secret settings masked
+func (c Config) Masked() Config {
+	masked := c
+	walk(reflect.ValueOf(&masked).Elem(), func(field reflect.StructField, value reflect.Value) {
+		if field.Tag.Get("secret") == "true" && !value.IsZero() {
+			value.SetString(maskedValue)
+		}
+	})
+	return masked
+}
+
+// Print writes the configuration as YAML with the secret settings masked
+func (c Config) Print(w io.Writer) error {
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(c.Masked()); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
@@ -0,0 +1,315 @@
+// Package config loads the configuration of the workers from a YAML file and environment overrides
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/containifyci/temporal-worker/pkg/logging"
+	"github.com/containifyci/temporal-worker/pkg/versioning"
+	"github.com/containifyci/temporal-worker/pkg/workflows/engineci"
+	"github.com/containifyci/temporal-worker/pkg/workflows/golangmajor"
+)
+
+// PathEnv names the environment variable holding the path of the configuration file
+const PathEnv = "TEMPORAL_WORKER_CONFIG"
+
+// Config is the configuration of a worker
+// Every setting can be overridden by the environment variable in its env tag, settings tagged
+// secret are masked when the configuration is printed
+type Config struct {
+	Temporal Temporal `yaml:"temporal"`
+	Codec    Codec    `yaml:"codec"`
+	Logging  Logging  `yaml:"logging"`
+	Worker   Worker   `yaml:"worker"`
+	Update   Update   `yaml:"update"`
+	HTTP     HTTP     `yaml:"http"`
+	Tracing  Tracing  `yaml:"tracing"`
+	EngineCI EngineCI `yaml:"engineCI"`
+	GoMajor  GoMajor  `yaml:"goMajor"`
+}
+
+// Temporal is the connection to the Temporal service, see connection.Settings
+type Temporal struct {
+	HostPort   string `yaml:"hostPort" env:"TEMPORAL_HOST"`
+	Namespace  string `yaml:"namespace" env:"TEMPORAL_NAMESPACE"`
+	TLS        TLS    `yaml:"tls"`
+	APIKey     string `yaml:"apiKey" env:"TEMPORAL_API_KEY" secret:"true"`
+	APIKeyFile string `yaml:"apiKeyFile" env:"TEMPORAL_API_KEY_FILE"`
+}
+
+// TLS secures the connection to the Temporal service, setting any file enables it
+type TLS struct {
+	Enabled    bool   `yaml:"enabled" env:"TEMPORAL_TLS"`
+	CAFile     string `yaml:"caFile" env:"TEMPORAL_TLS_CA"`
+	CertFile   string `yaml:"certFile" env:"TEMPORAL_TLS_CERT"` // client certificate for mTLS, reloaded on rotation
+	KeyFile    string `yaml:"keyFile" env:"TEMPORAL_TLS_KEY"`
+	ServerName string `yaml:"serverName" env:"TEMPORAL_TLS_SERVER_NAME"`
+}
+
+// Codec configures payload encryption, see codec.Settings
+type Codec struct {
+	Keys     string `yaml:"keys" env:"TEMPORAL_CODEC_KEYS" secret:"true"`
+	KeysFile string `yaml:"keysFile" env:"TEMPORAL_CODEC_KEYS_FILE"`
+	KeyID    string `yaml:"keyID" env:"TEMPORAL_CODEC_KEY_ID"`
+	Compress bool   `yaml:"compress" env:"TEMPORAL_CODEC_COMPRESS"`
+}
+
+// Logging configures the worker's logger
+type Logging struct {
+	Format    string `yaml:"format" env:"LOG_FORMAT"` // pretty, json or logfmt
+	Level     string `yaml:"level" env:"LOG_LEVEL"`   // debug, info, warn or error, changes at runtime, see logging.LevelHandler
+	AddSource bool   `yaml:"addSource" env:"LOG_ADD_SOURCE"`
+}
+
+// Worker configures the workflow bundles, their task queues and the limits of the Temporal workers
+type Worker struct {
+	Bundles                      []string          `yaml:"bundles" env:"WORKER_BUNDLES"`        // see bundle.All
+	TaskQueues                   map[string]string `yaml:"taskQueues" env:"WORKER_TASK_QUEUES"` // bundle name to task queue, e.g. engineci=engine-ci-large
+	MaxConcurrentWorkflows       int               `yaml:"maxConcurrentWorkflows" env:"WORKER_MAX_CONCURRENT_WORKFLOWS"`
+	MaxConcurrentActivities      int               `yaml:"maxConcurrentActivities" env:"WORKER_MAX_CONCURRENT_ACTIVITIES"`
+	StickyScheduleToStartTimeout time.Duration     `yaml:"stickyScheduleToStartTimeout" env:"WORKER_STICKY_SCHEDULE_TO_START_TIMEOUT"`
+	DrainTimeout                 time.Duration     `yaml:"drainTimeout" env:"WORKER_DRAIN_TIMEOUT"` // how long running activities may finish on shutdown
+	Versioning                   Versioning        `yaml:"versioning"`
+}
+
+// Versioning registers the workers as a version of a Temporal Worker Deployment, see versioning.Settings
+type Versioning struct {
+	Enabled         bool   `yaml:"enabled" env:"WORKER_VERSIONING"`
+	DeploymentName  string `yaml:"deploymentName" env:"WORKER_DEPLOYMENT_NAME"`
+	BuildID         string `yaml:"buildID" env:"WORKER_BUILD_ID"`                    // defaults to the release version of the binary
+	DefaultBehavior string `yaml:"defaultBehavior" env:"WORKER_VERSIONING_BEHAVIOR"` // pinned or autoUpgrade
+}
+
+// Update configures the background updater of the worker, see autoupdate.Updater
+type Update struct {
+	Enabled     bool          `yaml:"enabled" env:"WORKER_AUTO_UPDATE"`
+	Interval    time.Duration `yaml:"interval" env:"WORKER_UPDATE_INTERVAL"`        // between release checks
+	GracePeriod time.Duration `yaml:"gracePeriod" env:"WORKER_UPDATE_GRACE_PERIOD"` // a new release has to be ready within, it is rolled back otherwise
+}
+
+// HTTP configures the health and metrics endpoints, they are disabled without a listen address
+// The log level endpoint changes the worker, it is served on the admin address which defaults to localhost
+type HTTP struct {
+	Listen      string `yaml:"listen" env:"WORKER_HTTP_LISTEN"`            // e.g. :9090
+	AdminListen string `yaml:"adminListen" env:"WORKER_HTTP_ADMIN_LISTEN"` // e.g. localhost:9091
+}
+
+// Tracing configures the OpenTelemetry span exporter, see tracing.Settings
+type Tracing struct {
+	Exporter    string `yaml:"exporter" env:"OTEL_TRACES_EXPORTER"` // none, otlp, stdout or file
+	Endpoint    string `yaml:"endpoint" env:"OTEL_EXPORTER_OTLP_ENDPOINT"`
+	Protocol    string `yaml:"protocol" env:"OTEL_EXPORTER_OTLP_PROTOCOL"` // grpc or http/protobuf
+	File        string `yaml:"file" env:"OTEL_TRACES_FILE"`
+	ServiceName string `yaml:"serviceName" env:"OTEL_SERVICE_NAME"`
+}
+
+// EngineCI holds the defaults of the Engine-CI workflows and activities
+// The workflow settings (idle timeout, retries, timeouts) must be the same on all workers of a queue
+type EngineCI struct {
+	AllowedCommands             []string      `yaml:"allowedCommands" env:"ENGINE_CI_ALLOWED_COMMANDS"`
+	AllowedEnvKeys              []string      `yaml:"allowedEnvKeys" env:"ENGINE_CI_ALLOWED_ENV_KEYS"`
+	Labels                      []string      `yaml:"labels" env:"ENGINE_CI_WORKER_LABELS"`
+	DetectLabels                bool          `yaml:"detectLabels" env:"ENGINE_CI_DETECT_LABELS"`
+	LabelSets                   []string      `yaml:"labelSets" env:"ENGINE_CI_LABEL_SETS"`
+	CacheDir                    string        `yaml:"cacheDir" env:"ENGINE_CI_CACHE_DIR"`
+	CacheMaxSize                int64         `yaml:"cacheMaxSize" env:"ENGINE_CI_CACHE_MAX_SIZE"` // bytes
+	ResultCacheTTL              time.Duration `yaml:"resultCacheTTL" env:"ENGINE_CI_RESULT_CACHE_TTL"`
+	ArtifactStore               string        `yaml:"artifactStore" env:"ARTIFACT_STORE"`
+	ArtifactRetention           time.Duration `yaml:"artifactRetention" env:"ARTIFACT_RETENTION"`
+	SecretsProvider             string        `yaml:"secretsProvider" env:"ENGINE_CI_SECRETS_PROVIDER"`
+	SecretsFile                 string        `yaml:"secretsFile" env:"ENGINE_CI_SECRETS_FILE"`
+	IdleTimeout                 time.Duration `yaml:"idleTimeout" env:"ENGINE_CI_IDLE_TIMEOUT"`
+	MaxInfraRetries             int           `yaml:"maxInfraRetries" env:"ENGINE_CI_MAX_INFRA_RETRIES"`
+	InfraRetryBackoff           time.Duration `yaml:"infraRetryBackoff" env:"ENGINE_CI_INFRA_RETRY_BACKOFF"`
+	LabelScheduleToStartTimeout time.Duration `yaml:"labelScheduleToStartTimeout" env:"ENGINE_CI_LABEL_SCHEDULE_TO_START_TIMEOUT"`
+	CallbackDeliveryTimeout     time.Duration `yaml:"callbackDeliveryTimeout" env:"ENGINE_CI_CALLBACK_DELIVERY_TIMEOUT"`
+	CallbackAllowedHosts        []string      `yaml:"callbackAllowedHosts" env:"ENGINE_CI_CALLBACK_ALLOWED_HOSTS"`
+}
+
+// GoMajor holds the defaults of the Go major upgrade workflows
+type GoMajor struct {
+	Organization          string `yaml:"organization" env:"GITHUB_ORGANIZATION"`
+	MaxConcurrency        int    `yaml:"maxConcurrency" env:"GOMAJOR_MAX_CONCURRENCY"`
+	OpenPullRequestsLimit int    `yaml:"openPullRequestsLimit" env:"GOMAJOR_OPEN_PULL_REQUESTS_LIMIT"`
+}
+
+// Default returns the built-in configuration of a worker
+func Default() Config {
+	return Config{
+		Temporal: Temporal{
+			HostPort:  "localhost:7233",
+			Namespace: "default",
+		},
+		Logging: Logging{
+			Format: logging.FormatPretty,
+			Level:  "info",
+		},
+		Worker: Worker{
+			MaxConcurrentWorkflows:       2,
+			MaxConcurrentActivities:      4,
+			StickyScheduleToStartTimeout: 10 * time.Minute,
+			DrainTimeout:                 10 * time.Minute,
+			Versioning: Versioning{
+				DeploymentName:  versioning.DefaultDeploymentName,
+				DefaultBehavior: versioning.BehaviorPinned,
+			},
+		},
+		Update: Update{
+			Interval:    time.Hour,
+			GracePeriod: 5 * time.Minute,
+		},
+		HTTP: HTTP{
+			AdminListen: "localhost:9091",
+		},
+		EngineCI: EngineCI{
+			DetectLabels:                true,
+			CacheDir:                    engineci.CacheRoot,
+			CacheMaxSize:                engineci.CacheMaxSize,
+			ResultCacheTTL:              engineci.ResultCacheTTL,
+			ArtifactRetention:           engineci.ArtifactRetention,
+			SecretsProvider:             engineci.SecretProviderEnv,
+			IdleTimeout:                 engineci.IdleTimeout,
+			MaxInfraRetries:             engineci.MaxInfraRetries,
+			InfraRetryBackoff:           engineci.InfraRetryBackoff,
+			LabelScheduleToStartTimeout: engineci.LabelScheduleToStartTimeout,
+			CallbackDeliveryTimeout:     engineci.CallbackDeliveryTimeout,
+		},
+		GoMajor: GoMajor{
+			Organization:          golangmajor.DefaultOrganization,
+			MaxConcurrency:        golangmajor.DefaultMaxConcurrency,
+			OpenPullRequestsLimit: golangmajor.DefaultOpenPullRequestsLimit,
+		},
+	}
+}
+
+// Load reads the configuration file at path on top of defaults, applies the environment overrides and validates the result
+// An empty path uses the file named by TEMPORAL_WORKER_CONFIG, without one only the defaults and the environment apply
+func Load(path string, defaults Config) (*Config, error) {
+	if path == "" {
+		path = os.Getenv(PathEnv)
+	}
+
+	cfg := defaults
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config: %w", err)
+		}
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("failed to parse config %s: %w", path, err)
+		}
+	}
+	if err := applyEnv(&cfg); err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	return &cfg, nil
+}
+
+// Validate checks the configuration before the worker starts
+func (c Config) Validate() error {
+	var errs []error
+
+	if _, _, err := net.SplitHostPort(c.Temporal.HostPort); err != nil {
+		errs = append(errs, fmt.Errorf("temporal.hostPort %q must be host:port", c.Temporal.HostPort))
+	}
+	if (c.Temporal.TLS.CertFile == "") != (c.Temporal.TLS.KeyFile == "") {
+		errs = append(errs, errors.New("temporal.tls.certFile and temporal.tls.keyFile must be set together"))
+	}
+	if c.Temporal.APIKey != "" && c.Temporal.APIKeyFile != "" {
+		errs = append(errs, errors.New("set either temporal.apiKey or temporal.apiKeyFile"))
+	}
+	if c.Temporal.Namespace == "" {
+		errs = append(errs, errors.New("temporal.namespace must not be empty"))
+	}
+	if err := c.Logging.Settings().Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("logging: %w", err))
+	}
+	for name, queue := range c.Worker.TaskQueues {
+		if queue == "" {
+			errs = append(errs, fmt.Errorf("worker.taskQueues.%s must not be empty", name))
+		}
+	}
+	if c.Worker.MaxConcurrentWorkflows < 1 {
+		errs = append(errs, errors.New("worker.maxConcurrentWorkflows must be at least 1"))
+	}
+	if c.Worker.MaxConcurrentActivities < 1 {
+		errs = append(errs, errors.New("worker.maxConcurrentActivities must be at least 1"))
+	}
+	if c.Worker.StickyScheduleToStartTimeout < 0 {
+		errs = append(errs, errors.New("worker.stickyScheduleToStartTimeout must not be negative"))
+	}
+	if c.Worker.DrainTimeout < 0 {
+		errs = append(errs, errors.New("worker.drainTimeout must not be negative"))
+	}
+	// Development builds are versioned as dev unless worker.versioning.buildID is set
+	if err := c.Worker.Versioning.Settings("dev").Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("worker.versioning: %w", err))
+	}
+	if c.Update.Interval <= 0 {
+		errs = append(errs, errors.New("update.interval must be positive"))
+	}
+	if c.Update.GracePeriod <= 0 {
+		errs = append(errs, errors.New("update.gracePeriod must be positive"))
+	}
+	if c.HTTP.Listen != "" {
+		if _, _, err := net.SplitHostPort(c.HTTP.Listen); err != nil {
+			errs = append(errs, fmt.Errorf("http.listen %q must be [host]:port", c.HTTP.Listen))
+		}
+	}
+	if c.HTTP.AdminListen != "" {
+		if _, _, err := net.SplitHostPort(c.HTTP.AdminListen); err != nil {
+			errs = append(errs, fmt.Errorf("http.adminListen %q must be [host]:port", c.HTTP.AdminListen))
+		}
+	}
+
+	if err := c.Tracing.Settings("").Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("tracing: %w", err))
+	}
+
+	if _, err := engineci.NewSecretProvider(c.EngineCI.SecretsProvider, c.EngineCI.SecretsFile); err != nil {
+		errs = append(errs, fmt.Errorf("engineCI.secretsProvider: %w", err))
+	}
+	if c.EngineCI.CacheMaxSize < 0 {
+		errs = append(errs, errors.New("engineCI.cacheMaxSize must not be negative"))
+	}
+	if c.EngineCI.MaxInfraRetries < 0 {
+		errs = append(errs, errors.New("engineCI.maxInfraRetries must not be negative"))
+	}
+	for _, d := range []struct {
+		name  string
+		value time.Duration
+	}{
+		{"engineCI.resultCacheTTL", c.EngineCI.ResultCacheTTL},
+		{"engineCI.artifactRetention", c.EngineCI.ArtifactRetention},
+		{"engineCI.idleTimeout", c.EngineCI.IdleTimeout},
+		{"engineCI.infraRetryBackoff", c.EngineCI.InfraRetryBackoff},
+		{"engineCI.labelScheduleToStartTimeout", c.EngineCI.LabelScheduleToStartTimeout},
+		{"engineCI.callbackDeliveryTimeout", c.EngineCI.CallbackDeliveryTimeout},
+	} {
+		if d.value <= 0 {
+			errs = append(errs, fmt.Errorf("%s must be positive", d.name))
+		}
+	}
+
+	if c.GoMajor.MaxConcurrency < 1 {
+		errs = append(errs, errors.New("goMajor.maxConcurrency must be at least 1"))
+	}
+	if c.GoMajor.OpenPullRequestsLimit < 1 {
+		errs = append(errs, errors.New("goMajor.openPullRequestsLimit must be at least 1"))
+	}
+
+	return errors.Join(errs...)
+}
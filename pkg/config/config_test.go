@@ -0,0 +1,211 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/containifyci/temporal-worker/pkg/workflows/engineci"
+	"github.com/containifyci/temporal-worker/pkg/workflows/golangmajor"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "worker.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoad_Defaults(t *testing.T) {
+	t.Setenv(PathEnv, "")
+
+	cfg, err := Load("", Default())
+	require.NoError(t, err)
+	assert.Equal(t, "localhost:7233", cfg.Temporal.HostPort)
+	assert.Equal(t, "default", cfg.Temporal.Namespace)
+	assert.Equal(t, Logging{Format: "pretty", Level: "info"}, cfg.Logging)
+	assert.Empty(t, cfg.Worker.Bundles)
+	assert.Equal(t, 2, cfg.Worker.MaxConcurrentWorkflows)
+	assert.Equal(t, 4, cfg.Worker.MaxConcurrentActivities)
+	assert.Equal(t, 10*time.Minute, cfg.Worker.StickyScheduleToStartTimeout)
+}
+
+func TestLoad_FileAndEnvironment(t *testing.T) {
+	path := writeConfig(t, `
+temporal:
+  hostPort: temporal.example.com:7233
+  namespace: ci
+logging:
+  level: info
+worker:
+  bundles: [engineci]
+  taskQueues:
+    engineci: engine-ci-large
+  maxConcurrentActivities: 8
+engineCI:
+  allowedCommands: [make, ./scripts/ci.sh]
+  idleTimeout: 5m
+`)
+	t.Setenv(PathEnv, path)
+	t.Setenv("TEMPORAL_NAMESPACE", "ci-staging")
+	t.Setenv("LOG_FORMAT", "json")
+	t.Setenv("ENGINE_CI_ALLOWED_ENV_KEYS", "CI_*, GOPRIVATE")
+	t.Setenv("ENGINE_CI_DETECT_LABELS", "false")
+	t.Setenv("WORKER_TASK_QUEUES", "golangmajor=upgrades, diagnostics = upgrades")
+
+	cfg, err := Load("", Default())
+	require.NoError(t, err)
+	assert.Equal(t, "temporal.example.com:7233", cfg.Temporal.HostPort)
+	assert.Equal(t, "ci-staging", cfg.Temporal.Namespace, "the environment wins over the file")
+	assert.Equal(t, "info", cfg.Logging.Level)
+	assert.Equal(t, "json", cfg.Logging.Format)
+	assert.Equal(t, []string{"engineci"}, cfg.Worker.Bundles)
+	assert.Equal(t, map[string]string{"golangmajor": "upgrades", "diagnostics": "upgrades"}, cfg.Worker.TaskQueues, "the environment replaces the map")
+	assert.Equal(t, 2, cfg.Worker.MaxConcurrentWorkflows, "unset settings keep their default")
+	assert.Equal(t, 8, cfg.Worker.MaxConcurrentActivities)
+	assert.Equal(t, []string{"make", "./scripts/ci.sh"}, cfg.EngineCI.AllowedCommands)
+	assert.Equal(t, []string{"CI_*", "GOPRIVATE"}, cfg.EngineCI.AllowedEnvKeys)
+	assert.False(t, cfg.EngineCI.DetectLabels)
+	assert.Equal(t, 5*time.Minute, cfg.EngineCI.IdleTimeout)
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	t.Setenv(PathEnv, "")
+
+	tests := []struct {
+		name    string
+		content string
+		env     map[string]string
+		errs    []string
+	}{
+		{
+			name:    "unknown setting",
+			content: "worker:\n  queue: x\n",
+			errs:    []string{"field queue not found"},
+		},
+		{
+			name: "invalid environment value",
+			env:  map[string]string{"WORKER_MAX_CONCURRENT_ACTIVITIES": "many", "WORKER_TASK_QUEUES": "engineci"},
+			errs: []string{"WORKER_MAX_CONCURRENT_ACTIVITIES", `WORKER_TASK_QUEUES: "engineci" must be key=value`},
+		},
+		{
+			name: "incomplete temporal credentials",
+			env:  map[string]string{"TEMPORAL_TLS_CERT": "/etc/temporal/client.pem", "TEMPORAL_API_KEY": "secret", "TEMPORAL_API_KEY_FILE": "/run/secrets/api-key"},
+			errs: []string{"temporal.tls.certFile and temporal.tls.keyFile must be set together", "either temporal.apiKey or temporal.apiKeyFile"},
+		},
+		{
+			name: "invalid tracing",
+			env:  map[string]string{"OTEL_TRACES_EXPORTER": "jaeger", "OTEL_EXPORTER_OTLP_PROTOCOL": "http/json"},
+			errs: []string{`tracing: unknown exporter "jaeger"`, `unknown OTLP protocol "http/json"`},
+		},
+		{
+			name: "invalid log format",
+			env:  map[string]string{"LOG_FORMAT": "xml"},
+			errs: []string{`logging: unknown format "xml", use pretty, json or logfmt`},
+		},
+		{
+			name:    "invalid values",
+			content: "temporal:\n  hostPort: localhost\nlogging:\n  level: loud\nhttp:\n  listen: \"9090\"\n  adminListen: localhost\nworker:\n  maxConcurrentWorkflows: 0\n  drainTimeout: -1m\n  versioning:\n    enabled: true\n    defaultBehavior: latest\nupdate:\n  gracePeriod: 0s\nengineCI:\n  secretsProvider: vault\n  idleTimeout: 0s\n",
+			errs: []string{
+				`temporal.hostPort "localhost" must be host:port`,
+				`logging: unknown level "loud"`,
+				`http.listen "9090" must be [host]:port`,
+				`http.adminListen "localhost" must be [host]:port`,
+				"worker.maxConcurrentWorkflows must be at least 1",
+				"worker.drainTimeout must not be negative",
+				`worker.versioning: unknown default behavior "latest"`,
+				"update.gracePeriod must be positive",
+				`unknown secret provider "vault"`,
+				"engineCI.idleTimeout must be positive",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			path := ""
+			if tt.content != "" {
+				path = writeConfig(t, tt.content)
+			}
+			_, err := Load(path, Default())
+			require.Error(t, err)
+			for _, msg := range tt.errs {
+				assert.ErrorContains(t, err, msg)
+			}
+		})
+	}
+}
+
+func TestPrint_MasksSecrets(t *testing.T) {
+	cfg := Default()
+	cfg.Codec.Keys = "2025=c2VjcmV0LWtleQ=="
+	cfg.Codec.KeyID = "2025"
+	cfg.Temporal.APIKey = "tmprl-service-account-key"
+
+	var out bytes.Buffer
+	require.NoError(t, cfg.Print(&out))
+	assert.NotContains(t, out.String(), "c2VjcmV0LWtleQ")
+	assert.NotContains(t, out.String(), "tmprl-service-account-key")
+	assert.Contains(t, out.String(), "keys: '********'")
+	assert.Contains(t, out.String(), "keyID: \"2025\"")
+	assert.Contains(t, out.String(), "stickyScheduleToStartTimeout: 10m0s")
+	assert.Equal(t, "2025=c2VjcmV0LWtleQ==", cfg.Codec.Keys, "the configuration itself is not modified")
+
+	// The printed configuration can be loaded again
+	printed := writeConfig(t, out.String())
+	_, err := Load(printed, Default())
+	require.NoError(t, err)
+}
+
+func TestApply(t *testing.T) {
+	defer func(commands, keys []string, idle time.Duration, org string) {
+		engineci.AllowedCommands, engineci.AllowedEnvKeys, engineci.IdleTimeout = commands, keys, idle
+		golangmajor.DefaultOrganization = org
+	}(engineci.AllowedCommands, engineci.AllowedEnvKeys, engineci.IdleTimeout, golangmajor.DefaultOrganization)
+	defer func(secrets engineci.SecretProvider) { engineci.Secrets = secrets }(engineci.Secrets)
+	defer func(labels []string) { engineci.Labels = labels }(engineci.Labels)
+
+	cfg := Default()
+	cfg.EngineCI.AllowedCommands = []string{"make"}
+	cfg.EngineCI.AllowedEnvKeys = []string{"CI_*"}
+	cfg.EngineCI.IdleTimeout = 3 * time.Minute
+	cfg.EngineCI.SecretsProvider = engineci.SecretProviderDotenv
+	cfg.EngineCI.SecretsFile = writeConfig(t, "NPM_TOKEN=npm_123\n")
+	cfg.EngineCI.Labels = []string{"gpu"}
+	cfg.EngineCI.DetectLabels = false
+	cfg.GoMajor.Organization = "acme"
+	require.NoError(t, cfg.Apply())
+
+	assert.Equal(t, []string{"make"}, engineci.AllowedCommands)
+	assert.Equal(t, []string{"CI_*"}, engineci.AllowedEnvKeys)
+	assert.Equal(t, 3*time.Minute, engineci.IdleTimeout)
+	assert.Equal(t, []string{"gpu"}, engineci.Labels)
+	token, err := engineci.Secrets.Secret("NPM_TOKEN")
+	require.NoError(t, err)
+	assert.Equal(t, "npm_123", token)
+
+	t.Setenv("GITHUB_ORGANIZATION", "")
+	inputs := golangmajor.GoMajorSweepWorkflowInputs{}
+	inputs.Defaults()
+	assert.Equal(t, "acme", inputs.Organization)
+}
+
+func TestVersioningSettings(t *testing.T) {
+	cfg := Default()
+	cfg.Worker.Versioning.Enabled = true
+	settings := cfg.Worker.Versioning.Settings("1.4.0")
+	assert.Equal(t, "temporal-worker", settings.DeploymentName)
+	assert.Equal(t, "1.4.0", settings.BuildID, "the build ID is the release version")
+	assert.Equal(t, "pinned", settings.DefaultBehavior)
+
+	cfg.Worker.Versioning.BuildID = "1.4.0-hotfix"
+	assert.Equal(t, "1.4.0-hotfix", cfg.Worker.Versioning.Settings("1.4.0").BuildID)
+}
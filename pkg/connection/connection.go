@@ -0,0 +1,157 @@
+// Package connection configures how the workers and the client connect to Temporal: address, namespace, TLS,
+// client certificates and API keys
+package connection
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+
+	"go.temporal.io/sdk/client"
+)
+
+// Settings of the Temporal connection, usually from the environment or the worker configuration
+type Settings struct {
+	HostPort   string // host:port of the frontend, localhost:7233 when empty
+	Namespace  string // namespace of the workflows, default when empty
+	TLS        bool   // connect with TLS, implied by the other TLS settings and an API key
+	CAFile     string // PEM CA bundle verifying the server, the system roots when empty
+	CertFile   string // PEM client certificate for mTLS, reloaded when the file changes
+	KeyFile    string // PEM key of the client certificate, reloaded when the file changes
+	ServerName string // name the server certificate must match, the host of HostPort when empty
+	APIKey     string // API key, e.g. of a Temporal Cloud service account
+	APIKeyFile string // file holding the API key, e.g. a mounted secret, reloaded when the file changes
+}
+
+// SettingsFromEnv reads TEMPORAL_HOST, TEMPORAL_NAMESPACE, TEMPORAL_TLS, TEMPORAL_TLS_CA, TEMPORAL_TLS_CERT,
+// TEMPORAL_TLS_KEY, TEMPORAL_TLS_SERVER_NAME, TEMPORAL_API_KEY and TEMPORAL_API_KEY_FILE
+func SettingsFromEnv() (Settings, error) {
+	s := Settings{
+		HostPort:   os.Getenv("TEMPORAL_HOST"),
+		Namespace:  os.Getenv("TEMPORAL_NAMESPACE"),
+		CAFile:     os.Getenv("TEMPORAL_TLS_CA"),
+		CertFile:   os.Getenv("TEMPORAL_TLS_CERT"),
+		KeyFile:    os.Getenv("TEMPORAL_TLS_KEY"),
+		ServerName: os.Getenv("TEMPORAL_TLS_SERVER_NAME"),
+		APIKey:     os.Getenv("TEMPORAL_API_KEY"),
+		APIKeyFile: os.Getenv("TEMPORAL_API_KEY_FILE"),
+	}
+	if value := os.Getenv("TEMPORAL_TLS"); value != "" {
+		var err error
+		if s.TLS, err = strconv.ParseBool(value); err != nil {
+			return Settings{}, fmt.Errorf("TEMPORAL_TLS: %w", err)
+		}
+	}
+	return s, nil
+}
+
+// TLSEnabled reports whether the connection uses TLS
+func (s Settings) TLSEnabled() bool {
+	return s.TLS || s.CAFile != "" || s.CertFile != "" || s.ServerName != "" || s.APIKey != "" || s.APIKeyFile != ""
+}
+
+// Auth names how the client authenticates: mTLS, API key or none
+func (s Settings) Auth() string {
+	switch {
+	case s.CertFile != "":
+		return "mTLS"
+	case s.APIKey != "" || s.APIKeyFile != "":
+		return "API key"
+	}
+	return "none"
+}
+
+// Validate checks the settings without reading the files
+func (s Settings) Validate() error {
+	var errs []error
+	if s.HostPort != "" {
+		if _, _, err := net.SplitHostPort(s.HostPort); err != nil {
+			errs = append(errs, fmt.Errorf("hostPort %q must be host:port", s.HostPort))
+		}
+	}
+	if (s.CertFile == "") != (s.KeyFile == "") {
+		errs = append(errs, errors.New("the client certificate and its key must be set together"))
+	}
+	if s.APIKey != "" && s.APIKeyFile != "" {
+		errs = append(errs, errors.New("set either the API key or the API key file"))
+	}
+	return errors.Join(errs...)
+}
+
+// ConfigureClient sets the connection of the client options from the environment
+func ConfigureClient(opts *client.Options) error {
+	s, err := SettingsFromEnv()
+	if err != nil {
+		return err
+	}
+	return Configure(opts, s)
+}
+
+// Configure is ConfigureClient with explicit settings
+// The certificate, key and API key files are read right away, so a broken setup fails before dialing
+func Configure(opts *client.Options, s Settings) error {
+	if err := s.Validate(); err != nil {
+		return err
+	}
+	if s.HostPort != "" {
+		opts.HostPort = s.HostPort
+	}
+	if s.Namespace != "" {
+		opts.Namespace = s.Namespace
+	}
+
+	if s.TLSEnabled() {
+		tlsConfig, err := s.tlsConfig()
+		if err != nil {
+			return err
+		}
+		opts.ConnectionOptions.TLS = tlsConfig
+	}
+
+	switch {
+	case s.APIKey != "":
+		opts.Credentials = client.NewAPIKeyStaticCredentials(s.APIKey)
+	case s.APIKeyFile != "":
+		apiKey := newFileReloader(s.APIKeyFile)
+		if _, err := apiKey.Get(); err != nil {
+			return fmt.Errorf("failed to read API key: %w", err)
+		}
+		opts.Credentials = client.NewAPIKeyDynamicCredentials(func(context.Context) (string, error) {
+			return apiKey.Get()
+		})
+	}
+	return nil
+}
+
+// tlsConfig returns the TLS configuration of the settings, the client certificate is reloaded when its files change
+func (s Settings) tlsConfig() (*tls.Config, error) {
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12, ServerName: s.ServerName}
+
+	if s.CAFile != "" {
+		pem, err := os.ReadFile(s.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no PEM certificate in CA file %s", s.CAFile)
+		}
+		cfg.RootCAs = pool
+	}
+
+	if s.CertFile != "" {
+		certs := newCertReloader(s.CertFile, s.KeyFile)
+		if _, err := certs.Get(); err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		cfg.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return certs.Get()
+		}
+	}
+	return cfg, nil
+}
@@ -0,0 +1,252 @@
+package connection
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/client"
+)
+
+// testCA issues the server and client certificates of the tests
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue returns a PEM certificate and key for name, usable by servers and clients
+func (ca *testCA) issue(t *testing.T, name string, serial int64) (certPEM, keyPEM []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+// writeFile writes data and moves the modification time forward, so a rewrite within the clock resolution is noticed
+func writeFile(t *testing.T, path string, data []byte, age time.Duration) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+	modified := time.Now().Add(age)
+	require.NoError(t, os.Chtimes(path, modified, modified))
+}
+
+func TestConfigure(t *testing.T) {
+	ca := newTestCA(t)
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "ca.pem")
+	certFile := filepath.Join(dir, "client.pem")
+	keyFile := filepath.Join(dir, "client.key")
+	apiKeyFile := filepath.Join(dir, "api-key")
+	writeFile(t, caFile, ca.pem, 0)
+	certPEM, keyPEM := ca.issue(t, "worker", 2)
+	writeFile(t, certFile, certPEM, 0)
+	writeFile(t, keyFile, keyPEM, 0)
+	writeFile(t, apiKeyFile, []byte("key-from-file\n"), 0)
+
+	tests := []struct {
+		name        string
+		settings    Settings
+		tls         bool
+		credentials bool
+		err         string
+	}{
+		{name: "plaintext", settings: Settings{HostPort: "temporal:7233", Namespace: "ci"}},
+		{name: "tls with system roots", settings: Settings{TLS: true}, tls: true},
+		{name: "custom CA", settings: Settings{CAFile: caFile, ServerName: "temporal.internal"}, tls: true},
+		{name: "mTLS", settings: Settings{CAFile: caFile, CertFile: certFile, KeyFile: keyFile}, tls: true},
+		{name: "API key", settings: Settings{HostPort: "ci.a1b2c.tmprl.cloud:7233", APIKey: "secret"}, tls: true, credentials: true},
+		{name: "API key file", settings: Settings{APIKeyFile: apiKeyFile}, tls: true, credentials: true},
+		{name: "invalid host port", settings: Settings{HostPort: "temporal"}, err: `hostPort "temporal" must be host:port`},
+		{name: "certificate without key", settings: Settings{CertFile: certFile}, err: "must be set together"},
+		{name: "both API keys", settings: Settings{APIKey: "secret", APIKeyFile: apiKeyFile}, err: "either the API key or the API key file"},
+		{name: "missing CA", settings: Settings{CAFile: filepath.Join(dir, "missing.pem")}, err: "failed to read CA"},
+		{name: "CA without certificate", settings: Settings{CAFile: keyFile}, err: "no PEM certificate"},
+		{name: "key does not match", settings: Settings{CertFile: caFile, KeyFile: keyFile}, err: "failed to load client certificate"},
+		{name: "missing API key file", settings: Settings{APIKeyFile: filepath.Join(dir, "missing")}, err: "failed to read API key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := client.Options{}
+			err := Configure(&opts, tt.settings)
+			if tt.err != "" {
+				assert.ErrorContains(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.settings.HostPort, opts.HostPort)
+			assert.Equal(t, tt.settings.Namespace, opts.Namespace)
+			assert.Equal(t, tt.tls, opts.ConnectionOptions.TLS != nil, "TLS config")
+			assert.Equal(t, tt.credentials, opts.Credentials != nil, "credentials")
+			if tt.settings.CAFile != "" {
+				assert.NotNil(t, opts.ConnectionOptions.TLS.RootCAs)
+				assert.Equal(t, tt.settings.ServerName, opts.ConnectionOptions.TLS.ServerName)
+			}
+		})
+	}
+}
+
+func TestConfigure_MutualTLSHandshake(t *testing.T) {
+	ca := newTestCA(t)
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "ca.pem")
+	certFile := filepath.Join(dir, "client.pem")
+	keyFile := filepath.Join(dir, "client.key")
+	writeFile(t, caFile, ca.pem, 0)
+	certPEM, keyPEM := ca.issue(t, "worker-1", 2)
+	writeFile(t, certFile, certPEM, -time.Minute)
+	writeFile(t, keyFile, keyPEM, -time.Minute)
+
+	serverCert, serverKey := ca.issue(t, "temporal.internal", 3)
+	serverPair, err := tls.X509KeyPair(serverCert, serverKey)
+	require.NoError(t, err)
+	clients := x509.NewCertPool()
+	clients.AddCert(ca.cert)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+	server.TLS = &tls.Config{Certificates: []tls.Certificate{serverPair}, ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clients}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	opts := client.Options{}
+	require.NoError(t, Configure(&opts, Settings{CAFile: caFile, CertFile: certFile, KeyFile: keyFile, ServerName: "temporal.internal"}))
+
+	peer := func() string {
+		t.Helper()
+		httpClient := &http.Client{Transport: &http.Transport{TLSClientConfig: opts.ConnectionOptions.TLS.Clone()}}
+		resp, err := httpClient.Get(server.URL)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return string(body)
+	}
+	assert.Equal(t, "worker-1", peer())
+
+	// The rotated certificate is used by the next handshake
+	certPEM, keyPEM = ca.issue(t, "worker-2", 4)
+	writeFile(t, certFile, certPEM, 0)
+	writeFile(t, keyFile, keyPEM, 0)
+	assert.Equal(t, "worker-2", peer())
+}
+
+func TestReloader(t *testing.T) {
+	ca := newTestCA(t)
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "client.pem")
+	keyFile := filepath.Join(dir, "client.key")
+	certPEM, keyPEM := ca.issue(t, "worker-1", 2)
+	writeFile(t, certFile, certPEM, -time.Minute)
+	writeFile(t, keyFile, keyPEM, -time.Minute)
+
+	certs := newCertReloader(certFile, keyFile)
+	first, err := certs.Get()
+	require.NoError(t, err)
+	again, err := certs.Get()
+	require.NoError(t, err)
+	assert.Same(t, first, again, "unchanged files are not reloaded")
+
+	// Half way through a rotation the certificate does not match the key yet
+	certPEM, keyPEM = ca.issue(t, "worker-2", 3)
+	writeFile(t, certFile, certPEM, -30*time.Second)
+	during, err := certs.Get()
+	require.NoError(t, err)
+	assert.Same(t, first, during, "the previous certificate is kept while the files don't match")
+
+	writeFile(t, keyFile, keyPEM, 0)
+	rotated, err := certs.Get()
+	require.NoError(t, err)
+	leaf, err := x509.ParseCertificate(rotated.Certificate[0])
+	require.NoError(t, err)
+	assert.Equal(t, "worker-2", leaf.Subject.CommonName)
+
+	apiKeyFile := filepath.Join(dir, "api-key")
+	writeFile(t, apiKeyFile, []byte("first\n"), -time.Minute)
+	apiKey := newFileReloader(apiKeyFile)
+	key, err := apiKey.Get()
+	require.NoError(t, err)
+	assert.Equal(t, "first", key)
+	writeFile(t, apiKeyFile, []byte("second"), 0)
+	key, err = apiKey.Get()
+	require.NoError(t, err)
+	assert.Equal(t, "second", key)
+
+	empty := newFileReloader(filepath.Join(dir, "empty"))
+	writeFile(t, filepath.Join(dir, "empty"), []byte("\n"), 0)
+	_, err = empty.Get()
+	assert.ErrorContains(t, err, "is empty")
+}
+
+func TestSettingsFromEnv(t *testing.T) {
+	t.Setenv("TEMPORAL_HOST", "ci.a1b2c.tmprl.cloud:7233")
+	t.Setenv("TEMPORAL_NAMESPACE", "ci.a1b2c")
+	t.Setenv("TEMPORAL_API_KEY", "secret")
+	t.Setenv("TEMPORAL_TLS", "true")
+
+	s, err := SettingsFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, Settings{HostPort: "ci.a1b2c.tmprl.cloud:7233", Namespace: "ci.a1b2c", TLS: true, APIKey: "secret"}, s)
+	assert.Equal(t, "API key", s.Auth())
+
+	t.Setenv("TEMPORAL_TLS", "sometimes")
+	_, err = SettingsFromEnv()
+	assert.ErrorContains(t, err, "TEMPORAL_TLS")
+
+	opts := client.Options{}
+	t.Setenv("TEMPORAL_TLS", "")
+	require.NoError(t, ConfigureClient(&opts))
+	assert.Equal(t, "ci.a1b2c.tmprl.cloud:7233", opts.HostPort)
+	assert.NotNil(t, opts.Credentials)
+}
@@ -0,0 +1,99 @@
+package connection
+
+import (
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+)
+
+// reloader caches a value loaded from files and loads it again once one of the files changed
+// A failed reload keeps the previous value, e.g. while a rotation wrote the new certificate but not yet its key
+type reloader[T any] struct {
+	files []string
+	load  func() (T, error)
+
+	mu      sync.Mutex
+	value   T
+	loaded  bool
+	version string // modification times and sizes of the files the value was loaded from
+	failure string // last reload error that was logged
+}
+
+// newCertReloader reloads the client certificate when the certificate or key file changes
+func newCertReloader(certFile, keyFile string) *reloader[*tls.Certificate] {
+	return &reloader[*tls.Certificate]{
+		files: []string{certFile, keyFile},
+		load: func() (*tls.Certificate, error) {
+			cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+			if err != nil {
+				return nil, err
+			}
+			return &cert, nil
+		},
+	}
+}
+
+// newFileReloader reloads the trimmed content of path when it changes
+func newFileReloader(path string) *reloader[string] {
+	return &reloader[string]{
+		files: []string{path},
+		load: func() (string, error) {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return "", err
+			}
+			// Secret files usually end with a line break
+			if key := strings.TrimSpace(string(data)); key != "" {
+				return key, nil
+			}
+			return "", fmt.Errorf("%s is empty", path)
+		},
+	}
+}
+
+// Get returns the current value, reloading it when the files changed since the last load
+func (r *reloader[T]) Get() (T, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	version, err := r.stat()
+	if err == nil && r.loaded && version == r.version {
+		return r.value, nil
+	}
+	if err == nil {
+		var value T
+		if value, err = r.load(); err == nil {
+			if r.loaded {
+				slog.Info("Reloaded Temporal credentials", "files", r.files)
+			}
+			r.value, r.loaded, r.version, r.failure = value, true, version, ""
+			return value, nil
+		}
+	}
+
+	if !r.loaded {
+		var zero T
+		return zero, err
+	}
+	if err.Error() != r.failure {
+		r.failure = err.Error()
+		slog.Warn("Failed to reload Temporal credentials, keeping the previous ones", "files", r.files, "error", err)
+	}
+	return r.value, nil
+}
+
+// stat returns the modification times and sizes of the files
+func (r *reloader[T]) stat() (string, error) {
+	var version strings.Builder
+	for _, file := range r.files {
+		info, err := os.Stat(file)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&version, "%s:%d:%d;", file, info.ModTime().UnixNano(), info.Size())
+	}
+	return version.String(), nil
+}
@@ -0,0 +1,193 @@
+// Package drain stops workers gracefully: polling stops, running activities finish within a deadline while
+// heartbeating, and sessions are released before the process exits or restarts
+package drain
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/interceptor"
+)
+
+// sessionCreationActivity is the activity a session worker runs for the lifetime of each session it hosts
+const sessionCreationActivity = "internalSessionCreationActivity"
+
+// HeartbeatInterval is how often running activities heartbeat while draining
+var HeartbeatInterval = 10 * time.Second
+
+// Worker is the part of worker.Worker the drain needs
+type Worker interface {
+	Stop()
+}
+
+// Drainer tracks the running activities of the workers it intercepts, add it to worker.Options.Interceptors
+type Drainer struct {
+	interceptor.WorkerInterceptorBase
+
+	mu       sync.Mutex
+	running  map[*run]struct{} // activities except the session creations
+	sessions map[*run]struct{}
+	changed  chan struct{}
+	draining chan struct{}
+	once     sync.Once
+}
+
+// run is a running activity
+type run struct {
+	name   string
+	cancel context.CancelFunc
+}
+
+// New returns a Drainer
+func New() *Drainer {
+	return &Drainer{
+		running:  map[*run]struct{}{},
+		sessions: map[*run]struct{}{},
+		changed:  make(chan struct{}, 1),
+		draining: make(chan struct{}),
+	}
+}
+
+// InterceptActivity implements interceptor.WorkerInterceptor
+func (d *Drainer) InterceptActivity(ctx context.Context, next interceptor.ActivityInboundInterceptor) interceptor.ActivityInboundInterceptor {
+	i := &activityInbound{drainer: d}
+	i.Next = next
+	return i
+}
+
+// Running returns the number of running activities, not counting sessions
+func (d *Drainer) Running() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.running)
+}
+
+// Drain stops the workers from polling and waits until their running activities finished or ctx is done
+// Sessions stay open while their activities run; once those finished no further activity of a session can be
+// polled, so the sessions are released and their workflows learn right away that they have to start a new one
+// Set worker.Options.WorkerStopTimeout to the drain deadline, so activities still running then are cancelled
+func (d *Drainer) Drain(ctx context.Context, workers ...Worker) error {
+	d.once.Do(func() { close(d.draining) })
+
+	stopped := make(chan struct{})
+	go func() {
+		var wg sync.WaitGroup
+		for _, w := range workers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				w.Stop()
+			}()
+		}
+		wg.Wait()
+		close(stopped)
+	}()
+
+	for running := d.Running(); running > 0; running = d.Running() {
+		select {
+		case <-d.changed:
+		case <-ctx.Done():
+			d.releaseSessions()
+			return fmt.Errorf("%d activities still running: %w", running, ctx.Err())
+		}
+	}
+	d.releaseSessions()
+
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("workers did not stop: %w", ctx.Err())
+	}
+}
+
+// releaseSessions cancels the session creation activities, which completes their sessions
+func (d *Drainer) releaseSessions() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for r := range d.sessions {
+		r.cancel()
+	}
+}
+
+// start tracks an activity until the returned function is called
+func (d *Drainer) start(r *run) func() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	tracked := d.running
+	if r.name == sessionCreationActivity {
+		tracked = d.sessions
+	}
+	tracked[r] = struct{}{}
+	return func() {
+		d.mu.Lock()
+		delete(tracked, r)
+		d.mu.Unlock()
+		select {
+		case d.changed <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// heartbeat records a heartbeat every HeartbeatInterval once draining started, until done is closed
+// A heartbeat lets the server know the activity is still alive and delivers its cancellation
+func (d *Drainer) heartbeat(ctx context.Context, done <-chan struct{}) {
+	select {
+	case <-d.draining:
+	case <-done:
+		return
+	}
+	ticker := time.NewTicker(HeartbeatInterval)
+	defer ticker.Stop()
+	for {
+		activity.RecordHeartbeat(ctx)
+		select {
+		case <-ticker.C:
+		case <-done:
+			return
+		}
+	}
+}
+
+type activityInbound struct {
+	interceptor.ActivityInboundInterceptorBase
+	drainer *Drainer
+}
+
+func (a *activityInbound) ExecuteActivity(ctx context.Context, in *interceptor.ExecuteActivityInput) (interface{}, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	r := &run{name: activity.GetInfo(ctx).ActivityType.Name, cancel: cancel}
+	defer a.drainer.start(r)()
+
+	// The session creation activity heartbeats by itself
+	if r.name != sessionCreationActivity {
+		done, finished := make(chan struct{}), make(chan struct{})
+		go func() {
+			defer close(finished)
+			a.drainer.heartbeat(ctx, done)
+		}()
+		defer func() {
+			close(done)
+			<-finished
+		}()
+	}
+	return a.Next.ExecuteActivity(ctx, in)
+}
+
+// Deadline returns the context of a drain: done after timeout or when another SIGINT or SIGTERM arrives
+func Deadline(timeout time.Duration) (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	return ctx, func() {
+		cancel()
+		stop()
+	}
+}
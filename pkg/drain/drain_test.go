@@ -0,0 +1,133 @@
+package drain
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/converter"
+	"go.temporal.io/sdk/interceptor"
+	"go.temporal.io/sdk/testsuite"
+	"go.temporal.io/sdk/worker"
+	"go.temporal.io/sdk/workflow"
+)
+
+// fakeWorker records that the drain stopped it
+type fakeWorker struct {
+	stopped atomic.Bool
+}
+
+func (w *fakeWorker) Stop() { w.stopped.Store(true) }
+
+// drainEnv runs a workflow with a build activity and an activity standing in for the creation of a session, the
+// test environment replaces the real session creation by one that returns right away
+// The build finishes when release is closed
+type drainEnv struct {
+	drainer    *Drainer
+	release    chan struct{}
+	released   atomic.Bool // the session was released
+	heartbeats atomic.Int32
+	done       chan struct{}
+	err        error
+}
+
+func startDrainEnv(t *testing.T) *drainEnv {
+	t.Helper()
+	previous := HeartbeatInterval
+	HeartbeatInterval = 10 * time.Millisecond
+	t.Cleanup(func() { HeartbeatInterval = previous })
+
+	e := &drainEnv{drainer: New(), release: make(chan struct{}), done: make(chan struct{})}
+
+	session := func(ctx context.Context) error {
+		<-ctx.Done()
+		e.released.Store(true)
+		return nil
+	}
+	build := func(ctx context.Context) error {
+		select {
+		case <-e.release:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	upgradeWorkflow := func(ctx workflow.Context) error {
+		ctx = workflow.WithActivityOptions(ctx, workflow.ActivityOptions{StartToCloseTimeout: time.Hour})
+		sessionFuture := workflow.ExecuteActivity(ctx, sessionCreationActivity)
+		if err := workflow.ExecuteActivity(ctx, "build").Get(ctx, nil); err != nil {
+			return err
+		}
+		return sessionFuture.Get(ctx, nil)
+	}
+
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestWorkflowEnvironment()
+	env.SetTestTimeout(time.Minute)
+	env.SetWorkerOptions(worker.Options{Interceptors: []interceptor.WorkerInterceptor{e.drainer}})
+	env.RegisterWorkflowWithOptions(upgradeWorkflow, workflow.RegisterOptions{Name: "upgradeWorkflow"})
+	env.RegisterActivityWithOptions(session, activity.RegisterOptions{Name: sessionCreationActivity})
+	env.RegisterActivityWithOptions(build, activity.RegisterOptions{Name: "build"})
+	env.SetOnActivityHeartbeatListener(func(info *activity.Info, _ converter.EncodedValues) {
+		if info.ActivityType.Name == "build" {
+			e.heartbeats.Add(1)
+		}
+	})
+
+	go func() {
+		defer close(e.done)
+		env.ExecuteWorkflow("upgradeWorkflow")
+		e.err = env.GetWorkflowError()
+	}()
+	require.Eventually(t, func() bool {
+		e.drainer.mu.Lock()
+		defer e.drainer.mu.Unlock()
+		return len(e.drainer.running) == 1 && len(e.drainer.sessions) == 1
+	}, 5*time.Second, time.Millisecond, "the build and the session run")
+	return e
+}
+
+func TestDrain(t *testing.T) {
+	e := startDrainEnv(t)
+	assert.Zero(t, e.heartbeats.Load(), "activities only heartbeat while draining")
+
+	w := &fakeWorker{}
+	var drainErr error
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		drainErr = e.drainer.Drain(context.Background(), w)
+	}()
+
+	require.Eventually(t, func() bool { return e.heartbeats.Load() > 0 }, 5*time.Second, time.Millisecond, "the build heartbeats")
+	assert.Eventually(t, w.stopped.Load, 5*time.Second, time.Millisecond, "polling stopped")
+	assert.False(t, e.released.Load(), "the session is kept while the build runs")
+
+	close(e.release)
+	wg.Wait()
+	require.NoError(t, drainErr)
+	<-e.done
+	require.NoError(t, e.err)
+	assert.True(t, e.released.Load(), "the session is released once the build finished")
+}
+
+func TestDrain_Deadline(t *testing.T) {
+	e := startDrainEnv(t)
+	defer func() {
+		close(e.release)
+		<-e.done
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := e.drainer.Drain(ctx, &fakeWorker{})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.ErrorContains(t, err, "1 activities still running")
+	assert.Eventually(t, e.released.Load, 5*time.Second, time.Millisecond, "the session is released at the deadline")
+}
@@ -0,0 +1,130 @@
+// Package health serves the liveness, readiness and metrics endpoints of a worker
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"go.temporal.io/sdk/client"
+
+	"github.com/containifyci/temporal-worker/pkg/metrics"
+	"github.com/containifyci/temporal-worker/pkg/preflight"
+)
+
+// CheckTimeout bounds every readiness check
+var CheckTimeout = 5 * time.Second
+
+// Check is a readiness condition of the worker
+type Check struct {
+	Name string
+	Run  func(ctx context.Context) error
+}
+
+// Report is the body of the readiness endpoint
+type Report struct {
+	Status string            `json:"status"` // ok or unavailable
+	Checks map[string]string `json:"checks"` // ok or the error of each check
+}
+
+// NewHandler serves
+//   - /healthz: liveness, ok as long as the process serves requests
+//   - /readyz: readiness, ok when all checks pass, 503 with the failing checks otherwise
+//   - /metrics: the Prometheus metrics
+func NewHandler(checks ...Check) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("ok\n"))
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		report := Evaluate(r.Context(), checks...)
+		w.Header().Set("Content-Type", "application/json")
+		if report.Status != "ok" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		_ = json.NewEncoder(w).Encode(report)
+	})
+	mux.Handle("/metrics", metrics.Handler())
+	return mux
+}
+
+// Serve listens on addr and serves handler in the background, listen errors are returned right away
+func Serve(addr string, handler http.Handler) (*http.Server, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	server := &http.Server{Handler: handler, ReadHeaderTimeout: 10 * time.Second}
+	go func() { _ = server.Serve(ln) }()
+	return server, nil
+}
+
+// Evaluate runs the checks
+func Evaluate(ctx context.Context, checks ...Check) Report {
+	report := Report{Status: "ok", Checks: make(map[string]string, len(checks))}
+	for _, check := range checks {
+		checkCtx, cancel := context.WithTimeout(ctx, CheckTimeout)
+		err := check.Run(checkCtx)
+		cancel()
+		if err != nil {
+			report.Status = "unavailable"
+			report.Checks[check.Name] = err.Error()
+			continue
+		}
+		report.Checks[check.Name] = "ok"
+	}
+	return report
+}
+
+// TemporalCheck passes when the Temporal frontend answers a health check
+func TemporalCheck(c client.Client) Check {
+	return Check{Name: "temporal", Run: func(ctx context.Context) error {
+		_, err := c.CheckHealth(ctx, &client.CheckHealthRequest{})
+		return err
+	}}
+}
+
+// ToolsCheck passes when all tools are installed by a bundle or found in PATH
+func ToolsCheck(tools ...string) Check {
+	return Check{Name: "tools", Run: func(context.Context) error {
+		var errs []error
+		for _, tool := range tools {
+			if _, err := preflight.LookPath(tool); err != nil {
+				errs = append(errs, fmt.Errorf("%s not found in PATH", tool))
+			}
+		}
+		return errors.Join(errs...)
+	}}
+}
+
+// Status is a readiness condition set by the worker itself, e.g. once it polls its task queues
+type Status struct {
+	ready  atomic.Bool
+	reason string
+}
+
+// NewStatus returns a status that is not ready, reporting reason until Set(true)
+func NewStatus(reason string) *Status {
+	return &Status{reason: reason}
+}
+
+// Set marks the status ready or not ready
+func (s *Status) Set(ready bool) {
+	s.ready.Store(ready)
+}
+
+// Check returns the readiness check of the status
+func (s *Status) Check(name string) Check {
+	return Check{Name: name, Run: func(context.Context) error {
+		if !s.ready.Load() {
+			return errors.New(s.reason)
+		}
+		return nil
+	}}
+}
@@ -0,0 +1,77 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func get(t *testing.T, handler http.Handler, path string) (*http.Response, string) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	body, err := io.ReadAll(rec.Result().Body)
+	require.NoError(t, err)
+	return rec.Result(), string(body)
+}
+
+func TestHandler(t *testing.T) {
+	polling := NewStatus("worker is not polling")
+	temporal := Check{Name: "temporal", Run: func(context.Context) error { return nil }}
+	handler := NewHandler(temporal, polling.Check("polling"), ToolsCheck("go"))
+
+	t.Run("liveness", func(t *testing.T) {
+		resp, _ := get(t, handler, "/healthz")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+
+	t.Run("not ready before polling", func(t *testing.T) {
+		resp, body := get(t, handler, "/readyz")
+		assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+		var report Report
+		require.NoError(t, json.Unmarshal([]byte(body), &report))
+		assert.Equal(t, Report{
+			Status: "unavailable",
+			Checks: map[string]string{"temporal": "ok", "polling": "worker is not polling", "tools": "ok"},
+		}, report)
+	})
+
+	t.Run("ready", func(t *testing.T) {
+		polling.Set(true)
+		resp, body := get(t, handler, "/readyz")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.JSONEq(t, `{"status":"ok","checks":{"temporal":"ok","polling":"ok","tools":"ok"}}`, body)
+	})
+
+	t.Run("metrics", func(t *testing.T) {
+		resp, body := get(t, handler, "/metrics")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Contains(t, body, "go_major_upgrades_detected_total")
+	})
+}
+
+func TestEvaluate(t *testing.T) {
+	report := Evaluate(context.Background(),
+		Check{Name: "temporal", Run: func(context.Context) error { return errors.New("connection refused") }},
+		ToolsCheck("go", "definitely-not-installed-tool"),
+	)
+	assert.Equal(t, "unavailable", report.Status)
+	assert.Equal(t, "connection refused", report.Checks["temporal"])
+	assert.Equal(t, "definitely-not-installed-tool not found in PATH", report.Checks["tools"])
+}
+
+func TestServe(t *testing.T) {
+	server, err := Serve("127.0.0.1:0", NewHandler())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = server.Close() })
+
+	_, err = Serve("invalid-address", NewHandler())
+	assert.Error(t, err)
+}
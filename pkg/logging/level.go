@@ -0,0 +1,39 @@
+package logging
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// LevelHandler serves the level of the logger
+//   - GET returns the level
+//   - PUT sets the level in the body, e.g. curl -X PUT -d debug localhost:9091/loglevel
+func LevelHandler(level *slog.LevelVar, logger *slog.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut:
+			body, err := io.ReadAll(io.LimitReader(r.Body, 64))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			parsed, err := ParseLevel(strings.TrimSpace(string(body)))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if parsed != level.Level() {
+				logger.Warn("Log level changed", "from", level.Level(), "to", parsed, "remote", r.RemoteAddr)
+				level.Set(parsed)
+			}
+		default:
+			w.Header().Set("Allow", "GET, PUT")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		_, _ = w.Write([]byte(strings.ToLower(level.Level().String()) + "\n"))
+	})
+}
@@ -0,0 +1,68 @@
+// Package logging builds the logger of the worker in the configured format, lets its level change at runtime and
+// adds the repository a workflow works on to the log lines of its activities
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+
+	"github.com/dusted-go/logging/prettylog"
+)
+
+// Formats of the log lines
+const (
+	FormatPretty = "pretty" // colored, for terminals
+	FormatJSON   = "json"
+	FormatLogfmt = "logfmt" // key=value pairs
+)
+
+// Settings configure the logger
+type Settings struct {
+	Format    string // pretty, json or logfmt
+	Level     string // debug, info, warn or error
+	AddSource bool
+}
+
+// Validate checks the settings
+func (s Settings) Validate() error {
+	if !slices.Contains([]string{FormatPretty, FormatJSON, FormatLogfmt}, s.Format) {
+		return fmt.Errorf("unknown format %q, use %s, %s or %s", s.Format, FormatPretty, FormatJSON, FormatLogfmt)
+	}
+	if _, err := ParseLevel(s.Level); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ParseLevel parses debug, info, warn or error
+func ParseLevel(text string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(text)); err != nil {
+		return level, fmt.Errorf("unknown level %q, use debug, info, warn or error", text)
+	}
+	return level, nil
+}
+
+// New returns the logger writing to w and its level, setting the level changes it at runtime
+func New(w io.Writer, s Settings) (*slog.Logger, *slog.LevelVar, error) {
+	if err := s.Validate(); err != nil {
+		return nil, nil, err
+	}
+	level := &slog.LevelVar{}
+	parsed, _ := ParseLevel(s.Level)
+	level.Set(parsed)
+
+	opts := &slog.HandlerOptions{Level: level, AddSource: s.AddSource}
+	var handler slog.Handler
+	switch s.Format {
+	case FormatJSON:
+		handler = slog.NewJSONHandler(w, opts)
+	case FormatLogfmt:
+		handler = slog.NewTextHandler(w, opts)
+	default:
+		handler = prettylog.New(opts, prettylog.WithDestinationWriter(w), prettylog.WithColor(), prettylog.WithOutputEmptyAttrs())
+	}
+	return slog.New(handler), level, nil
+}
@@ -0,0 +1,120 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/interceptor"
+	"go.temporal.io/sdk/log"
+	"go.temporal.io/sdk/testsuite"
+	"go.temporal.io/sdk/worker"
+	"go.temporal.io/sdk/workflow"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		format string
+		line   string
+	}{
+		{format: FormatJSON, line: `"msg":"Cloned","repository":"containifyci/engine-ci"`},
+		{format: FormatLogfmt, line: `msg=Cloned repository=containifyci/engine-ci`},
+		{format: FormatPretty, line: `Cloned`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var out bytes.Buffer
+			logger, level, err := New(&out, Settings{Format: tt.format, Level: "info"})
+			require.NoError(t, err)
+
+			logger.Debug("Hidden")
+			logger.Info("Cloned", "repository", "containifyci/engine-ci")
+			assert.Contains(t, out.String(), tt.line)
+			assert.NotContains(t, out.String(), "Hidden")
+
+			level.Set(slog.LevelDebug)
+			logger.Debug("Shown")
+			assert.Contains(t, out.String(), "Shown")
+		})
+	}
+
+	_, _, err := New(io.Discard, Settings{Format: "xml", Level: "info"})
+	assert.EqualError(t, err, `unknown format "xml", use pretty, json or logfmt`)
+	_, _, err = New(io.Discard, Settings{Format: FormatJSON, Level: "loud"})
+	assert.EqualError(t, err, `unknown level "loud", use debug, info, warn or error`)
+}
+
+func TestLevelHandler(t *testing.T) {
+	level := &slog.LevelVar{}
+	handler := LevelHandler(level, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	serve := func(method, body string) (int, string) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(method, "/loglevel", strings.NewReader(body)))
+		return rec.Code, rec.Body.String()
+	}
+
+	code, body := serve(http.MethodGet, "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "info\n", body)
+
+	code, body = serve(http.MethodPut, "debug\n")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "debug\n", body)
+	assert.Equal(t, slog.LevelDebug, level.Level())
+
+	code, _ = serve(http.MethodPut, "loud")
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, slog.LevelDebug, level.Level())
+
+	code, _ = serve(http.MethodDelete, "")
+	assert.Equal(t, http.StatusMethodNotAllowed, code)
+}
+
+func cloneActivity(ctx context.Context) error {
+	activity.GetLogger(ctx).Info("Cloned")
+	return nil
+}
+
+func upgradeWorkflow(ctx workflow.Context, repository string) error {
+	ctx = WithRepository(ctx, repository)
+	ctx = workflow.WithActivityOptions(ctx, workflow.ActivityOptions{StartToCloseTimeout: time.Minute})
+	return workflow.ExecuteActivity(ctx, cloneActivity).Get(ctx, nil)
+}
+
+func TestRepository(t *testing.T) {
+	var out bytes.Buffer
+	logger, _, err := New(&out, Settings{Format: FormatJSON, Level: "info"})
+	require.NoError(t, err)
+
+	var suite testsuite.WorkflowTestSuite
+	suite.SetLogger(log.NewStructuredLogger(logger))
+	suite.SetContextPropagators([]workflow.ContextPropagator{NewPropagator()})
+	env := suite.NewTestWorkflowEnvironment()
+	env.SetWorkerOptions(worker.Options{Interceptors: []interceptor.WorkerInterceptor{NewInterceptor()}})
+	env.RegisterActivity(cloneActivity)
+	env.ExecuteWorkflow(upgradeWorkflow, "containifyci/engine-ci")
+	require.NoError(t, env.GetWorkflowError())
+
+	var line map[string]any
+	for _, l := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		if strings.Contains(l, `"msg":"Cloned"`) {
+			require.NoError(t, json.Unmarshal([]byte(l), &line))
+		}
+	}
+	require.NotNil(t, line, out.String())
+	assert.Equal(t, "containifyci/engine-ci", line[RepositoryKey])
+	assert.Equal(t, "cloneActivity", line["ActivityType"])
+	assert.NotEmpty(t, line["WorkflowID"])
+	assert.NotEmpty(t, line["RunID"])
+}
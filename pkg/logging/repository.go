@@ -0,0 +1,157 @@
+package logging
+
+import (
+	"context"
+
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/converter"
+	"go.temporal.io/sdk/interceptor"
+	"go.temporal.io/sdk/log"
+	"go.temporal.io/sdk/workflow"
+)
+
+// RepositoryKey is the Temporal header and the log field carrying the repository a workflow works on
+const RepositoryKey = "Repository"
+
+type repositoryKey struct{}
+
+// WithRepository returns a workflow context whose workflow and activity log lines carry the repository, the
+// activities and child workflows started with it inherit the repository
+func WithRepository(ctx workflow.Context, repository string) workflow.Context {
+	if repository == "" {
+		return ctx
+	}
+	return workflow.WithValue(ctx, repositoryKey{}, repository)
+}
+
+// Repository returns the repository of an activity or workflow context, empty if it has none
+func Repository(ctx interface{ Value(any) any }) string {
+	repository, _ := ctx.Value(repositoryKey{}).(string)
+	return repository
+}
+
+// Configure adds the propagator of the repository header and the interceptor adding it to the log lines to the
+// client options, the workers of the client use both
+func Configure(opts *client.Options) {
+	opts.ContextPropagators = append(opts.ContextPropagators, NewPropagator())
+	opts.Interceptors = append(opts.Interceptors, NewInterceptor())
+}
+
+// NewPropagator returns the context propagator carrying the repository from workflows to their activities and
+// child workflows
+func NewPropagator() workflow.ContextPropagator {
+	return propagator{}
+}
+
+type propagator struct{}
+
+func (propagator) Inject(ctx context.Context, writer workflow.HeaderWriter) error {
+	return inject(Repository(ctx), writer)
+}
+
+func (propagator) InjectFromWorkflow(ctx workflow.Context, writer workflow.HeaderWriter) error {
+	return inject(Repository(ctx), writer)
+}
+
+func (propagator) Extract(ctx context.Context, reader workflow.HeaderReader) (context.Context, error) {
+	repository, err := extract(reader)
+	if err != nil || repository == "" {
+		return ctx, err
+	}
+	return context.WithValue(ctx, repositoryKey{}, repository), nil
+}
+
+func (propagator) ExtractToWorkflow(ctx workflow.Context, reader workflow.HeaderReader) (workflow.Context, error) {
+	repository, err := extract(reader)
+	if err != nil {
+		return ctx, err
+	}
+	return WithRepository(ctx, repository), nil
+}
+
+func inject(repository string, writer workflow.HeaderWriter) error {
+	if repository == "" {
+		return nil
+	}
+	payload, err := converter.GetDefaultDataConverter().ToPayload(repository)
+	if err != nil {
+		return err
+	}
+	writer.Set(RepositoryKey, payload)
+	return nil
+}
+
+func extract(reader workflow.HeaderReader) (string, error) {
+	payload, ok := reader.Get(RepositoryKey)
+	if !ok {
+		return "", nil
+	}
+	var repository string
+	err := converter.GetDefaultDataConverter().FromPayload(payload, &repository)
+	return repository, err
+}
+
+// NewInterceptor returns the interceptor adding the repository of the context to the log lines of workflows and
+// activities, next to the workflow ID, run ID and activity type the SDK adds
+func NewInterceptor() interceptor.Interceptor {
+	return &repositoryInterceptor{}
+}
+
+type repositoryInterceptor struct {
+	interceptor.InterceptorBase
+}
+
+func (*repositoryInterceptor) InterceptActivity(_ context.Context, next interceptor.ActivityInboundInterceptor) interceptor.ActivityInboundInterceptor {
+	i := &activityInbound{}
+	i.Next = next
+	return i
+}
+
+func (*repositoryInterceptor) InterceptWorkflow(_ workflow.Context, next interceptor.WorkflowInboundInterceptor) interceptor.WorkflowInboundInterceptor {
+	i := &workflowInbound{}
+	i.Next = next
+	return i
+}
+
+type activityInbound struct {
+	interceptor.ActivityInboundInterceptorBase
+}
+
+func (a *activityInbound) Init(outbound interceptor.ActivityOutboundInterceptor) error {
+	o := &activityOutbound{}
+	o.Next = outbound
+	return a.Next.Init(o)
+}
+
+type activityOutbound struct {
+	interceptor.ActivityOutboundInterceptorBase
+}
+
+func (a *activityOutbound) GetLogger(ctx context.Context) log.Logger {
+	return withRepository(a.Next.GetLogger(ctx), Repository(ctx))
+}
+
+type workflowInbound struct {
+	interceptor.WorkflowInboundInterceptorBase
+}
+
+func (w *workflowInbound) Init(outbound interceptor.WorkflowOutboundInterceptor) error {
+	o := &workflowOutbound{}
+	o.Next = outbound
+	return w.Next.Init(o)
+}
+
+type workflowOutbound struct {
+	interceptor.WorkflowOutboundInterceptorBase
+}
+
+func (w *workflowOutbound) GetLogger(ctx workflow.Context) log.Logger {
+	return withRepository(w.Next.GetLogger(ctx), Repository(ctx))
+}
+
+func withRepository(logger log.Logger, repository string) log.Logger {
+	if repository == "" {
+		return logger
+	}
+	return log.With(logger, RepositoryKey, repository)
+}
@@ -0,0 +1,11 @@
+//go:build !unix
+
+package logging
+
+import (
+	"context"
+	"log/slog"
+)
+
+// ToggleOnSignal does nothing, this platform has no SIGUSR1; the level changes through LevelHandler only
+func ToggleOnSignal(context.Context, *slog.LevelVar, *slog.Logger) {}
@@ -0,0 +1,37 @@
+//go:build unix
+
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// ToggleOnSignal switches the level between debug and the level it had before on every SIGUSR1 until ctx is done,
+// e.g. kill -USR1 $(pidof temporal-worker)
+func ToggleOnSignal(ctx context.Context, level *slog.LevelVar, logger *slog.Logger) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGUSR1)
+	go func() {
+		defer signal.Stop(signals)
+		previous := level.Level()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-signals:
+			}
+			next := slog.LevelDebug
+			if level.Level() == slog.LevelDebug {
+				next = previous
+			} else {
+				previous = level.Level()
+			}
+			logger.Warn("Log level changed", "from", level.Level(), "to", next, "signal", "SIGUSR1")
+			level.Set(next)
+		}
+	}()
+}
@@ -0,0 +1,85 @@
+// Package metrics defines the worker metrics and exposes them, together with the Temporal SDK metrics, to Prometheus
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Results of a job run
+const (
+	ResultSucceeded = "succeeded"
+	ResultFailed    = "failed"
+	ResultError     = "error" // the command could not be started
+)
+
+var (
+	// JobsRun counts the CI commands run by the worker
+	JobsRun = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "engine_ci_jobs_total",
+		Help: "CI jobs run by the worker, by runner and result",
+	}, []string{"runner", "result"})
+
+	// JobDuration observes how long the CI commands ran
+	JobDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "engine_ci_job_duration_seconds",
+		Help:    "Duration of the CI jobs run by the worker, by runner and result",
+		Buckets: []float64{5, 15, 30, 60, 120, 300, 600, 1200, 1800, 3600},
+	}, []string{"runner", "result"})
+
+	// PullRequestsCreated counts the pull requests opened by the worker
+	PullRequestsCreated = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "pull_requests_created_total",
+		Help: "Pull requests created by the worker, by organization",
+	}, []string{"org"})
+
+	// UpgradesDetected counts the major dependency upgrades found in the scanned repositories
+	UpgradesDetected = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "go_major_upgrades_detected_total",
+		Help: "Major Go dependency upgrades detected",
+	})
+
+	// GitHubAPICalls counts the requests to the GitHub API
+	GitHubAPICalls = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "github_api_calls_total",
+		Help: "Requests to the GitHub API, by method and status code (error when no response was received)",
+	}, []string{"method", "code"})
+)
+
+// Handler serves all registered metrics in the Prometheus text format
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// RecordJob counts a finished job and observes its duration
+func RecordJob(runner, result string, d time.Duration) {
+	JobsRun.WithLabelValues(runner, result).Inc()
+	JobDuration.WithLabelValues(runner, result).Observe(d.Seconds())
+}
+
+// GitHubTransport counts the requests sent through base, http.DefaultTransport when nil
+func GitHubTransport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return countingTransport{base: base}
+}
+
+type countingTransport struct {
+	base http.RoundTripper
+}
+
+func (t countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	code := "error"
+	if err == nil {
+		code = strconv.Itoa(resp.StatusCode)
+	}
+	GitHubAPICalls.WithLabelValues(req.Method, code).Inc()
+	return resp, err
+}
@@ -0,0 +1,65 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordJob(t *testing.T) {
+	before := testutil.ToFloat64(JobsRun.WithLabelValues("go-test", ResultFailed))
+
+	RecordJob("go-test", ResultFailed, 90*time.Second)
+
+	assert.Equal(t, before+1, testutil.ToFloat64(JobsRun.WithLabelValues("go-test", ResultFailed)))
+}
+
+func TestGitHubTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	t.Cleanup(server.Close)
+
+	before := testutil.ToFloat64(GitHubAPICalls.WithLabelValues(http.MethodGet, "404"))
+	client := &http.Client{Transport: GitHubTransport(nil)}
+	resp, err := client.Get(server.URL + "/repos/test/repo")
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, before+1, testutil.ToFloat64(GitHubAPICalls.WithLabelValues(http.MethodGet, "404")))
+
+	beforeErrors := testutil.ToFloat64(GitHubAPICalls.WithLabelValues(http.MethodGet, "error"))
+	_, err = client.Get("http://127.0.0.1:1/unreachable")
+	require.Error(t, err)
+	assert.Equal(t, beforeErrors+1, testutil.ToFloat64(GitHubAPICalls.WithLabelValues(http.MethodGet, "error")))
+}
+
+func TestTemporalHandler(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	handler, closer := NewTemporalHandler(reg)
+
+	tagged := handler.WithTags(map[string]string{"namespace": "default", "task_queue": "engine-ci-queue"})
+	tagged.Counter("temporal_request").Inc(2)
+	tagged.WithTags(map[string]string{"task_queue": "dunebot"}).Counter("temporal_request").Inc(1)
+	tagged.Gauge("temporal_num_pollers").Update(4)
+	tagged.Timer("temporal_request_latency").Record(250 * time.Millisecond)
+	require.NoError(t, closer.Close())
+
+	expected := `
+# HELP temporal_request temporal_request counter
+# TYPE temporal_request counter
+temporal_request{namespace="default",task_queue="dunebot"} 1
+temporal_request{namespace="default",task_queue="engine_ci_queue"} 2
+# HELP temporal_num_pollers temporal_num_pollers gauge
+# TYPE temporal_num_pollers gauge
+temporal_num_pollers{namespace="default",task_queue="engine_ci_queue"} 4
+`
+	require.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(expected), "temporal_request", "temporal_num_pollers"))
+	assert.Equal(t, 1, testutil.CollectAndCount(reg, "temporal_request_latency"))
+}
@@ -0,0 +1,39 @@
+package metrics
+
+import (
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/uber-go/tally/v4"
+	tallyprom "github.com/uber-go/tally/v4/prometheus"
+	"go.temporal.io/sdk/client"
+	sdktally "go.temporal.io/sdk/contrib/tally"
+)
+
+// temporalReportInterval is how often the SDK metrics are handed to Prometheus
+const temporalReportInterval = time.Second
+
+// timerBuckets are the histogram buckets in seconds of the SDK timers, from poll latencies to long activities
+var timerBuckets = []float64{0.001, 0.005, 0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 300, 900}
+
+// NewTemporalHandler returns a metrics handler for client.Options that reports the SDK metrics with the
+// tally Prometheus reporter to reg, the default Prometheus registry when nil
+// Close the returned closer when the client is closed, it reports the last values
+func NewTemporalHandler(reg prometheus.Registerer) (client.MetricsHandler, io.Closer) {
+	reporter := tallyprom.NewReporter(tallyprom.Options{
+		Registerer:              reg,
+		DefaultTimerType:        tallyprom.HistogramTimerType,
+		DefaultHistogramBuckets: timerBuckets,
+		OnRegisterError: func(err error) {
+			slog.Warn("Dropped Temporal SDK metric", "error", err)
+		},
+	})
+	scope, closer := tally.NewRootScope(tally.ScopeOptions{
+		CachedReporter:  reporter,
+		Separator:       tallyprom.DefaultSeparator,
+		SanitizeOptions: &sdktally.PrometheusSanitizeOptions,
+	}, temporalReportInterval)
+	return sdktally.NewMetricsHandler(scope), closer
+}
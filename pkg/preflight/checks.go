@@ -0,0 +1,212 @@
+package preflight
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	"golang.org/x/mod/semver"
+)
+
+// GitHubAPI is the GitHub API the token checks call
+var GitHubAPI = "https://api.github.com"
+
+// versionArgs print the version of the tools that don't support --version
+var versionArgs = map[string][]string{"go": {"version"}}
+
+// versionPattern finds the version in the output of `<tool> --version`, e.g. git version 2.43.0 or go1.22.5
+var versionPattern = regexp.MustCompile(`(\d+)\.(\d+)(\.\d+)?`)
+
+// installed are the tools a bundle installed outside PATH, by name
+var installed sync.Map
+
+// Install records the path of a tool a bundle installed outside PATH, the checks and the activities run it from there
+func Install(name, path string) {
+	installed.Store(name, path)
+}
+
+// Installed returns the path of a tool installed with Install
+func Installed(name string) (string, bool) {
+	path, ok := installed.Load(name)
+	if !ok {
+		return "", false
+	}
+	return path.(string), true
+}
+
+// LookPath returns the path of the installed tool or else of the tool in PATH
+func LookPath(name string) (string, error) {
+	if path, ok := Installed(name); ok {
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+	return exec.LookPath(name)
+}
+
+// Secret passes when the environment variable is set
+func Secret(name string) Check {
+	return Check{Name: "secret " + name, Run: func(context.Context) (string, error) {
+		if os.Getenv(name) == "" {
+			return "", errors.New("not set")
+		}
+		return "set", nil
+	}}
+}
+
+// Tool passes when the tool is installed or in PATH and, with a minimum version, reports at least that version
+func Tool(name, minVersion string) Check {
+	checkName := "tool " + name
+	if minVersion != "" {
+		checkName += " >= " + minVersion
+	}
+	return Check{Name: checkName, Run: func(ctx context.Context) (string, error) {
+		path, err := LookPath(name)
+		if err != nil {
+			return "", fmt.Errorf("%s not found in PATH", name)
+		}
+		version, err := toolVersion(ctx, name, path)
+		if minVersion == "" {
+			// The version is informational only
+			if err != nil {
+				return path, nil
+			}
+			return path + " " + version, nil
+		}
+		if err != nil {
+			return "", err
+		}
+		if semver.Compare("v"+version, "v"+minVersion) < 0 {
+			return "", fmt.Errorf("version %s is older than %s", version, minVersion)
+		}
+		return path + " " + version, nil
+	}}
+}
+
+// toolVersion runs the tool to find its version
+func toolVersion(ctx context.Context, name, path string) (string, error) {
+	args, ok := versionArgs[name]
+	if !ok {
+		args = []string{"--version"}
+	}
+	output, err := exec.CommandContext(ctx, path, args...).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("%s %s failed: %w", name, strings.Join(args, " "), err)
+	}
+	version := versionPattern.FindString(string(output))
+	if version == "" {
+		return "", fmt.Errorf("no version in %q", strings.TrimSpace(string(output)))
+	}
+	return version, nil
+}
+
+// GitHubToken passes when the token in the environment variable authenticates with GitHub and, for classic personal
+// access tokens, has the scopes; fine-grained tokens don't report their permissions
+func GitHubToken(env string, scopes ...string) Check {
+	return Check{Name: "github token " + env, Run: func(ctx context.Context) (string, error) {
+		token := os.Getenv(env)
+		if token == "" {
+			return "", errors.New("not set")
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, GitHubAPI+"/user", nil)
+		if err != nil {
+			return "", err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Accept", "application/vnd.github+json")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return "", fmt.Errorf("GitHub API not reachable: %w", err)
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode == http.StatusUnauthorized {
+			return "", errors.New("token is invalid or expired")
+		}
+		if resp.StatusCode != http.StatusOK {
+			return "", fmt.Errorf("GitHub API answered %s", resp.Status)
+		}
+
+		header, classic := resp.Header["X-Oauth-Scopes"]
+		if !classic {
+			return "fine-grained token, scopes not checked", nil
+		}
+		granted := strings.Split(strings.Join(header, ","), ",")
+		for i := range granted {
+			granted[i] = strings.TrimSpace(granted[i])
+		}
+		var missing []string
+		for _, scope := range scopes {
+			if !slices.Contains(granted, scope) {
+				missing = append(missing, scope)
+			}
+		}
+		if len(missing) > 0 {
+			return "", fmt.Errorf("token lacks the scopes %s", strings.Join(missing, ", "))
+		}
+		return "scopes " + strings.Join(granted, ", "), nil
+	}}
+}
+
+// WritableDir passes when a file can be created in dir
+func WritableDir(dir string) Check {
+	return Check{Name: "writable " + dir, Run: func(context.Context) (string, error) {
+		f, err := os.CreateTemp(dir, ".preflight-*")
+		if err != nil {
+			return "", err
+		}
+		_ = f.Close()
+		return "ok", os.Remove(f.Name())
+	}}
+}
+
+// FreeSpace passes when the file system of dir has at least minBytes available
+func FreeSpace(dir string, minBytes uint64) Check {
+	return Check{Name: fmt.Sprintf("free space %s >= %s", dir, formatBytes(minBytes)), Run: func(context.Context) (string, error) {
+		available, err := availableBytes(dir)
+		if errors.Is(err, errors.ErrUnsupported) {
+			return "not checked on this platform", nil
+		}
+		if err != nil {
+			return "", err
+		}
+		if available < minBytes {
+			return "", fmt.Errorf("only %s available", formatBytes(available))
+		}
+		return formatBytes(available) + " available", nil
+	}}
+}
+
+// ModuleProxy passes when the Go module proxy serves module versions
+func ModuleProxy(proxy string) Check {
+	proxy = strings.TrimSuffix(proxy, "/")
+	return Check{Name: "module proxy " + proxy, Run: func(ctx context.Context) (string, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, proxy+"/golang.org/x/mod/@latest", nil)
+		if err != nil {
+			return "", err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return "", fmt.Errorf("%s not reachable: %w", proxy, err)
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return "", fmt.Errorf("%s answered %s", proxy, resp.Status)
+		}
+		return "reachable", nil
+	}}
+}
+
+func formatBytes(n uint64) string {
+	const gib = 1 << 30
+	if n >= gib {
+		return fmt.Sprintf("%.1f GiB", float64(n)/gib)
+	}
+	return fmt.Sprintf("%d MiB", n>>20)
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package preflight
+
+import "errors"
+
+// availableBytes is not supported on this platform
+func availableBytes(string) (uint64, error) {
+	return 0, errors.ErrUnsupported
+}
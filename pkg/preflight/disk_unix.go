@@ -0,0 +1,14 @@
+//go:build unix
+
+package preflight
+
+import "syscall"
+
+// availableBytes returns the space of the file system of dir available to the worker
+func availableBytes(dir string) (uint64, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(dir, &st); err != nil {
+		return 0, err
+	}
+	return st.Bavail * uint64(st.Bsize), nil
+}
@@ -0,0 +1,77 @@
+// Package preflight checks the requirements of the workflow bundles before the worker polls, so a missing tool or an
+// invalid token fails the start with a readable report instead of failing deep inside an activity
+// `temporal-worker doctor` runs the same checks on demand
+package preflight
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// Timeout bounds every check
+var Timeout = 15 * time.Second
+
+// Check is a requirement, Run returns what it found, e.g. the version of a tool
+type Check struct {
+	Name string
+	Run  func(ctx context.Context) (string, error)
+}
+
+// Result is the outcome of a check of a bundle
+type Result struct {
+	Bundle string
+	Check  string
+	Detail string
+	Err    error
+}
+
+// Report holds the results in the order of the checks
+type Report []Result
+
+// Run runs the checks of a bundle concurrently
+func Run(ctx context.Context, bundle string, checks ...Check) Report {
+	report := make(Report, len(checks))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			checkCtx, cancel := context.WithTimeout(ctx, Timeout)
+			defer cancel()
+			detail, err := check.Run(checkCtx)
+			report[i] = Result{Bundle: bundle, Check: check.Name, Detail: detail, Err: err}
+		}()
+	}
+	wg.Wait()
+	return report
+}
+
+// Err joins the failed checks, nil when all passed
+func (r Report) Err() error {
+	var errs []error
+	for _, result := range r {
+		if result.Err != nil {
+			errs = append(errs, fmt.Errorf("%s: %s: %w", result.Bundle, result.Check, result.Err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Print writes the report as a table
+func (r Report) Print(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "STATUS\tBUNDLE\tCHECK\tDETAIL")
+	for _, result := range r {
+		status, detail := "ok", result.Detail
+		if result.Err != nil {
+			status, detail = "FAIL", result.Err.Error()
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", status, result.Bundle, result.Check, detail)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,146 @@
+package preflight
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun(t *testing.T) {
+	pass := Check{Name: "pass", Run: func(context.Context) (string, error) { return "found", nil }}
+	fail := Check{Name: "fail", Run: func(context.Context) (string, error) { return "", errors.New("missing") }}
+
+	report := Run(context.Background(), "golangmajor", pass, fail, pass)
+	require.Len(t, report, 3)
+	assert.Equal(t, Result{Bundle: "golangmajor", Check: "pass", Detail: "found"}, report[0])
+	assert.EqualError(t, report.Err(), "golangmajor: fail: missing")
+
+	var out bytes.Buffer
+	require.NoError(t, report.Print(&out))
+	assert.Equal(t, "STATUS  BUNDLE       CHECK  DETAIL\n"+
+		"ok      golangmajor  pass   found\n"+
+		"FAIL    golangmajor  fail   missing\n"+
+		"ok      golangmajor  pass   found\n", out.String())
+
+	assert.NoError(t, Run(context.Background(), "diagnostics").Err())
+}
+
+// fakeTool puts a script printing output in a PATH of its own
+func fakeTool(t *testing.T, name, output string) {
+	t.Helper()
+	dir := t.TempDir()
+	script := "#!/bin/sh\necho '" + output + "'\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(script), 0o755))
+	t.Setenv("PATH", dir)
+}
+
+func TestTool(t *testing.T) {
+	tests := []struct {
+		name       string
+		tool       string
+		output     string
+		minVersion string
+		detail     string
+		err        string
+	}{
+		{name: "presence", tool: "mod", output: "usage: mod", detail: "mod"},
+		{name: "version with go syntax", tool: "go", output: "go version go1.22.5 linux/amd64", minVersion: "1.21", detail: "go 1.22.5"},
+		{name: "too old", tool: "git", output: "git version 2.17.1", minVersion: "2.25", err: "version 2.17.1 is older than 2.25"},
+		{name: "no version", tool: "git", output: "git", minVersion: "2.25", err: `no version in "git"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeTool(t, tt.tool, tt.output)
+			detail, err := Tool(tt.tool, tt.minVersion).Run(context.Background())
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, filepath.Join(os.Getenv("PATH"), tt.detail), detail)
+		})
+	}
+
+	t.Run("missing", func(t *testing.T) {
+		t.Setenv("PATH", t.TempDir())
+		_, err := Tool("go", "1.21").Run(context.Background())
+		assert.EqualError(t, err, "go not found in PATH")
+	})
+}
+
+func TestGitHubToken(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Header.Get("Authorization") {
+		case "Bearer classic":
+			w.Header().Set("X-OAuth-Scopes", "repo, read:org")
+		case "Bearer public":
+			w.Header().Set("X-OAuth-Scopes", "public_repo")
+		case "Bearer fine-grained":
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer server.Close()
+	previous := GitHubAPI
+	GitHubAPI = server.URL
+	t.Cleanup(func() { GitHubAPI = previous })
+
+	tests := []struct {
+		token  string
+		detail string
+		err    string
+	}{
+		{token: "classic", detail: "scopes repo, read:org"},
+		{token: "fine-grained", detail: "fine-grained token, scopes not checked"},
+		{token: "public", err: "token lacks the scopes repo"},
+		{token: "revoked", err: "token is invalid or expired"},
+		{token: "", err: "not set"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.token, func(t *testing.T) {
+			t.Setenv("PREFLIGHT_TEST_TOKEN", tt.token)
+			detail, err := GitHubToken("PREFLIGHT_TEST_TOKEN", "repo").Run(context.Background())
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.detail, detail)
+		})
+	}
+}
+
+func TestModuleProxy(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/golang.org/x/mod/@latest" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	_, err := ModuleProxy(server.URL + "/").Run(context.Background())
+	assert.NoError(t, err)
+	_, err = ModuleProxy(server.URL + "/private").Run(context.Background())
+	assert.ErrorContains(t, err, "404 Not Found")
+}
+
+func TestWorkspace(t *testing.T) {
+	dir := t.TempDir()
+	_, err := WritableDir(dir).Run(context.Background())
+	assert.NoError(t, err)
+	_, err = WritableDir(filepath.Join(dir, "missing")).Run(context.Background())
+	assert.Error(t, err)
+
+	_, err = FreeSpace(dir, 1).Run(context.Background())
+	assert.NoError(t, err)
+	_, err = FreeSpace(dir, 1<<62).Run(context.Background())
+	assert.ErrorContains(t, err, "available")
+}
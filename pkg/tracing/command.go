@@ -0,0 +1,66 @@
+package tracing
+
+import (
+	"context"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Run runs cmd in a span that is a child of the activity span in ctx
+// The span is named after the command and its subcommand, e.g. `git clone`; the other arguments are not recorded
+// since they can carry credentials
+func Run(ctx context.Context, cmd *exec.Cmd) error {
+	end := startCommand(ctx, cmd)
+	err := cmd.Run()
+	end(err)
+	return err
+}
+
+// Output is cmd.Output in a span, see Run
+func Output(ctx context.Context, cmd *exec.Cmd) ([]byte, error) {
+	end := startCommand(ctx, cmd)
+	output, err := cmd.Output()
+	end(err)
+	return output, err
+}
+
+// CombinedOutput is cmd.CombinedOutput in a span, see Run
+func CombinedOutput(ctx context.Context, cmd *exec.Cmd) ([]byte, error) {
+	end := startCommand(ctx, cmd)
+	output, err := cmd.CombinedOutput()
+	end(err)
+	return output, err
+}
+
+// startCommand starts the span of cmd, the returned func ends it with the result of the command
+func startCommand(ctx context.Context, cmd *exec.Cmd) func(error) {
+	name := filepath.Base(cmd.Path)
+	if len(cmd.Args) > 0 {
+		name = filepath.Base(cmd.Args[0])
+	}
+	attrs := []attribute.KeyValue{attribute.String("process.executable.name", name)}
+	if cmd.Dir != "" {
+		attrs = append(attrs, attribute.String("process.working_directory", cmd.Dir))
+	}
+	spanName := name
+	if len(cmd.Args) > 1 && !strings.HasPrefix(cmd.Args[1], "-") {
+		spanName += " " + cmd.Args[1]
+	}
+
+	_, span := tracer().Start(ctx, spanName, trace.WithSpanKind(trace.SpanKindInternal), trace.WithAttributes(attrs...))
+	return func(err error) {
+		if cmd.ProcessState != nil {
+			span.SetAttributes(attribute.Int("process.exit.code", cmd.ProcessState.ExitCode()))
+		}
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}
+}
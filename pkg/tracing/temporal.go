@@ -0,0 +1,40 @@
+package tracing
+
+import (
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/contrib/opentelemetry"
+	"go.temporal.io/sdk/interceptor"
+)
+
+// NewInterceptor returns the Temporal interceptor of the SDK's OpenTelemetry integration, it creates a span
+// for every client call, workflow, activity, signal, query and update and adds the trace and span IDs to the
+// workflow and activity loggers
+// It is both a client and a worker interceptor, set on client.Options it also traces the workers of the client
+func NewInterceptor() (interceptor.Interceptor, error) {
+	return opentelemetry.NewTracingInterceptor(opentelemetry.TracerOptions{
+		Tracer:            tracer(),
+		TextMapPropagator: propagator(),
+		// Workflows started before tracing was enabled carry no or foreign headers
+		AllowInvalidParentSpans: true,
+	})
+}
+
+// Configure adds the tracing interceptor to the client options, it traces the client and its workers
+func Configure(opts *client.Options) error {
+	i, err := NewInterceptor()
+	if err != nil {
+		return err
+	}
+	opts.Interceptors = append(opts.Interceptors, i)
+	return nil
+}
+
+// propagator is the global propagator, trace context and baggage unless the process configured another one
+func propagator() propagation.TextMapPropagator {
+	if p := otel.GetTextMapPropagator(); len(p.Fields()) > 0 {
+		return p
+	}
+	return propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+}
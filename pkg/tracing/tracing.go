@@ -0,0 +1,186 @@
+// Package tracing traces the client, workflows and activities with OpenTelemetry, including the commands and
+// GitHub requests the activities make
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName names the tracer of all spans created by this package
+const instrumentationName = "github.com/containifyci/temporal-worker/pkg/tracing"
+
+// Exporters
+const (
+	ExporterNone   = "none"
+	ExporterOTLP   = "otlp"
+	ExporterStdout = "stdout"
+	ExporterFile   = "file"
+)
+
+// OTLP protocols
+const (
+	ProtocolGRPC = "grpc"
+	ProtocolHTTP = "http/protobuf"
+)
+
+// Settings select the span exporter, usually from the environment or the worker configuration
+type Settings struct {
+	Exporter       string // none (default), otlp, stdout or file
+	Endpoint       string // OTLP endpoint URL, e.g. http://collector:4317, the exporter's default when empty
+	Protocol       string // OTLP protocol, grpc (default) or http/protobuf
+	File           string // file the file exporter appends the spans to as JSON
+	ServiceName    string // service.name of the spans, the binary name when empty
+	ServiceVersion string // service.version of the spans
+}
+
+// SettingsFromEnv reads OTEL_TRACES_EXPORTER, OTEL_EXPORTER_OTLP_ENDPOINT, OTEL_EXPORTER_OTLP_PROTOCOL,
+// OTEL_TRACES_FILE and OTEL_SERVICE_NAME
+func SettingsFromEnv() Settings {
+	return Settings{
+		Exporter:    os.Getenv("OTEL_TRACES_EXPORTER"),
+		Endpoint:    os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"),
+		Protocol:    os.Getenv("OTEL_EXPORTER_OTLP_PROTOCOL"),
+		File:        os.Getenv("OTEL_TRACES_FILE"),
+		ServiceName: os.Getenv("OTEL_SERVICE_NAME"),
+	}
+}
+
+// Enabled reports whether spans are exported
+func (s Settings) Enabled() bool {
+	return s.Exporter != "" && s.Exporter != ExporterNone
+}
+
+// Validate checks the exporter settings
+func (s Settings) Validate() error {
+	var errs []error
+	switch s.Exporter {
+	case "", ExporterNone, ExporterOTLP, ExporterStdout:
+	case ExporterFile:
+		if s.File == "" {
+			errs = append(errs, errors.New("the file exporter requires a file"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("unknown exporter %q, expected none, otlp, stdout or file", s.Exporter))
+	}
+	switch s.Protocol {
+	case "", ProtocolGRPC, ProtocolHTTP:
+	default:
+		errs = append(errs, fmt.Errorf("unknown OTLP protocol %q, expected grpc or http/protobuf", s.Protocol))
+	}
+	if s.Endpoint != "" {
+		if u, err := url.Parse(s.Endpoint); err != nil || u.Scheme == "" || u.Host == "" {
+			errs = append(errs, fmt.Errorf("OTLP endpoint %q must be a URL like http://collector:4317", s.Endpoint))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Setup installs the tracer provider of the settings as the global one and returns its shutdown, which flushes
+// the pending spans
+// Without an exporter nothing is installed, the spans of this package are then no-ops
+// The sampler follows OTEL_TRACES_SAMPLER, by default every trace started by the client is sampled
+func Setup(ctx context.Context, s Settings) (func(context.Context) error, error) {
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+	if !s.Enabled() {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, closeOutput, err := newExporter(ctx, s)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s span exporter: %w", s.Exporter, err)
+	}
+
+	name := s.ServiceName
+	if name == "" {
+		name = filepath.Base(os.Args[0])
+	}
+	attrs := []attribute.KeyValue{attribute.String("service.name", name)}
+	if s.ServiceVersion != "" {
+		attrs = append(attrs, attribute.String("service.version", s.ServiceVersion))
+	}
+	res, err := resource.New(ctx, resource.WithFromEnv(), resource.WithTelemetrySDK(), resource.WithHost(), resource.WithAttributes(attrs...))
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe the service: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res))
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	return func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+		if closeOutput != nil {
+			err = errors.Join(err, closeOutput.Close())
+		}
+		return err
+	}, nil
+}
+
+// newExporter returns the exporter of the settings and the file it writes to, if any
+func newExporter(ctx context.Context, s Settings) (sdktrace.SpanExporter, io.Closer, error) {
+	switch s.Exporter {
+	case ExporterStdout:
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(os.Stdout), stdouttrace.WithPrettyPrint())
+		return exporter, nil, err
+	case ExporterFile:
+		f, err := os.OpenFile(s.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, nil, err
+		}
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(f))
+		if err != nil {
+			_ = f.Close()
+			return nil, nil, err
+		}
+		return exporter, f, nil
+	}
+
+	if s.Protocol == ProtocolHTTP {
+		var opts []otlptracehttp.Option
+		if s.Endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpointURL(tracesURL(s.Endpoint)))
+		}
+		exporter, err := otlptracehttp.New(ctx, opts...)
+		return exporter, nil, err
+	}
+	var opts []otlptracegrpc.Option
+	if s.Endpoint != "" {
+		opts = append(opts, otlptracegrpc.WithEndpointURL(s.Endpoint))
+	}
+	exporter, err := otlptracegrpc.New(ctx, opts...)
+	return exporter, nil, err
+}
+
+// tracesURL appends the traces path to an OTLP/HTTP base URL, like the exporter does for OTEL_EXPORTER_OTLP_ENDPOINT
+func tracesURL(endpoint string) string {
+	u, err := url.Parse(endpoint)
+	if err != nil || strings.Trim(u.Path, "/") != "" {
+		return endpoint
+	}
+	u.Path = "/v1/traces"
+	return u.String()
+}
+
+// tracer returns the tracer of this package from the global provider, so it follows a later Setup
+func tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
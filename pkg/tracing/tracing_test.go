@@ -0,0 +1,143 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/interceptor"
+	"go.temporal.io/sdk/testsuite"
+	"go.temporal.io/sdk/worker"
+	"go.temporal.io/sdk/workflow"
+)
+
+func TestSettings_Validate(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings Settings
+		err      string
+	}{
+		{name: "disabled", settings: Settings{}},
+		{name: "otlp", settings: Settings{Exporter: ExporterOTLP, Endpoint: "http://collector:4318", Protocol: ProtocolHTTP}},
+		{name: "unknown exporter", settings: Settings{Exporter: "jaeger"}, err: `unknown exporter "jaeger"`},
+		{name: "file without path", settings: Settings{Exporter: ExporterFile}, err: "requires a file"},
+		{name: "unknown protocol", settings: Settings{Exporter: ExporterOTLP, Protocol: "http/json"}, err: `unknown OTLP protocol "http/json"`},
+		{name: "endpoint without scheme", settings: Settings{Exporter: ExporterOTLP, Endpoint: "collector:4317"}, err: "must be a URL"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.settings.Validate()
+			if tt.err == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tt.err)
+		})
+	}
+}
+
+func TestTracesURL(t *testing.T) {
+	assert.Equal(t, "http://collector:4318/v1/traces", tracesURL("http://collector:4318"))
+	assert.Equal(t, "http://collector:4318/v1/traces", tracesURL("http://collector:4318/"))
+	assert.Equal(t, "https://otel.example.com/custom", tracesURL("https://otel.example.com/custom"))
+}
+
+func TestSetup_File(t *testing.T) {
+	previous := otel.GetTracerProvider()
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	path := filepath.Join(t.TempDir(), "spans.json")
+	shutdown, err := Setup(context.Background(), Settings{Exporter: ExporterFile, File: path, ServiceName: "worker-test"})
+	require.NoError(t, err)
+
+	require.NoError(t, Run(context.Background(), exec.Command("go", "version")))
+	require.NoError(t, shutdown(context.Background()))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"Name":"go version"`)
+	assert.Contains(t, string(data), "worker-test")
+}
+
+func TestSetup_Disabled(t *testing.T) {
+	shutdown, err := Setup(context.Background(), Settings{Exporter: ExporterNone})
+	require.NoError(t, err)
+	assert.NoError(t, shutdown(context.Background()))
+}
+
+func TestInterceptor(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	t.Cleanup(server.Close)
+	httpClient := &http.Client{Transport: Transport(nil)}
+
+	tracedActivity := func(ctx context.Context) error {
+		if err := Run(ctx, exec.Command("go", "version")); err != nil {
+			return err
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/repos/containifyci/temporal-worker?token=secret", http.NoBody)
+		if err != nil {
+			return err
+		}
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			return err
+		}
+		return resp.Body.Close()
+	}
+	tracedWorkflow := func(ctx workflow.Context) error {
+		ctx = workflow.WithActivityOptions(ctx, workflow.ActivityOptions{StartToCloseTimeout: time.Minute})
+		return workflow.ExecuteActivity(ctx, "tracedActivity").Get(ctx, nil)
+	}
+
+	tracingInterceptor, err := NewInterceptor()
+	require.NoError(t, err)
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestWorkflowEnvironment()
+	env.SetWorkerOptions(worker.Options{Interceptors: []interceptor.WorkerInterceptor{tracingInterceptor}})
+	env.RegisterWorkflowWithOptions(tracedWorkflow, workflow.RegisterOptions{Name: "tracedWorkflow"})
+	env.RegisterActivityWithOptions(tracedActivity, activity.RegisterOptions{Name: "tracedActivity"})
+	env.ExecuteWorkflow("tracedWorkflow")
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = span
+	}
+	for _, name := range []string{"RunWorkflow:tracedWorkflow", "StartActivity:tracedActivity", "RunActivity:tracedActivity", "go version", "HTTP GET"} {
+		require.Contains(t, spans, name)
+	}
+
+	workflowSpan := spans["RunWorkflow:tracedWorkflow"]
+	activitySpan := spans["RunActivity:tracedActivity"]
+	assert.Equal(t, workflowSpan.SpanContext().TraceID(), activitySpan.SpanContext().TraceID(), "the activity continues the workflow trace")
+	assert.Equal(t, spans["StartActivity:tracedActivity"].SpanContext().SpanID(), activitySpan.Parent().SpanID())
+	assert.Equal(t, activitySpan.SpanContext().SpanID(), spans["go version"].Parent().SpanID())
+	assert.Equal(t, activitySpan.SpanContext().SpanID(), spans["HTTP GET"].Parent().SpanID())
+
+	attrs := map[string]string{}
+	for _, attr := range spans["HTTP GET"].Attributes() {
+		attrs[string(attr.Key)] = attr.Value.Emit()
+	}
+	assert.Equal(t, "/repos/containifyci/temporal-worker", attrs["url.path"], "the query string is not recorded")
+	assert.Equal(t, "404", attrs["http.response.status_code"])
+	assert.Equal(t, "Error", spans["HTTP GET"].Status().Code.String())
+}
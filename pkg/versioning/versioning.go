@@ -0,0 +1,112 @@
+// Package versioning registers the workers as a version of a Temporal Worker Deployment
+// The build ID of the version is the release version of the worker, workflows are pinned to the version they started
+// on by default, so a change of the workflow code never replays running executions. Promote makes a new version the
+// current one, new executions start there while the pinned ones finish on the previous version
+package versioning
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/worker"
+	"go.temporal.io/sdk/workflow"
+)
+
+// DefaultDeploymentName is the Worker Deployment of the workers
+const DefaultDeploymentName = "temporal-worker"
+
+// Behaviors of the workflows that don't choose one at registration
+const (
+	BehaviorPinned      = "pinned"      // the execution finishes on the version it started on
+	BehaviorAutoUpgrade = "autoUpgrade" // the execution moves to the current version, changes need workflow.GetVersion
+)
+
+// Settings configure the versioning of the workers
+type Settings struct {
+	Enabled         bool
+	DeploymentName  string
+	BuildID         string // the release version of the worker
+	DefaultBehavior string // pinned or autoUpgrade
+}
+
+// Validate checks the settings
+func (s Settings) Validate() error {
+	if !s.Enabled {
+		return nil
+	}
+	var errs []error
+	if s.DeploymentName == "" {
+		errs = append(errs, errors.New("deployment name must not be empty"))
+	}
+	if s.BuildID == "" {
+		errs = append(errs, errors.New("build ID must not be empty"))
+	}
+	if !slices.Contains([]string{BehaviorPinned, BehaviorAutoUpgrade}, s.DefaultBehavior) {
+		errs = append(errs, fmt.Errorf("unknown default behavior %q, use %s or %s", s.DefaultBehavior, BehaviorPinned, BehaviorAutoUpgrade))
+	}
+	return errors.Join(errs...)
+}
+
+// Version returns the deployment version of the workers
+func (s Settings) Version() worker.WorkerDeploymentVersion {
+	return worker.WorkerDeploymentVersion{DeploymentName: s.DeploymentName, BuildID: s.BuildID}
+}
+
+// Configure registers the workers created with options as the version of the settings, nothing changes when
+// versioning is disabled
+func Configure(options *worker.Options, s Settings) error {
+	if !s.Enabled {
+		return nil
+	}
+	if err := s.Validate(); err != nil {
+		return err
+	}
+	behavior := workflow.VersioningBehaviorPinned
+	if s.DefaultBehavior == BehaviorAutoUpgrade {
+		behavior = workflow.VersioningBehaviorAutoUpgrade
+	}
+	options.DeploymentOptions = worker.DeploymentOptions{
+		UseVersioning:             true,
+		Version:                   s.Version(),
+		DefaultVersioningBehavior: behavior,
+	}
+	return nil
+}
+
+// Promote makes the version with buildID the current version of the deployment and returns the build ID of the
+// previous one, empty when unversioned workers were current
+// The version must have polled, i.e. a worker of that build ID must have started
+func Promote(ctx context.Context, c client.Client, deploymentName, buildID string) (string, error) {
+	handle := c.WorkerDeploymentClient().GetHandle(deploymentName)
+	desc, err := handle.Describe(ctx, client.WorkerDeploymentDescribeOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to describe deployment %s: %w", deploymentName, err)
+	}
+
+	var previous string
+	if current := desc.Info.RoutingConfig.CurrentVersion; current != nil {
+		previous = current.BuildID
+	}
+	if previous == buildID {
+		return previous, nil
+	}
+	known := slices.ContainsFunc(desc.Info.VersionSummaries, func(v client.WorkerDeploymentVersionSummary) bool {
+		return v.Version.BuildID == buildID
+	})
+	if !known {
+		return "", fmt.Errorf("deployment %s has no version %s, start a worker of that build first", deploymentName, buildID)
+	}
+
+	// The conflict token fails the promotion when the deployment changed since it was described
+	_, err = handle.SetCurrentVersion(ctx, client.WorkerDeploymentSetCurrentVersionOptions{
+		BuildID:       buildID,
+		ConflictToken: desc.ConflictToken,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to promote %s of deployment %s: %w", buildID, deploymentName, err)
+	}
+	return previous, nil
+}
@@ -0,0 +1,149 @@
+package versioning
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/worker"
+	"go.temporal.io/sdk/workflow"
+)
+
+func TestConfigure(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings Settings
+		options  worker.DeploymentOptions
+		err      string
+	}{
+		{
+			name:     "disabled",
+			settings: Settings{DeploymentName: DefaultDeploymentName, BuildID: "1.4.0"},
+		},
+		{
+			name:     "pinned",
+			settings: Settings{Enabled: true, DeploymentName: DefaultDeploymentName, BuildID: "1.4.0", DefaultBehavior: BehaviorPinned},
+			options: worker.DeploymentOptions{
+				UseVersioning:             true,
+				Version:                   worker.WorkerDeploymentVersion{DeploymentName: DefaultDeploymentName, BuildID: "1.4.0"},
+				DefaultVersioningBehavior: workflow.VersioningBehaviorPinned,
+			},
+		},
+		{
+			name:     "auto upgrade",
+			settings: Settings{Enabled: true, DeploymentName: "ci", BuildID: "1.4.0", DefaultBehavior: BehaviorAutoUpgrade},
+			options: worker.DeploymentOptions{
+				UseVersioning:             true,
+				Version:                   worker.WorkerDeploymentVersion{DeploymentName: "ci", BuildID: "1.4.0"},
+				DefaultVersioningBehavior: workflow.VersioningBehaviorAutoUpgrade,
+			},
+		},
+		{
+			name:     "invalid",
+			settings: Settings{Enabled: true, DefaultBehavior: "latest"},
+			err:      "deployment name must not be empty\nbuild ID must not be empty\nunknown default behavior \"latest\", use pinned or autoUpgrade",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var options worker.Options
+			err := Configure(&options, tt.settings)
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.options, options.DeploymentOptions)
+		})
+	}
+}
+
+func TestConfigure_Sessions(t *testing.T) {
+	c, err := client.NewLazyClient(client.Options{})
+	require.NoError(t, err)
+	defer c.Close()
+
+	// The Go major upgrades run in sessions, a versioned worker must still run them
+	options := worker.Options{EnableSessionWorker: true}
+	require.NoError(t, Configure(&options, Settings{Enabled: true, DeploymentName: DefaultDeploymentName, BuildID: "1.4.0", DefaultBehavior: BehaviorPinned}))
+	assert.NotPanics(t, func() { worker.New(c, "dunebot", options) })
+}
+
+// fakeDeployment is a Worker Deployment recording the promotions, the embedded interfaces panic on other calls
+type fakeDeployment struct {
+	client.WorkerDeploymentClient
+	client.WorkerDeploymentHandle
+	describe client.WorkerDeploymentDescribeResponse
+	setErr   error
+	promoted []client.WorkerDeploymentSetCurrentVersionOptions
+}
+
+// fakeClient serves the deployment
+type fakeClient struct {
+	client.Client
+	deployment *fakeDeployment
+}
+
+func (c fakeClient) WorkerDeploymentClient() client.WorkerDeploymentClient { return c.deployment }
+
+func (d *fakeDeployment) GetHandle(string) client.WorkerDeploymentHandle { return d }
+
+func (d *fakeDeployment) Describe(context.Context, client.WorkerDeploymentDescribeOptions) (client.WorkerDeploymentDescribeResponse, error) {
+	return d.describe, nil
+}
+
+func (d *fakeDeployment) SetCurrentVersion(_ context.Context, options client.WorkerDeploymentSetCurrentVersionOptions) (client.WorkerDeploymentSetCurrentVersionResponse, error) {
+	d.promoted = append(d.promoted, options)
+	return client.WorkerDeploymentSetCurrentVersionResponse{}, d.setErr
+}
+
+func TestPromote(t *testing.T) {
+	version := func(buildID string) client.WorkerDeploymentVersionSummary {
+		return client.WorkerDeploymentVersionSummary{Version: worker.WorkerDeploymentVersion{DeploymentName: DefaultDeploymentName, BuildID: buildID}}
+	}
+	describe := client.WorkerDeploymentDescribeResponse{
+		ConflictToken: []byte("token"),
+		Info: client.WorkerDeploymentInfo{
+			Name:             DefaultDeploymentName,
+			VersionSummaries: []client.WorkerDeploymentVersionSummary{version("1.3.0"), version("1.4.0")},
+			RoutingConfig: client.WorkerDeploymentRoutingConfig{
+				CurrentVersion: &worker.WorkerDeploymentVersion{DeploymentName: DefaultDeploymentName, BuildID: "1.3.0"},
+			},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		buildID  string
+		setErr   error // of SetCurrentVersion
+		promoted []client.WorkerDeploymentSetCurrentVersionOptions
+		previous string
+		err      string
+	}{
+		{
+			name:     "new version",
+			buildID:  "1.4.0",
+			promoted: []client.WorkerDeploymentSetCurrentVersionOptions{{BuildID: "1.4.0", ConflictToken: []byte("token")}},
+			previous: "1.3.0",
+		},
+		{name: "already current", buildID: "1.3.0", previous: "1.3.0"},
+		{name: "unknown version", buildID: "1.5.0", err: "deployment temporal-worker has no version 1.5.0, start a worker of that build first"},
+		{name: "conflict", buildID: "1.4.0", setErr: errors.New("conflict token mismatch"), err: "failed to promote 1.4.0 of deployment temporal-worker: conflict token mismatch"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &fakeDeployment{describe: describe, setErr: tt.setErr}
+			previous, err := Promote(context.Background(), fakeClient{deployment: d}, DefaultDeploymentName, tt.buildID)
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.previous, previous)
+			assert.Equal(t, tt.promoted, d.promoted)
+		})
+	}
+}
@@ -7,9 +7,16 @@ import (
 	"os"
 	"os/exec"
 	"strings"
+	"time"
+
+	"github.com/containifyci/temporal-worker/pkg/activities/git"
+	"github.com/containifyci/temporal-worker/pkg/metrics"
+	"github.com/containifyci/temporal-worker/pkg/preflight"
+	"github.com/containifyci/temporal-worker/pkg/tracing"
 
 	"go.temporal.io/sdk/activity"
 	"go.temporal.io/sdk/log"
+	"go.temporal.io/sdk/temporal"
 )
 
 // logWriter writes to logger and buffers output
@@ -17,37 +24,108 @@ type logWriter struct {
 	logger log.Logger
 	prefix string
 	buffer *bytes.Buffer
+	redact *redactor
+	// pending holds an incomplete line while secrets are redacted, a secret may span several writes
+	pending []byte
 }
 
 func (w *logWriter) Write(p []byte) (n int, err error) {
 	// Log the output in real-time
-	w.logger.Info(w.prefix, "output", string(p))
+	if w.redact == nil {
+		w.logger.Info(w.prefix, "output", string(p))
+	} else {
+		w.pending = append(w.pending, p...)
+		if i := bytes.LastIndexByte(w.pending, '\n'); i >= 0 {
+			w.logger.Info(w.prefix, "output", w.redact.Redact(string(w.pending[:i+1])))
+			w.pending = append(w.pending[:0], w.pending[i+1:]...)
+		}
+	}
 
 	// Also buffer it for Last50Lines
 	return w.buffer.Write(p)
 }
 
-// RunEngineCI executes the engine-ci binary in the specified working directory
+// flush logs the last incomplete line
+func (w *logWriter) flush() {
+	if len(w.pending) > 0 {
+		w.logger.Info(w.prefix, "output", w.redact.Redact(string(w.pending)))
+		w.pending = nil
+	}
+}
+
+// RunEngineCI runs engine-ci with the arguments of the previous release
+// Deprecated: kept for one release so activities scheduled before the upgrade still decode on retry, use RunEngineCIJob
 func RunEngineCI(ctx context.Context, workDir string, args []string, env map[string]string) (*EngineCIDetails, error) {
+	return RunEngineCIJob(ctx, RunEngineCIInput{WorkDir: workDir, Args: args, Env: env})
+}
+
+// RunEngineCIJob executes the job's runner (engine-ci by default) in the specified working directory
+func RunEngineCIJob(ctx context.Context, input RunEngineCIInput) (*EngineCIDetails, error) {
 	logger := activity.GetLogger(ctx)
-	logger.Info("RunEngineCI started", "workDir", workDir, "args", args)
+	logger.Info("RunEngineCIJob started", "workDir", input.WorkDir, "runner", input.Runner.Kind, "args", input.Args)
 
-	// Build command
-	cmd := exec.Command("engine-ci", args...)
-	cmd.Dir = workDir
+	// Build command, rejected runners fail without retry
+	runner, name, args, err := resolveCommand(input.Runner, input.Args)
+	if err != nil {
+		return nil, err
+	}
+	// The worker may have installed the runner's tool outside PATH, e.g. engine-ci
+	if path, ok := preflight.Installed(name); ok {
+		name = path
+	}
+	cmd := exec.Command(name, args...)
+	cmd.Dir = input.WorkDir
+
+	// The workflow validates the job, the activity enforces the environment keys again as it runs the command
+	for key := range input.Env {
+		if err := validateEnvKey(key); err != nil {
+			return nil, temporal.NewNonRetryableApplicationError(err.Error(), invalidJobErrorType, err)
+		}
+	}
+
+	// Resolve secret references, their values are redacted from the output
+	jobEnv, redact, err := resolveSecrets(Secrets, input.Env)
+	if err != nil {
+		return nil, err
+	}
 
 	// Set environment variables
 	cmd.Env = os.Environ()
-	for k, v := range env {
+	for k, v := range jobEnv {
 		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", k, v))
 	}
 
+	// Lock and inject the managed caches, they take precedence over the job environment
+	var caches []CacheStatus
+	if !input.Cache.IsEmpty() {
+		lease, err := acquireCaches(ctx, input.Cache)
+		if err != nil {
+			return nil, fmt.Errorf("failed to prepare caches: %w", err)
+		}
+		defer func() {
+			lease.release()
+			evicted, err := evictCaches()
+			if err != nil {
+				logger.Warn("Cache eviction failed (non-critical)", "error", err)
+			}
+			if len(evicted) > 0 {
+				logger.Info("Evicted caches", "keys", evicted)
+			}
+		}()
+		for k, v := range lease.env {
+			cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", k, v))
+		}
+		caches = lease.statuses
+		logger.Info("Caches prepared", "key", input.Cache.Key, "caches", caches)
+	}
+
 	// Create buffer and logger writer for real-time output streaming
 	var outputBuf bytes.Buffer
 	writer := &logWriter{
 		logger: logger,
-		prefix: "[engine-ci]",
+		prefix: "[" + runner.Name() + "]",
 		buffer: &outputBuf,
+		redact: redact,
 	}
 
 	// Set stdout and stderr to our custom writer
@@ -55,8 +133,11 @@ func RunEngineCI(ctx context.Context, workDir string, args []string, env map[str
 	cmd.Stderr = writer
 
 	// Execute and capture output (streams in real-time)
-	err := cmd.Run()
-	outStr := outputBuf.String()
+	started := time.Now()
+	err = tracing.Run(ctx, cmd)
+	duration := time.Since(started)
+	writer.flush()
+	outStr := redact.Redact(outputBuf.String())
 
 	// Determine exit code
 	exitCode := 0
@@ -65,7 +146,8 @@ func RunEngineCI(ctx context.Context, workDir string, args []string, env map[str
 			exitCode = exitError.ExitCode()
 		} else {
 			// Command failed to execute (binary not found, permission denied, etc.)
-			return nil, fmt.Errorf("failed to execute engine-ci: %w", err)
+			metrics.RecordJob(runner.Name(), metrics.ResultError, duration)
+			return nil, fmt.Errorf("failed to execute %s: %w", name, err)
 		}
 	}
 
@@ -77,14 +159,31 @@ func RunEngineCI(ctx context.Context, workDir string, args []string, env map[str
 	}
 
 	details := &EngineCIDetails{
-		ExitCode:    exitCode,
-		Last50Lines: last50,
+		ExitCode:     exitCode,
+		Last50Lines:  last50,
+		FailureClass: ClassifyOutput(exitCode, outStr),
+		Caches:       caches,
+	}
+
+	// Attach the parsed test reports, they are gone once the workspace is cleaned up
+	tests, err := CollectTestReports(input.WorkDir, input.Reports, outStr)
+	if err != nil {
+		logger.Warn("Test report collection incomplete (non-critical)", "error", err)
+	}
+	redact.redactTests(tests)
+	details.Tests = tests
+
+	// Record the commit that was actually built, used as result cache key
+	if sha, err := git.HeadCommit(ctx, input.WorkDir); err == nil {
+		details.CommitSHA = sha
 	}
 
 	if exitCode != 0 {
-		logger.Error("Engine-CI execution failed", "exitCode", exitCode, "output", last50)
+		metrics.RecordJob(runner.Name(), metrics.ResultFailed, duration)
+		logger.Error("Engine-CI execution failed", "runner", runner.Name(), "exitCode", exitCode, "failureClass", details.FailureClass, "output", last50)
 	} else {
-		logger.Info("Engine-CI execution successful")
+		metrics.RecordJob(runner.Name(), metrics.ResultSucceeded, duration)
+		logger.Info("Engine-CI execution successful", "runner", runner.Name())
 	}
 
 	return details, nil
@@ -26,8 +26,9 @@ func TestSanitizeRepoNameInActivities(t *testing.T) {
 		repoURL  string
 		expected string
 	}{
-		{"https://github.com/test/repo", "/tmp/ci-repo"},
-		{"https://github.com/test/my.repo", "/tmp/ci-my-repo"},
+		{"https://github.com/test/repo", "/tmp/ci/github.com/test/repo"},
+		{"https://github.com/test/my.repo", "/tmp/ci/github.com/test/my.repo"},
+		{"https://github.com/other/my.repo", "/tmp/ci/github.com/other/my.repo"},
 	}
 
 	for _, tc := range testCases {
@@ -45,7 +46,7 @@ func TestRunEngineCI(t *testing.T) {
 
 	testSuite := &testsuite.WorkflowTestSuite{}
 	env := testSuite.NewTestActivityEnvironment()
-	env.RegisterActivity(RunEngineCI)
+	env.RegisterActivity(RunEngineCIJob)
 
 	// Create a temporary directory for the test
 	tempDir := "/tmp/test-engine-ci-version-" + strings.ReplaceAll(t.Name(), "/", "-")
@@ -53,8 +54,8 @@ func TestRunEngineCI(t *testing.T) {
 	require.NoError(t, err)
 	t.Cleanup(func() { _ = os.RemoveAll(tempDir) })
 
-	// Execute RunEngineCI with 'version' argument
-	val, err := env.ExecuteActivity(RunEngineCI, tempDir, []string{"version"}, map[string]string{})
+	// Execute RunEngineCIJob with 'version' argument
+	val, err := env.ExecuteActivity(RunEngineCIJob, RunEngineCIInput{WorkDir: tempDir, Args: []string{"version"}, Env: map[string]string{}})
 
 	// Should succeed without error
 	assert.NoError(t, err)
@@ -80,3 +81,87 @@ func TestRunEngineCI_ExitCodeHandling(t *testing.T) {
 
 	t.Skip("Skipping integration test - requires engine-ci binary")
 }
+
+func TestRunEngineCI_CommandRunner(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not found in PATH, skipping test")
+	}
+	defer func(orig []string) { AllowedCommands = orig }(AllowedCommands)
+	AllowedCommands = []string{"sh"}
+
+	testSuite := &testsuite.WorkflowTestSuite{}
+	env := testSuite.NewTestActivityEnvironment()
+	env.RegisterActivity(RunEngineCIJob)
+
+	val, err := env.ExecuteActivity(RunEngineCIJob, RunEngineCIInput{
+		WorkDir: t.TempDir(),
+		Runner:  RunnerSpec{Kind: RunnerCommand, Command: "sh"},
+		Args:    []string{"-c", "echo $GREETING; exit 3"},
+		Env:     map[string]string{"GREETING": "hello"},
+	})
+	require.NoError(t, err)
+
+	var details *EngineCIDetails
+	require.NoError(t, val.Get(&details))
+	assert.Equal(t, 3, details.ExitCode)
+	assert.Equal(t, FailureClassBuild, details.FailureClass)
+	assert.Contains(t, details.Last50Lines, "hello")
+}
+
+func TestRunEngineCI_CommandNotAllowed(t *testing.T) {
+	defer func(orig []string) { AllowedCommands = orig }(AllowedCommands)
+	AllowedCommands = []string{"make"}
+
+	testSuite := &testsuite.WorkflowTestSuite{}
+	env := testSuite.NewTestActivityEnvironment()
+	env.RegisterActivity(RunEngineCIJob)
+
+	_, err := env.ExecuteActivity(RunEngineCIJob, RunEngineCIInput{
+		WorkDir: t.TempDir(),
+		Runner:  RunnerSpec{Kind: RunnerCommand, Command: "rm"},
+		Args:    []string{"-rf", "."},
+	})
+	require.Error(t, err)
+	assert.ErrorContains(t, err, `command "rm" is not allowed`)
+	assert.Equal(t, FailureClassInvalid, ClassifyError(err))
+}
+
+func TestRunEngineCI_ReservedEnvKey(t *testing.T) {
+	defer func(orig []string) { AllowedCommands = orig }(AllowedCommands)
+	AllowedCommands = []string{"sh"}
+
+	testSuite := &testsuite.WorkflowTestSuite{}
+	env := testSuite.NewTestActivityEnvironment()
+	env.RegisterActivity(RunEngineCIJob)
+
+	_, err := env.ExecuteActivity(RunEngineCIJob, RunEngineCIInput{
+		WorkDir: t.TempDir(),
+		Runner:  RunnerSpec{Kind: RunnerCommand, Command: "sh"},
+		Args:    []string{"-c", "true"},
+		Env:     map[string]string{"LD_PRELOAD": "/tmp/evil.so"},
+	})
+	require.Error(t, err)
+	assert.ErrorContains(t, err, `"LD_PRELOAD" is reserved`)
+	assert.Equal(t, FailureClassInvalid, ClassifyError(err))
+}
+
+func TestRunEngineCI_PreviousReleaseArguments(t *testing.T) {
+	// A fake engine-ci prints its arguments and environment
+	bin := t.TempDir()
+	script := "#!/bin/sh\necho \"args: $* env: $GREETING\"\n"
+	require.NoError(t, os.WriteFile(bin+"/engine-ci", []byte(script), 0o755))
+	t.Setenv("PATH", bin+":"+os.Getenv("PATH"))
+
+	testSuite := &testsuite.WorkflowTestSuite{}
+	env := testSuite.NewTestActivityEnvironment()
+	env.RegisterActivity(RunEngineCI)
+
+	// The positional arguments the previous release scheduled the activity with
+	val, err := env.ExecuteActivity(RunEngineCI, t.TempDir(), []string{"run", "-t", "build"}, map[string]string{"GREETING": "hello"})
+	require.NoError(t, err)
+
+	var details *EngineCIDetails
+	require.NoError(t, val.Get(&details))
+	assert.Equal(t, 0, details.ExitCode)
+	assert.Contains(t, details.Last50Lines, "args: run -t build env: hello")
+}
@@ -0,0 +1,128 @@
+package engineci
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/containifyci/temporal-worker/pkg/artifacts"
+
+	"go.temporal.io/sdk/activity"
+)
+
+// NewArtifactStore creates the store job artifacts are uploaded to, see artifacts.NewStoreFromEnv
+var NewArtifactStore = artifacts.NewStoreFromEnv
+
+// ArtifactRetention is how long uploaded artifacts are kept, set from ARTIFACT_RETENTION
+var ArtifactRetention = artifacts.RetentionFromEnv()
+
+// artifactPrefix is the part of the artifact store owned by Engine-CI jobs
+const artifactPrefix = "engine-ci"
+
+// ArtifactKey returns the store key of the artifact archive of a job
+func ArtifactKey(jobID string) string {
+	return path.Join(artifactPrefix, jobID, "artifacts.tar.gz")
+}
+
+// CollectArtifacts packages the files matching the globs below the working directory into a
+// gzip compressed tar archive and uploads it to the artifact store
+// It returns no refs when no file matched, expired artifacts of other jobs are pruned afterwards
+func CollectArtifacts(ctx context.Context, input CollectArtifactsInput) ([]ArtifactRef, error) {
+	logger := activity.GetLogger(ctx)
+	logger.Info("CollectArtifacts started", "workDir", input.WorkDir, "globs", input.Globs, "key", input.Key)
+
+	files, err := matchArtifactFiles(input.WorkDir, input.Globs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find artifacts: %w", err)
+	}
+	if len(files) == 0 {
+		logger.Info("No artifacts matched")
+		return []ArtifactRef{}, nil
+	}
+
+	store, err := NewArtifactStore()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open artifact store: %w", err)
+	}
+
+	// Package into a temporary file first, the upload needs the size
+	archive, err := os.CreateTemp("", "engine-ci-artifacts-*.tar.gz")
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = archive.Close()
+		_ = os.Remove(archive.Name())
+	}()
+
+	hash := sha256.New()
+	if err := artifacts.WriteArchive(io.MultiWriter(archive, hash), input.WorkDir, files); err != nil {
+		return nil, fmt.Errorf("failed to package artifacts: %w", err)
+	}
+	size, err := archive.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := archive.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	if err := store.Put(ctx, input.Key, archive, size); err != nil {
+		return nil, fmt.Errorf("failed to upload artifacts: %w", err)
+	}
+
+	now := time.Now()
+	ref := ArtifactRef{
+		Key:       input.Key,
+		Files:     len(files),
+		Size:      size,
+		SHA256:    hex.EncodeToString(hash.Sum(nil)),
+		ExpiresAt: now.Add(ArtifactRetention),
+	}
+	logger.Info("Artifacts uploaded", "key", ref.Key, "files", ref.Files, "size", ref.Size)
+
+	pruned, err := artifacts.Prune(ctx, store, artifactPrefix+"/", ArtifactRetention, now)
+	if err != nil {
+		logger.Warn("Artifact pruning failed (non-critical)", "error", err)
+	}
+	if len(pruned) > 0 {
+		logger.Info("Pruned expired artifacts", "keys", pruned)
+	}
+
+	return []ArtifactRef{ref}, nil
+}
+
+// matchArtifactFiles returns the regular files below workDir matching any glob, relative and slash separated
+func matchArtifactFiles(workDir string, globs []string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(workDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(workDir, p)
+		if err != nil {
+			return err
+		}
+		if rel = filepath.ToSlash(rel); matchAny(globs, rel) {
+			files = append(files, rel)
+		}
+		return nil
+	})
+	return files, err
+}
@@ -0,0 +1,85 @@
+package engineci
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/containifyci/temporal-worker/pkg/artifacts"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/testsuite"
+)
+
+func useArtifactStore(t *testing.T) *artifacts.FilesystemStore {
+	t.Helper()
+	store := artifacts.NewFilesystemStore(t.TempDir())
+	orig := NewArtifactStore
+	NewArtifactStore = func() (artifacts.Store, error) { return store, nil }
+	t.Cleanup(func() { NewArtifactStore = orig })
+	return store
+}
+
+func TestCollectArtifacts(t *testing.T) {
+	store := useArtifactStore(t)
+
+	workDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(workDir, "dist"), 0o755))
+	require.NoError(t, os.MkdirAll(filepath.Join(workDir, ".git"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(workDir, "dist", "app"), []byte("binary"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(workDir, "coverage.out"), []byte("mode: set"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(workDir, "main.go"), []byte("package main"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(workDir, ".git", "coverage.out"), []byte("x"), 0o644))
+
+	env := (&testsuite.WorkflowTestSuite{}).NewTestActivityEnvironment()
+	env.RegisterActivity(CollectArtifacts)
+
+	key := ArtifactKey("github.com/test/repo/5f1c2a9b-1")
+	val, err := env.ExecuteActivity(CollectArtifacts, CollectArtifactsInput{
+		WorkDir: workDir,
+		Globs:   []string{"dist/**", "**/*.out"},
+		Key:     key,
+	})
+	require.NoError(t, err)
+
+	var refs []ArtifactRef
+	require.NoError(t, val.Get(&refs))
+	require.Len(t, refs, 1)
+	assert.Equal(t, "engine-ci/github.com/test/repo/5f1c2a9b-1/artifacts.tar.gz", refs[0].Key)
+	assert.Equal(t, 2, refs[0].Files)
+	assert.NotEmpty(t, refs[0].SHA256)
+	assert.False(t, refs[0].ExpiresAt.IsZero())
+
+	r, err := store.Get(context.Background(), key)
+	require.NoError(t, err)
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+	require.NoError(t, r.Close())
+	assert.Equal(t, refs[0].Size, int64(len(data)))
+
+	files, err := artifacts.ExtractArchive(bytes.NewReader(data), t.TempDir())
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"dist/app", "coverage.out"}, files)
+}
+
+func TestCollectArtifacts_NoMatch(t *testing.T) {
+	useArtifactStore(t)
+
+	env := (&testsuite.WorkflowTestSuite{}).NewTestActivityEnvironment()
+	env.RegisterActivity(CollectArtifacts)
+
+	val, err := env.ExecuteActivity(CollectArtifacts, CollectArtifactsInput{
+		WorkDir: t.TempDir(),
+		Globs:   []string{"dist/**"},
+		Key:     ArtifactKey("job"),
+	})
+	require.NoError(t, err)
+
+	var refs []ArtifactRef
+	require.NoError(t, val.Get(&refs))
+	assert.Empty(t, refs)
+}
@@ -0,0 +1,227 @@
+package engineci
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// CacheRoot is the directory holding all managed Engine-CI caches
+var CacheRoot = cacheRootFromEnv()
+
+// CacheMaxSize is the total size in bytes the managed caches may use before the least recently used keys are evicted
+var CacheMaxSize int64 = 10 << 30
+
+// cacheLockPollInterval is how often a busy cache lock is retried
+var cacheLockPollInterval = 1 * time.Second
+
+// Names of the built-in caches
+const (
+	cacheGoMod   = "gomod"
+	cacheGoBuild = "gobuild"
+)
+
+// lastUsedMarker is touched whenever a cache key is used and drives LRU eviction
+const lastUsedMarker = ".last-used"
+
+var cacheNameSanitizer = regexp.MustCompile(`[^a-z0-9_-]+`)
+
+func cacheRootFromEnv() string {
+	if dir := os.Getenv("ENGINE_CI_CACHE_DIR"); dir != "" {
+		return dir
+	}
+	return filepath.Join(os.TempDir(), "engine-ci-cache")
+}
+
+// IsEmpty reports whether the spec declares no caches at all
+func (c CacheSpec) IsEmpty() bool {
+	return !c.GoModCache && !c.GoBuildCache && len(c.Directories) == 0
+}
+
+// cacheLease is an acquired cache key that is held for the duration of a job
+type cacheLease struct {
+	keyDir   string
+	lock     *cacheLock
+	env      map[string]string
+	statuses []CacheStatus
+}
+
+// acquireCaches locks the cache key of the spec shared, creates the cache directories and
+// returns the environment variables pointing engine-ci at them
+// Jobs of the same key, e.g. the stages of a pipeline, run side by side, eviction waits until none holds the key
+func acquireCaches(ctx context.Context, spec CacheSpec) (*cacheLease, error) {
+	key := sanitizeCacheName(spec.Key)
+	if key == "" {
+		return nil, fmt.Errorf("cache key must not be empty")
+	}
+
+	if err := os.MkdirAll(CacheRoot, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	keyDir := filepath.Join(CacheRoot, key)
+	lock, err := lockCacheShared(ctx, keyDir+".lock")
+	if err != nil {
+		return nil, err
+	}
+	// The key may have been evicted while waiting for the lock
+	if err := os.MkdirAll(keyDir, 0755); err != nil {
+		lock.unlock()
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	lease := &cacheLease{
+		keyDir: keyDir,
+		lock:   lock,
+		env:    map[string]string{},
+	}
+
+	add := func(name, envKey string) error {
+		dir := filepath.Join(keyDir, name)
+		hit := dirHasEntries(dir)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create cache %s: %w", name, err)
+		}
+		lease.env[envKey] = dir
+		lease.statuses = append(lease.statuses, CacheStatus{Name: name, Hit: hit})
+		return nil
+	}
+
+	if spec.GoModCache {
+		if err := add(cacheGoMod, "GOMODCACHE"); err != nil {
+			lease.release()
+			return nil, err
+		}
+	}
+	if spec.GoBuildCache {
+		if err := add(cacheGoBuild, "GOCACHE"); err != nil {
+			lease.release()
+			return nil, err
+		}
+	}
+	for _, dir := range spec.Directories {
+		name := sanitizeCacheName(dir)
+		if name == "" || name == cacheGoMod || name == cacheGoBuild {
+			lease.release()
+			return nil, fmt.Errorf("invalid cache directory name %q", dir)
+		}
+		envKey := "ENGINE_CI_CACHE_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+		if err := add(name, envKey); err != nil {
+			lease.release()
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+	marker := filepath.Join(keyDir, lastUsedMarker)
+	if err := os.WriteFile(marker, nil, 0644); err == nil {
+		_ = os.Chtimes(marker, now, now)
+	}
+
+	return lease, nil
+}
+
+// release unlocks the cache key
+func (l *cacheLease) release() {
+	if l.lock != nil {
+		l.lock.unlock()
+		l.lock = nil
+	}
+}
+
+// evictCaches removes least recently used cache keys until the total size fits into CacheMaxSize
+// Keys that are locked by a running job are never evicted
+func evictCaches() ([]string, error) {
+	entries, err := os.ReadDir(CacheRoot)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	type cacheKey struct {
+		dir      string
+		size     int64
+		lastUsed time.Time
+	}
+
+	var keys []cacheKey
+	var total int64
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		dir := filepath.Join(CacheRoot, entry.Name())
+		size := dirSize(dir)
+		lastUsed := time.Time{}
+		if info, err := os.Stat(filepath.Join(dir, lastUsedMarker)); err == nil {
+			lastUsed = info.ModTime()
+		}
+		keys = append(keys, cacheKey{dir: dir, size: size, lastUsed: lastUsed})
+		total += size
+	}
+
+	sort.Slice(keys, func(i, j int) bool { return keys[i].lastUsed.Before(keys[j].lastUsed) })
+
+	var evicted []string
+	for _, key := range keys {
+		if total <= CacheMaxSize {
+			break
+		}
+		lock, ok := tryLockCache(key.dir + ".lock")
+		if !ok {
+			continue
+		}
+		err := removeCacheDir(key.dir)
+		lock.unlock()
+		if err != nil {
+			return evicted, fmt.Errorf("failed to evict cache %s: %w", key.dir, err)
+		}
+		total -= key.size
+		evicted = append(evicted, filepath.Base(key.dir))
+	}
+	return evicted, nil
+}
+
+// sanitizeCacheName turns a cache key or directory name into a safe path segment
+func sanitizeCacheName(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	name = cacheNameSanitizer.ReplaceAllString(name, "-")
+	return strings.Trim(name, "-")
+}
+
+func dirHasEntries(dir string) bool {
+	entries, err := os.ReadDir(dir)
+	return err == nil && len(entries) > 0
+}
+
+func dirSize(dir string) int64 {
+	var size int64
+	_ = filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info, err := d.Info(); err == nil && !d.IsDir() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
+
+// removeCacheDir removes a cache directory including read-only content such as the Go module cache
+func removeCacheDir(dir string) error {
+	_ = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && d.IsDir() {
+			_ = os.Chmod(path, 0755)
+		}
+		return nil
+	})
+	return os.RemoveAll(dir)
+}
@@ -0,0 +1,57 @@
+//go:build !unix
+
+package engineci
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// cacheLock falls back to an in-process lock on platforms without flock
+type cacheLock struct {
+	path string
+}
+
+var (
+	cacheLocksMu sync.Mutex
+	// cacheLocks counts the shared holders of a lock, -1 marks an exclusive holder
+	cacheLocks = map[string]int{}
+)
+
+func lockCacheShared(ctx context.Context, path string) (*cacheLock, error) {
+	for {
+		cacheLocksMu.Lock()
+		if cacheLocks[path] >= 0 {
+			cacheLocks[path]++
+			cacheLocksMu.Unlock()
+			return &cacheLock{path: path}, nil
+		}
+		cacheLocksMu.Unlock()
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(cacheLockPollInterval):
+		}
+	}
+}
+
+func tryLockCache(path string) (*cacheLock, bool) {
+	cacheLocksMu.Lock()
+	defer cacheLocksMu.Unlock()
+	if cacheLocks[path] != 0 {
+		return nil, false
+	}
+	cacheLocks[path] = -1
+	return &cacheLock{path: path}, true
+}
+
+func (l *cacheLock) unlock() {
+	cacheLocksMu.Lock()
+	defer cacheLocksMu.Unlock()
+	if cacheLocks[l.path] > 1 {
+		cacheLocks[l.path]--
+		return
+	}
+	delete(cacheLocks, l.path)
+}
@@ -0,0 +1,54 @@
+//go:build unix
+
+package engineci
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"syscall"
+	"time"
+)
+
+// cacheLock is an advisory file lock shared by all workers on the host
+type cacheLock struct {
+	file *os.File
+}
+
+// lockCacheShared blocks until the lock file could be locked shared or the context is done
+// Jobs of the same key share the lock, only eviction waits for all of them
+func lockCacheShared(ctx context.Context, path string) (*cacheLock, error) {
+	for {
+		lock, ok := flockCache(path, syscall.LOCK_SH)
+		if ok {
+			return lock, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("waiting for cache lock %s: %w", path, ctx.Err())
+		case <-time.After(cacheLockPollInterval):
+		}
+	}
+}
+
+// tryLockCache locks the lock file exclusively without waiting
+func tryLockCache(path string) (*cacheLock, bool) {
+	return flockCache(path, syscall.LOCK_EX)
+}
+
+func flockCache(path string, how int) (*cacheLock, bool) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, false
+	}
+	if err := syscall.Flock(int(file.Fd()), how|syscall.LOCK_NB); err != nil {
+		_ = file.Close()
+		return nil, false
+	}
+	return &cacheLock{file: file}, true
+}
+
+func (l *cacheLock) unlock() {
+	_ = syscall.Flock(int(l.file.Fd()), syscall.LOCK_UN)
+	_ = l.file.Close()
+}
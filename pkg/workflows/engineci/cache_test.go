@@ -0,0 +1,126 @@
+package engineci
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupCacheRoot(t *testing.T) string {
+	root := t.TempDir()
+	oldRoot, oldSize := CacheRoot, CacheMaxSize
+	CacheRoot = root
+	t.Cleanup(func() {
+		CacheRoot, CacheMaxSize = oldRoot, oldSize
+	})
+	return root
+}
+
+func TestAcquireCaches(t *testing.T) {
+	root := setupCacheRoot(t)
+
+	spec := CacheSpec{
+		Key:          "github.com/test/Repo",
+		GoModCache:   true,
+		GoBuildCache: true,
+		Directories:  []string{"node-modules"},
+	}
+
+	lease, err := acquireCaches(context.Background(), spec)
+	require.NoError(t, err)
+
+	keyDir := filepath.Join(root, "github-com-test-repo")
+	assert.Equal(t, filepath.Join(keyDir, "gomod"), lease.env["GOMODCACHE"])
+	assert.Equal(t, filepath.Join(keyDir, "gobuild"), lease.env["GOCACHE"])
+	assert.Equal(t, filepath.Join(keyDir, "node-modules"), lease.env["ENGINE_CI_CACHE_NODE_MODULES"])
+	assert.Equal(t, []CacheStatus{
+		{Name: "gomod", Hit: false},
+		{Name: "gobuild", Hit: false},
+		{Name: "node-modules", Hit: false},
+	}, lease.statuses)
+
+	// Populate the module cache and reuse the key
+	require.NoError(t, os.WriteFile(filepath.Join(lease.env["GOMODCACHE"], "mod.txt"), []byte("x"), 0644))
+	lease.release()
+
+	lease, err = acquireCaches(context.Background(), spec)
+	require.NoError(t, err)
+	defer lease.release()
+	assert.True(t, lease.statuses[0].Hit)
+	assert.False(t, lease.statuses[1].Hit)
+}
+
+func TestAcquireCaches_InvalidDirectory(t *testing.T) {
+	setupCacheRoot(t)
+
+	_, err := acquireCaches(context.Background(), CacheSpec{Key: "repo", Directories: []string{"gomod"}})
+	assert.Error(t, err)
+
+	_, err = acquireCaches(context.Background(), CacheSpec{Key: "", GoModCache: true})
+	assert.Error(t, err)
+}
+
+func TestAcquireCaches_SharedKey(t *testing.T) {
+	root := setupCacheRoot(t)
+	cacheLockPollInterval = 10 * time.Millisecond
+	t.Cleanup(func() { cacheLockPollInterval = 1 * time.Second })
+
+	// Jobs of the same key, e.g. parallel pipeline stages, don't wait for each other
+	spec := CacheSpec{Key: "repo", GoBuildCache: true}
+	first, err := acquireCaches(context.Background(), spec)
+	require.NoError(t, err)
+	second, err := acquireCaches(context.Background(), spec)
+	require.NoError(t, err)
+
+	// Eviction needs the key for itself
+	lockPath := filepath.Join(root, "repo.lock")
+	_, ok := tryLockCache(lockPath)
+	assert.False(t, ok)
+	first.release()
+	_, ok = tryLockCache(lockPath)
+	assert.False(t, ok)
+	second.release()
+
+	evicting, ok := tryLockCache(lockPath)
+	require.True(t, ok)
+	defer evicting.unlock()
+
+	// A job waits while the key is evicted
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = acquireCaches(ctx, spec)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestEvictCaches(t *testing.T) {
+	root := setupCacheRoot(t)
+
+	// Create two cache keys with 1KiB each, "old" used before "new"
+	for i, key := range []string{"old", "new"} {
+		lease, err := acquireCaches(context.Background(), CacheSpec{Key: key, GoBuildCache: true})
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(lease.env["GOCACHE"], "data"), make([]byte, 1024), 0644))
+		lease.release()
+
+		used := time.Now().Add(time.Duration(i-2) * time.Hour)
+		require.NoError(t, os.Chtimes(filepath.Join(root, key, lastUsedMarker), used, used))
+	}
+
+	// Read-only content like the Go module cache must be removable as well
+	require.NoError(t, os.Chmod(filepath.Join(root, "old", "gobuild"), 0555))
+
+	CacheMaxSize = 1500
+	evicted, err := evictCaches()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"old"}, evicted)
+
+	_, err = os.Stat(filepath.Join(root, "old"))
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(filepath.Join(root, "new"))
+	assert.NoError(t, err)
+}
@@ -0,0 +1,298 @@
+package engineci
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+)
+
+// Headers of a callback request
+const (
+	// CallbackSignatureHeader carries `sha256=<hex HMAC-SHA256 of the body>` when a secret is set
+	CallbackSignatureHeader = "X-Engine-CI-Signature"
+	// CallbackEventHeader names the event, always CallbackEventJobCompleted
+	CallbackEventHeader = "X-Engine-CI-Event"
+	// CallbackDeliveryHeader identifies the job, it is the same for every retry of a delivery
+	CallbackDeliveryHeader = "X-Engine-CI-Delivery"
+)
+
+// CallbackEventJobCompleted is sent when a job finished, failed or was canceled
+const CallbackEventJobCompleted = "job.completed"
+
+// callbackRejectedErrorType is the application error type of callbacks the receiver refused for good
+const callbackRejectedErrorType = "CallbackRejected"
+
+// errInternalCallbackAddr refuses connections of callbacks to the worker's networks
+var errInternalCallbackAddr = errors.New("callback host resolves to an internal address")
+
+// Callback delivery settings
+var (
+	// CallbackRequestTimeout bounds a single delivery attempt
+	CallbackRequestTimeout = 10 * time.Second
+	// CallbackDeliveryTimeout bounds all attempts of a delivery
+	CallbackDeliveryTimeout = 1 * time.Hour
+	// CallbackAllowedHosts may receive callbacks although they are on a loopback, link-local or private
+	// network, all other hosts must resolve to public addresses
+	CallbackAllowedHosts []string
+)
+
+// CallbackStatus is the outcome of a job as reported to the callback URL
+type CallbackStatus string
+
+const (
+	CallbackStatusSucceeded CallbackStatus = "succeeded"
+	CallbackStatusFailed    CallbackStatus = "failed"
+	CallbackStatusSkipped   CallbackStatus = "skipped"
+	CallbackStatusCanceled  CallbackStatus = "canceled"
+)
+
+// CallbackPayload is the JSON body posted to the callback URL of a finished job
+// Details has the same shape as the result of the job's EngineCIJobWorkflow
+type CallbackPayload struct {
+	Event      string
+	JobID      string
+	WorkflowID string
+	Repository string
+	RepoName   string
+	GitRef     string
+	Status     CallbackStatus
+	FinishedAt time.Time
+	Details    EngineCIDetails
+}
+
+// NotifyCallbackInput is the input of the NotifyCallback activity
+// Secret is a `secret://NAME` reference, NotifyCallback resolves it on the worker
+type NotifyCallbackInput struct {
+	URL     string
+	Secret  string
+	Payload CallbackPayload
+}
+
+// SignCallback returns the signature header value of a callback body
+func SignCallback(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyCallbackSignature reports whether the signature header value matches the body, for receivers
+func VerifyCallbackSignature(secret string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(SignCallback(secret, body)), []byte(signature))
+}
+
+// validateCallbackURL accepts absolute http and https URLs
+// Hosts that are internal by name or address are refused unless they are in CallbackAllowedHosts,
+// names resolving to internal addresses are refused when NotifyCallback connects
+func validateCallbackURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return fmt.Errorf("invalid callback URL %q", raw)
+	}
+	if callbackHostAllowed(u.Hostname()) {
+		return nil
+	}
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return fmt.Errorf("callback URL %q points to an internal address", raw)
+	}
+	if addr, err := netip.ParseAddr(host); err == nil && internalAddr(addr) {
+		return fmt.Errorf("callback URL %q points to an internal address", raw)
+	}
+	return nil
+}
+
+// validateCallbackSecret accepts `secret://NAME` references, plain secrets would end up in the workflow history
+func validateCallbackSecret(secret string) error {
+	name, ok := SecretRef(secret)
+	if !ok {
+		return fmt.Errorf("callback secret must be a %sNAME reference", SecretRefPrefix)
+	}
+	if !secretNamePattern.MatchString(name) {
+		return fmt.Errorf("callback secret references invalid secret name %q", name)
+	}
+	return nil
+}
+
+func callbackHostAllowed(host string) bool {
+	return slices.ContainsFunc(CallbackAllowedHosts, func(allowed string) bool { return strings.EqualFold(allowed, host) })
+}
+
+// internalAddr reports whether addr is a loopback, link-local, private or otherwise non-public address
+func internalAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsLoopback() || addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() || addr.IsPrivate() ||
+		addr.IsUnspecified() || addr.IsMulticast() || addr.IsInterfaceLocalMulticast()
+}
+
+// callbackClient returns the HTTP client delivering to host
+// Unless the host is allowed, the dialer refuses internal addresses after name resolution so a public name
+// can't be pointed at the worker's network; redirects are not followed
+func callbackClient(host string) *http.Client {
+	dialer := &net.Dialer{Timeout: CallbackRequestTimeout}
+	if !callbackHostAllowed(host) {
+		dialer.Control = func(_, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+			if internalAddr(addrPort.Addr()) {
+				return fmt.Errorf("%w: %s is %s", errInternalCallbackAddr, host, addrPort.Addr())
+			}
+			return nil
+		}
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{
+		Transport:     transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+}
+
+// callbackSecret resolves the secret reference of a delivery, an empty reference leaves the callback unsigned
+func callbackSecret(ref string) (string, error) {
+	if ref == "" {
+		return "", nil
+	}
+	if err := validateCallbackSecret(ref); err != nil {
+		return "", temporal.NewNonRetryableApplicationError(err.Error(), callbackRejectedErrorType, err)
+	}
+	name, _ := SecretRef(ref)
+	secret, err := Secrets.Secret(name)
+	if errors.Is(err, ErrSecretNotFound) {
+		err = fmt.Errorf("unresolved callback secret %s: %w", name, err)
+		return "", temporal.NewNonRetryableApplicationError(err.Error(), secretNotFoundErrorType, err)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve callback secret %s: %w", name, err)
+	}
+	return secret, nil
+}
+
+// NotifyCallback posts the payload to the callback URL
+// A 2xx response acknowledges the delivery, other responses and network errors are retried by
+// Temporal; 3xx and 4xx responses other than 408 and 429 are final since repeating the request won't help
+func NotifyCallback(ctx context.Context, input NotifyCallbackInput) error {
+	logger := activity.GetLogger(ctx)
+
+	// The activity may be scheduled without a validated job
+	if err := validateCallbackURL(input.URL); err != nil {
+		return temporal.NewNonRetryableApplicationError(err.Error(), callbackRejectedErrorType, err)
+	}
+
+	secret, err := callbackSecret(input.Secret)
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(input.Payload)
+	if err != nil {
+		return temporal.NewNonRetryableApplicationError("failed to encode callback payload", callbackRejectedErrorType, err)
+	}
+
+	reqCtx, cancel := context.WithTimeout(ctx, CallbackRequestTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodPost, input.URL, bytes.NewReader(body))
+	if err != nil {
+		return temporal.NewNonRetryableApplicationError("invalid callback URL", callbackRejectedErrorType, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "temporal-worker-engine-ci")
+	req.Header.Set(CallbackEventHeader, input.Payload.Event)
+	req.Header.Set(CallbackDeliveryHeader, input.Payload.WorkflowID)
+	if secret != "" {
+		req.Header.Set(CallbackSignatureHeader, SignCallback(secret, body))
+	}
+
+	resp, err := callbackClient(req.URL.Hostname()).Do(req)
+	if errors.Is(err, errInternalCallbackAddr) {
+		return temporal.NewNonRetryableApplicationError("callback refused", callbackRejectedErrorType, err)
+	}
+	if err != nil {
+		return fmt.Errorf("callback request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+
+	switch code := resp.StatusCode; {
+	case code >= 200 && code < 300:
+		logger.Info("Callback delivered", "url", input.URL, "status", code)
+		return nil
+	case code >= 300 && code < 500 && code != http.StatusRequestTimeout && code != http.StatusTooManyRequests:
+		return temporal.NewNonRetryableApplicationError(
+			fmt.Sprintf("callback rejected with status %d: %s", code, strings.TrimSpace(string(msg))), callbackRejectedErrorType, nil)
+	default:
+		return fmt.Errorf("callback failed with status %d: %s", code, strings.TrimSpace(string(msg)))
+	}
+}
+
+// callbackStatus derives the reported status from the job result
+func callbackStatus(details EngineCIDetails, canceled bool) CallbackStatus {
+	switch {
+	case canceled || details.FailureClass == FailureClassCanceled:
+		return CallbackStatusCanceled
+	case details.Skipped:
+		return CallbackStatusSkipped
+	case details.ExitCode == 0 && details.FailureClass == FailureClassNone:
+		return CallbackStatusSucceeded
+	default:
+		return CallbackStatusFailed
+	}
+}
+
+// notifyCallback delivers the job result to the job's callback URL, canceled jobs are reported too
+// Delivery failures are logged, they don't change the job result
+func notifyCallback(ctx workflow.Context, job EngineCIWorkflowInput, details EngineCIDetails) {
+	canceled := ctx.Err() != nil
+	ctx, cancel := workflow.NewDisconnectedContext(ctx)
+	defer cancel()
+
+	ctx = workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout:    CallbackRequestTimeout + 5*time.Second,
+		ScheduleToCloseTimeout: recordedSettings(ctx).CallbackDeliveryTimeout,
+		RetryPolicy: &temporal.RetryPolicy{
+			InitialInterval:    5 * time.Second,
+			BackoffCoefficient: 2,
+			MaximumInterval:    5 * time.Minute,
+		},
+	})
+
+	info := workflow.GetInfo(ctx)
+	input := NotifyCallbackInput{
+		URL:    job.CallbackURL,
+		Secret: job.CallbackSecret,
+		Payload: CallbackPayload{
+			Event:      CallbackEventJobCompleted,
+			JobID:      job.JobID,
+			WorkflowID: info.WorkflowExecution.ID,
+			Repository: job.GitRepoURL,
+			RepoName:   job.RepoName,
+			GitRef:     job.GitRef,
+			Status:     callbackStatus(details, canceled),
+			FinishedAt: workflow.Now(ctx).UTC(),
+			Details:    details,
+		},
+	}
+	if err := workflow.ExecuteActivity(ctx, NotifyCallback, input).Get(ctx, nil); err != nil {
+		workflow.GetLogger(ctx).Warn("Failed to deliver callback (non-critical)", "repo", job.RepoName, "url", job.CallbackURL, "error", err)
+	}
+}
@@ -0,0 +1,311 @@
+package engineci
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/containifyci/temporal-worker/pkg/activities/filesystem"
+	"github.com/containifyci/temporal-worker/pkg/activities/git"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/testsuite"
+)
+
+// callbackReceiver is a local callback endpoint that answers with the given status codes in turn
+type callbackReceiver struct {
+	*httptest.Server
+	calls    atomic.Int32
+	payload  CallbackPayload
+	header   http.Header
+	verified bool
+}
+
+// newCallbackReceiver allows callbacks to the local receiver and serves secret as secret://CALLBACK_SECRET
+func newCallbackReceiver(t *testing.T, secret string, statuses ...int) *callbackReceiver {
+	t.Helper()
+	hosts, secrets := CallbackAllowedHosts, Secrets
+	t.Cleanup(func() { CallbackAllowedHosts, Secrets = hosts, secrets })
+	CallbackAllowedHosts = []string{"127.0.0.1"}
+	Secrets = staticSecrets{"CALLBACK_SECRET": secret}
+
+	r := &callbackReceiver{}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		n := int(r.calls.Add(1))
+		body, _ := io.ReadAll(req.Body)
+		r.header = req.Header.Clone()
+		r.verified = VerifyCallbackSignature(secret, body, req.Header.Get(CallbackSignatureHeader))
+		_ = json.Unmarshal(body, &r.payload)
+
+		status := http.StatusOK
+		if n <= len(statuses) {
+			status = statuses[n-1]
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(r.Close)
+	return r
+}
+
+func callbackInput(url, secret string) NotifyCallbackInput {
+	return NotifyCallbackInput{
+		URL:    url,
+		Secret: secret,
+		Payload: CallbackPayload{
+			Event:      CallbackEventJobCompleted,
+			JobID:      "github.com/test/repo/5f1c2a9b-1",
+			WorkflowID: "engine-ci-job-github.com/test/repo/5f1c2a9b-1",
+			Status:     CallbackStatusFailed,
+			Details:    EngineCIDetails{ExitCode: 2, FailureClass: FailureClassBuild},
+		},
+	}
+}
+
+func TestNotifyCallback_SignedPayload(t *testing.T) {
+	receiver := newCallbackReceiver(t, "s3cret")
+
+	env := (&testsuite.WorkflowTestSuite{}).NewTestActivityEnvironment()
+	env.RegisterActivity(NotifyCallback)
+
+	_, err := env.ExecuteActivity(NotifyCallback, callbackInput(receiver.URL, "secret://CALLBACK_SECRET"))
+	require.NoError(t, err)
+
+	assert.Equal(t, int32(1), receiver.calls.Load())
+	assert.True(t, receiver.verified, "signature must verify with the shared secret")
+	assert.Equal(t, CallbackEventJobCompleted, receiver.header.Get(CallbackEventHeader))
+	assert.Equal(t, "engine-ci-job-github.com/test/repo/5f1c2a9b-1", receiver.header.Get(CallbackDeliveryHeader))
+	assert.Equal(t, "application/json", receiver.header.Get("Content-Type"))
+	assert.Equal(t, CallbackStatusFailed, receiver.payload.Status)
+	assert.Equal(t, 2, receiver.payload.Details.ExitCode)
+}
+
+func TestNotifyCallback_Unsigned(t *testing.T) {
+	receiver := newCallbackReceiver(t, "")
+
+	env := (&testsuite.WorkflowTestSuite{}).NewTestActivityEnvironment()
+	env.RegisterActivity(NotifyCallback)
+
+	_, err := env.ExecuteActivity(NotifyCallback, callbackInput(receiver.URL, ""))
+	require.NoError(t, err)
+	assert.Empty(t, receiver.header.Get(CallbackSignatureHeader))
+}
+
+func TestNotifyCallback_Errors(t *testing.T) {
+	tests := []struct {
+		name         string
+		status       int
+		nonRetryable bool
+	}{
+		{name: "server error is retried", status: http.StatusBadGateway},
+		{name: "rate limit is retried", status: http.StatusTooManyRequests},
+		{name: "client error is final", status: http.StatusNotFound, nonRetryable: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			receiver := newCallbackReceiver(t, "", tt.status)
+
+			env := (&testsuite.WorkflowTestSuite{}).NewTestActivityEnvironment()
+			env.RegisterActivity(NotifyCallback)
+
+			_, err := env.ExecuteActivity(NotifyCallback, callbackInput(receiver.URL, ""))
+			require.Error(t, err)
+
+			var appErr *temporal.ApplicationError
+			require.True(t, errors.As(err, &appErr))
+			assert.Equal(t, tt.nonRetryable, appErr.NonRetryable())
+		})
+	}
+}
+
+func TestNotifyCallback_Refused(t *testing.T) {
+	tests := []struct {
+		name  string
+		input func(receiver *callbackReceiver) NotifyCallbackInput
+		err   string
+	}{
+		{
+			name: "internal address",
+			input: func(receiver *callbackReceiver) NotifyCallbackInput {
+				CallbackAllowedHosts = nil
+				return callbackInput(receiver.URL, "")
+			},
+			err: "points to an internal address",
+		},
+		{
+			name: "unsupported scheme",
+			input: func(receiver *callbackReceiver) NotifyCallbackInput {
+				return callbackInput("file:///etc/passwd", "")
+			},
+			err: `invalid callback URL "file:///etc/passwd"`,
+		},
+		{
+			name: "plain secret",
+			input: func(receiver *callbackReceiver) NotifyCallbackInput {
+				return callbackInput(receiver.URL, "s3cret")
+			},
+			err: "callback secret must be a secret://NAME reference",
+		},
+		{
+			name: "missing secret",
+			input: func(receiver *callbackReceiver) NotifyCallbackInput {
+				return callbackInput(receiver.URL, "secret://OTHER")
+			},
+			err: "unresolved callback secret OTHER: secret not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			receiver := newCallbackReceiver(t, "s3cret")
+
+			env := (&testsuite.WorkflowTestSuite{}).NewTestActivityEnvironment()
+			env.RegisterActivity(NotifyCallback)
+
+			_, err := env.ExecuteActivity(NotifyCallback, tt.input(receiver))
+			require.Error(t, err)
+			assert.ErrorContains(t, err, tt.err)
+			var appErr *temporal.ApplicationError
+			require.True(t, errors.As(err, &appErr))
+			assert.True(t, appErr.NonRetryable())
+			assert.Equal(t, int32(0), receiver.calls.Load())
+		})
+	}
+}
+
+func TestValidateCallbackURL(t *testing.T) {
+	defer func(orig []string) { CallbackAllowedHosts = orig }(CallbackAllowedHosts)
+	CallbackAllowedHosts = []string{"hooks.internal"}
+
+	assert.NoError(t, validateCallbackURL("https://ci.example.com/hook"))
+	assert.NoError(t, validateCallbackURL("http://hooks.internal:8080/hook"))
+	assert.NoError(t, validateCallbackURL("https://8.8.8.8/hook"))
+	assert.EqualError(t, validateCallbackURL("ftp://ci.example.com/hook"), `invalid callback URL "ftp://ci.example.com/hook"`)
+	for _, raw := range []string{
+		"http://localhost:8080/hook",
+		"http://127.0.0.1/hook",
+		"http://[::1]/hook",
+		"http://169.254.169.254/latest/meta-data",
+		"http://10.0.0.5/hook",
+		"http://192.168.1.10/hook",
+		"http://[fd00::1]/hook",
+		"http://[::ffff:127.0.0.1]/hook",
+		"http://0.0.0.0/hook",
+	} {
+		assert.EqualError(t, validateCallbackURL(raw), `callback URL "`+raw+`" points to an internal address`, raw)
+	}
+}
+
+func TestVerifyCallbackSignature(t *testing.T) {
+	body := []byte(`{"Status":"succeeded"}`)
+	signature := SignCallback("s3cret", body)
+
+	assert.True(t, VerifyCallbackSignature("s3cret", body, signature))
+	assert.False(t, VerifyCallbackSignature("other", body, signature))
+	assert.False(t, VerifyCallbackSignature("s3cret", []byte(`{"Status":"failed"}`), signature))
+	assert.False(t, VerifyCallbackSignature("s3cret", body, ""))
+}
+
+func TestCallbackStatus(t *testing.T) {
+	assert.Equal(t, CallbackStatusSucceeded, callbackStatus(EngineCIDetails{}, false))
+	assert.Equal(t, CallbackStatusSkipped, callbackStatus(EngineCIDetails{Skipped: true}, false))
+	assert.Equal(t, CallbackStatusFailed, callbackStatus(EngineCIDetails{ExitCode: 1, FailureClass: FailureClassBuild}, false))
+	assert.Equal(t, CallbackStatusFailed, callbackStatus(EngineCIDetails{ExitCode: -1, FailureClass: FailureClassInfrastructure}, false))
+	assert.Equal(t, CallbackStatusCanceled, callbackStatus(EngineCIDetails{}, true))
+}
+
+func (s *WorkflowTestSuite) TestEngineCIJobWorkflow_RetriesCallbackUntilAcknowledged() {
+	receiver := newCallbackReceiver(s.T(), "s3cret", http.StatusServiceUnavailable, http.StatusServiceUnavailable)
+
+	env := s.NewTestWorkflowEnvironment()
+	env.RegisterActivity(NotifyCallback)
+	env.OnActivity(RunEngineCIJob, mock.Anything, mock.Anything).Return(&EngineCIDetails{ExitCode: 0}, nil)
+	s.mockWorkspaceActivities(env)
+
+	env.ExecuteWorkflow(EngineCIJobWorkflow, EngineCIWorkflowInput{
+		JobID:          "delivery-1",
+		GitRepoURL:     "https://github.com/test/repo",
+		GitRef:         "main",
+		EngineArgs:     []string{"run"},
+		Force:          true,
+		CallbackURL:    receiver.URL,
+		CallbackSecret: "secret://CALLBACK_SECRET",
+	})
+
+	s.True(env.IsWorkflowCompleted())
+	s.NoError(env.GetWorkflowError())
+	s.Equal(int32(3), receiver.calls.Load())
+	s.True(receiver.verified)
+	s.Equal(CallbackStatusSucceeded, receiver.payload.Status)
+	s.Equal("delivery-1", receiver.payload.JobID)
+	s.Equal("https://github.com/test/repo", receiver.payload.Repository)
+}
+
+func (s *WorkflowTestSuite) TestEngineCIJobWorkflow_CallbackFailureKeepsResult() {
+	env := s.NewTestWorkflowEnvironment()
+	env.OnActivity(RunEngineCIJob, mock.Anything, mock.Anything).Return(&EngineCIDetails{ExitCode: 1}, nil)
+	env.OnActivity(NotifyCallback, mock.Anything, mock.MatchedBy(func(input NotifyCallbackInput) bool {
+		return input.Payload.Status == CallbackStatusFailed && input.URL == "https://ci.example.com/hook"
+	})).Return(temporal.NewNonRetryableApplicationError("gone", callbackRejectedErrorType, nil)).Once()
+	s.mockWorkspaceActivities(env)
+
+	env.ExecuteWorkflow(EngineCIJobWorkflow, EngineCIWorkflowInput{
+		GitRepoURL:  "https://github.com/test/repo",
+		GitRef:      "main",
+		EngineArgs:  []string{"run"},
+		Force:       true,
+		CallbackURL: "https://ci.example.com/hook",
+	})
+
+	s.True(env.IsWorkflowCompleted())
+	s.NoError(env.GetWorkflowError())
+	var details EngineCIDetails
+	s.NoError(env.GetWorkflowResult(&details))
+	s.Equal(1, details.ExitCode)
+	env.AssertExpectations(s.T())
+}
+
+func (s *WorkflowTestSuite) TestEngineCIJobWorkflow_CallbackOnCancel() {
+	env := s.NewTestWorkflowEnvironment()
+	env.OnActivity(RunEngineCIJob, mock.Anything, mock.Anything).
+		Return(func(ctx context.Context, _ RunEngineCIInput) (*EngineCIDetails, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		})
+	var status CallbackStatus
+	env.OnActivity(NotifyCallback, mock.Anything, mock.Anything).
+		Return(func(_ context.Context, input NotifyCallbackInput) error {
+			status = input.Payload.Status
+			return nil
+		})
+	s.mockWorkspaceActivities(env)
+
+	env.RegisterDelayedCallback(env.CancelWorkflow, time.Second)
+	env.ExecuteWorkflow(EngineCIJobWorkflow, EngineCIWorkflowInput{
+		GitRepoURL:  "https://github.com/test/repo",
+		GitRef:      "main",
+		EngineArgs:  []string{"run"},
+		Force:       true,
+		CallbackURL: "https://ci.example.com/hook",
+	})
+
+	s.True(env.IsWorkflowCompleted())
+	s.True(temporal.IsCanceledError(env.GetWorkflowError()))
+	s.Equal(CallbackStatusCanceled, status)
+}
+
+// mockWorkspaceActivities mocks cloning and cleanup of the test repository
+func (s *WorkflowTestSuite) mockWorkspaceActivities(env *testsuite.TestWorkflowEnvironment) {
+	env.OnActivity(git.CloneRepo, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return("/tmp/ci/github.com/test/repo", nil).Maybe()
+	env.OnActivity(filesystem.CleanupDirectory, mock.Anything, mock.Anything).Return(nil).Maybe()
+}
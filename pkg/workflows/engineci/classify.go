@@ -0,0 +1,79 @@
+package engineci
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	"go.temporal.io/sdk/temporal"
+)
+
+// transientOutputPatterns match engine-ci output that points at the runner rather than the code
+var transientOutputPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)cannot connect to the docker daemon`),
+	regexp.MustCompile(`(?i)error during connect`),
+	regexp.MustCompile(`(?i)docker daemon is not running`),
+	regexp.MustCompile(`(?i)tls handshake timeout`),
+	regexp.MustCompile(`(?i)no such host`),
+	regexp.MustCompile(`(?i)temporary failure in name resolution`),
+	regexp.MustCompile(`(?i)toomanyrequests`),
+	regexp.MustCompile(`(?i)no space left on device`),
+}
+
+// runtimeNetworkPatterns match network errors that tests print as well, they only count on a line of the
+// container runtime or the registry, e.g. dial unix /var/run/docker.sock: connect: connection refused
+var runtimeNetworkPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)connection reset by peer`),
+	regexp.MustCompile(`(?i)connection refused`),
+	regexp.MustCompile(`(?i)i/o timeout`),
+	regexp.MustCompile(`(?i)unexpected eof`),
+}
+
+// runtimeLine matches the output lines of the container runtime and image pulls
+var runtimeLine = regexp.MustCompile(`(?i)docker|podman|daemon|containerd|registry|\.sock\b|pull(ing)? (image|manifest)|/v2/`)
+
+// ClassifyOutput classifies a finished engine-ci run by its exit code and output
+// A zero exit code is never a failure, known transient patterns are infrastructure failures, generic network
+// errors only when the container runtime reports them, and everything else is attributed to the build itself
+func ClassifyOutput(exitCode int, output string) FailureClass {
+	if exitCode == 0 {
+		return FailureClassNone
+	}
+	for _, pattern := range transientOutputPatterns {
+		if pattern.MatchString(output) {
+			return FailureClassInfrastructure
+		}
+	}
+	for _, line := range strings.Split(output, "\n") {
+		if !runtimeLine.MatchString(line) {
+			continue
+		}
+		for _, pattern := range runtimeNetworkPatterns {
+			if pattern.MatchString(line) {
+				return FailureClassInfrastructure
+			}
+		}
+	}
+	return FailureClassBuild
+}
+
+// ClassifyError classifies an activity or child workflow error returned to the workflow
+// Rejected jobs and runners, unresolved secrets, timeouts and cancellations are reported separately, every other error (clone, network,
+// missing binary) is an infrastructure failure
+func ClassifyError(err error) FailureClass {
+	if err == nil {
+		return FailureClassNone
+	}
+	var appErr *temporal.ApplicationError
+	if errors.As(err, &appErr) && (appErr.Type() == invalidRunnerErrorType || appErr.Type() == secretNotFoundErrorType ||
+		appErr.Type() == invalidJobErrorType) {
+		return FailureClassInvalid
+	}
+	if temporal.IsCanceledError(err) {
+		return FailureClassCanceled
+	}
+	if temporal.IsTimeoutError(err) {
+		return FailureClassTimeout
+	}
+	return FailureClassInfrastructure
+}
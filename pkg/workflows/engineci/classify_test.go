@@ -0,0 +1,125 @@
+package engineci
+
+import (
+	"errors"
+	"testing"
+
+	enumspb "go.temporal.io/api/enums/v1"
+	"go.temporal.io/sdk/temporal"
+)
+
+func TestClassifyOutput(t *testing.T) {
+	tests := []struct {
+		name     string
+		exitCode int
+		output   string
+		expected FailureClass
+	}{
+		{
+			name:     "Successful run",
+			exitCode: 0,
+			output:   "connection refused",
+			expected: FailureClassNone,
+		},
+		{
+			name:     "Failing test",
+			exitCode: 1,
+			output:   "--- FAIL: TestSomething (0.00s)",
+			expected: FailureClassBuild,
+		},
+		{
+			name:     "Docker daemon down",
+			exitCode: 1,
+			output:   "Cannot connect to the Docker daemon at unix:///var/run/docker.sock. Is the docker daemon running?",
+			expected: FailureClassInfrastructure,
+		},
+		{
+			name:     "Registry rate limit",
+			exitCode: 1,
+			output:   "toomanyrequests: You have reached your pull rate limit",
+			expected: FailureClassInfrastructure,
+		},
+		{
+			name:     "Failing test dialing a stopped server",
+			exitCode: 1,
+			output:   "--- FAIL: TestClient (0.01s)\n    client_test.go:42: dial tcp 127.0.0.1:8080: connect: connection refused\nFAIL",
+			expected: FailureClassBuild,
+		},
+		{
+			name:     "Failing test reading a truncated body",
+			exitCode: 1,
+			output:   "--- FAIL: TestDecode (0.00s)\n    decode_test.go:17: unexpected EOF\n    read_test.go:9: read tcp: i/o timeout",
+			expected: FailureClassBuild,
+		},
+		{
+			name:     "Docker socket refused",
+			exitCode: 1,
+			output:   "running tests\nerror: dial unix /var/run/docker.sock: connect: connection refused",
+			expected: FailureClassInfrastructure,
+		},
+		{
+			name:     "Image pull interrupted",
+			exitCode: 1,
+			output:   "Error pulling image golang:1.24: unexpected EOF",
+			expected: FailureClassInfrastructure,
+		},
+		{
+			name:     "DNS failure",
+			exitCode: 2,
+			output:   "dial tcp: lookup proxy.golang.org: no such host",
+			expected: FailureClassInfrastructure,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := ClassifyOutput(tt.exitCode, tt.output)
+			if result != tt.expected {
+				t.Errorf("ClassifyOutput(%d, %q) = %q, want %q", tt.exitCode, tt.output, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected FailureClass
+	}{
+		{
+			name:     "No error",
+			err:      nil,
+			expected: FailureClassNone,
+		},
+		{
+			name:     "Clone failure",
+			err:      errors.New("git clone failed: exit status 128"),
+			expected: FailureClassInfrastructure,
+		},
+		{
+			name:     "Activity timeout",
+			err:      temporal.NewTimeoutError(enumspb.TIMEOUT_TYPE_START_TO_CLOSE, nil),
+			expected: FailureClassTimeout,
+		},
+		{
+			name:     "Canceled job",
+			err:      temporal.NewCanceledError(),
+			expected: FailureClassCanceled,
+		},
+		{
+			name:     "Unresolved secret",
+			err:      temporal.NewNonRetryableApplicationError("unresolved secrets: NPM_TOKEN", secretNotFoundErrorType, nil),
+			expected: FailureClassInvalid,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := ClassifyError(tt.err)
+			if result != tt.expected {
+				t.Errorf("ClassifyError(%v) = %q, want %q", tt.err, result, tt.expected)
+			}
+		})
+	}
+}
@@ -5,5 +5,28 @@ import "time"
 // Signal names
 const EngineCISignal = "engine-ci-signal"
 
+// Update names
+const EngineCISubmitUpdate = "engine-ci-submit"
+
+// Query names
+const EngineCIResultsQuery = "engine-ci-results"
+
+// PipelineStatusQuery returns the PipelineResult of a running or finished pipeline
+const PipelineStatusQuery = "engine-ci-pipeline-status"
+
 // Timeout constants
 var IdleTimeout = 1 * time.Minute
+
+// LegacyDrainTimeout replaces IdleTimeout for queues still running under a name-only workflow ID
+var LegacyDrainTimeout = 5 * time.Second
+
+// Infrastructure retry settings
+var (
+	// MaxInfraRetries is how often a job is retried after an infrastructure failure
+	MaxInfraRetries = 2
+	// InfraRetryBackoff is the delay before the first infrastructure retry, doubled on every further retry
+	InfraRetryBackoff = 1 * time.Minute
+)
+
+// MaxJobResults bounds the number of job results kept for the results query
+const MaxJobResults = 50
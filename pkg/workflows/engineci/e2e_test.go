@@ -3,6 +3,7 @@ package engineci
 import (
 	"context"
 	"os/exec"
+	"sync"
 	"testing"
 	"time"
 
@@ -10,6 +11,7 @@ import (
 	"github.com/containifyci/temporal-worker/pkg/activities/git"
 
 	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/activity"
 	"go.temporal.io/sdk/client"
 	"go.temporal.io/sdk/testsuite"
 	"go.temporal.io/sdk/worker"
@@ -69,8 +71,16 @@ func TestEngineCIWorkflow_E2E_SingleJob(t *testing.T) {
 
 	// Register workflows and activities
 	w.RegisterWorkflow(EngineCIRepoWorkflow)
+	w.RegisterWorkflow(EngineCIJobWorkflow)
+	w.RegisterWorkflow(EngineCIPipelineWorkflow)
 	w.RegisterActivity(git.CloneRepo)
-	w.RegisterActivity(RunEngineCI)
+	w.RegisterActivity(git.ChangedFiles)
+	w.RegisterActivity(git.ResolveRef)
+	w.RegisterActivity(RunEngineCIJob)
+	w.RegisterActivity(LookupJobResult)
+	w.RegisterActivity(StoreJobResult)
+	w.RegisterActivity(CollectArtifacts)
+	w.RegisterActivity(NotifyCallback)
 	w.RegisterActivity(filesystem.CleanupDirectory)
 
 	// Prepare test input - use this repo for testing
@@ -169,8 +179,16 @@ func TestEngineCIWorkflow_E2E_MultipleJobs(t *testing.T) {
 
 	// Register workflows and activities
 	w.RegisterWorkflow(EngineCIRepoWorkflow)
+	w.RegisterWorkflow(EngineCIJobWorkflow)
+	w.RegisterWorkflow(EngineCIPipelineWorkflow)
 	w.RegisterActivity(git.CloneRepo)
-	w.RegisterActivity(RunEngineCI)
+	w.RegisterActivity(git.ChangedFiles)
+	w.RegisterActivity(git.ResolveRef)
+	w.RegisterActivity(RunEngineCIJob)
+	w.RegisterActivity(LookupJobResult)
+	w.RegisterActivity(StoreJobResult)
+	w.RegisterActivity(CollectArtifacts)
+	w.RegisterActivity(NotifyCallback)
 	w.RegisterActivity(filesystem.CleanupDirectory)
 
 	// Prepare first job
@@ -229,3 +247,79 @@ func TestEngineCIWorkflow_E2E_MultipleJobs(t *testing.T) {
 	err = server.Stop()
 	require.NoError(t, err)
 }
+
+// startLabelWorkers starts the label queue workers of a host with the given labels
+// RunEngineCIJob is wrapped to record the host that executed the job
+func startLabelWorkers(t *testing.T, c client.Client, host string, labels []string, ranOn *sync.Map) {
+	t.Helper()
+	for _, queue := range WorkerTaskQueues(labels, LabelSets) {
+		lw := worker.New(c, queue, worker.Options{DisableWorkflowWorker: true})
+		lw.RegisterActivity(git.CloneRepo)
+		lw.RegisterActivity(git.ChangedFiles)
+		lw.RegisterActivity(git.ResolveRef)
+		lw.RegisterActivityWithOptions(func(ctx context.Context, input RunEngineCIInput) (*EngineCIDetails, error) {
+			ranOn.Store(host, true)
+			return RunEngineCIJob(ctx, input)
+		}, activity.RegisterOptions{Name: "RunEngineCIJob"})
+		lw.RegisterActivity(LookupJobResult)
+		lw.RegisterActivity(StoreJobResult)
+		lw.RegisterActivity(CollectArtifacts)
+		lw.RegisterActivity(filesystem.CleanupDirectory)
+		require.NoError(t, lw.Start())
+		t.Cleanup(lw.Stop)
+	}
+}
+
+func TestEngineCIWorkflow_E2E_LabelRouting(t *testing.T) {
+	// Skip if engine-ci or git not available
+	if _, err := exec.LookPath("engine-ci"); err != nil {
+		t.Skip("engine-ci not found in PATH, skipping e2e test")
+	}
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found in PATH, skipping e2e test")
+	}
+
+	server, err := testsuite.StartDevServer(context.Background(), testsuite.DevServerOptions{
+		ClientOptions: &client.Options{HostPort: ""},
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = server.Stop() })
+	c := server.Client()
+
+	// The workflows run on the shared queue, two hosts with different labels poll the label queues
+	w := worker.New(c, TaskQueue, worker.Options{})
+	w.RegisterWorkflow(EngineCIJobWorkflow)
+	w.RegisterActivity(NotifyCallback)
+	require.NoError(t, w.Start())
+	t.Cleanup(w.Stop)
+
+	var ranOn sync.Map
+	defer func(orig []string) { LabelSets = orig }(LabelSets)
+	LabelSets = []string{"docker+mem-large"}
+	startLabelWorkers(t, c, "amd64-host", []string{"amd64", "docker"}, &ranOn)
+	startLabelWorkers(t, c, "arm64-host", []string{"arm64", "docker", "mem-large"}, &ranOn)
+
+	run, err := c.ExecuteWorkflow(context.Background(), client.StartWorkflowOptions{
+		ID:        JobWorkflowID("e2e-label-routing"),
+		TaskQueue: TaskQueue,
+	}, EngineCIJobWorkflow, EngineCIWorkflowInput{
+		JobID:      "e2e-label-routing",
+		GitRepoURL: "https://github.com/containifyci/go-self-update",
+		GitRef:     "main",
+		EngineArgs: []string{"version"},
+		Force:      true,
+		Labels:     []string{"mem-large", "docker"},
+	})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	var details EngineCIDetails
+	require.NoError(t, run.Get(ctx, &details))
+	require.Equal(t, 0, details.ExitCode)
+
+	_, onArm := ranOn.Load("arm64-host")
+	_, onAmd := ranOn.Load("amd64-host")
+	require.True(t, onArm, "job requiring mem-large must run on the arm64 host")
+	require.False(t, onAmd, "the amd64 host lacks mem-large")
+}
@@ -0,0 +1,115 @@
+package engineci
+
+import (
+	"net/url"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// RepoWorkflowIDPrefix prefixes the workflow ID of every EngineCIRepoWorkflow
+const RepoWorkflowIDPrefix = "engine-ci/"
+
+// legacyRepoWorkflowIDPrefix prefixed the name-only workflow IDs used before repositories were
+// identified by host and owner
+const legacyRepoWorkflowIDPrefix = "engine-ci-"
+
+// legacyQueueDrainChange versions the early exit of queues running under a legacy workflow ID
+const legacyQueueDrainChange = "legacy-queue-drain"
+
+// WorkspaceRoot is the directory repositories are cloned into, one directory per host/owner/repo
+var WorkspaceRoot = filepath.Join("/tmp", "ci")
+
+// localRepoHost is used as host for repositories given as a local path or file:// URL
+const localRepoHost = "local"
+
+var identitySegmentSanitizer = regexp.MustCompile(`[^a-z0-9._-]+`)
+
+// scpLikeURL matches the scp-like syntax used by ssh remotes, e.g. git@github.com:owner/repo.git
+var scpLikeURL = regexp.MustCompile(`^(?:[^@/]+@)?([^:/]+):(.+)$`)
+
+// RepoIdentity identifies a repository by host, owner and name
+// Owner may contain several path segments (e.g. GitLab subgroups)
+type RepoIdentity struct {
+	Host  string
+	Owner string
+	Name  string
+}
+
+// ParseRepoIdentity derives the identity of a repository from its URL
+// Example: git@github.com:containifyci/temporal-worker.git -> github.com/containifyci/temporal-worker
+func ParseRepoIdentity(repoURL string) RepoIdentity {
+	repoURL = strings.TrimSpace(repoURL)
+
+	var host, repoPath string
+	if u, err := url.Parse(repoURL); err == nil && u.Scheme != "" && u.Scheme != "file" && u.Host != "" {
+		host, repoPath = u.Hostname(), u.Path
+	} else if m := scpLikeURL.FindStringSubmatch(repoURL); m != nil && !strings.Contains(repoURL, "://") {
+		host, repoPath = m[1], m[2]
+	} else {
+		host, repoPath = localRepoHost, strings.TrimPrefix(repoURL, "file://")
+	}
+
+	var segments []string
+	for _, segment := range strings.Split(path.Clean("/"+filepath.ToSlash(repoPath)), "/") {
+		if segment = sanitizeIdentitySegment(segment); segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+
+	identity := RepoIdentity{Host: sanitizeIdentitySegment(host)}
+	if len(segments) > 0 {
+		identity.Name = strings.TrimSuffix(segments[len(segments)-1], ".git")
+		identity.Owner = strings.Join(segments[:len(segments)-1], "/")
+	}
+	return identity
+}
+
+func sanitizeIdentitySegment(segment string) string {
+	segment = strings.ToLower(segment)
+	segment = identitySegmentSanitizer.ReplaceAllString(segment, "-")
+	return strings.Trim(segment, "-.")
+}
+
+// String returns host/owner/name, unique per repository
+func (r RepoIdentity) String() string {
+	return path.Join(r.Host, r.Owner, r.Name)
+}
+
+// DisplayName returns owner/name, the name repositories are usually referred to by
+func (r RepoIdentity) DisplayName() string {
+	return path.Join(r.Owner, r.Name)
+}
+
+// CacheKey returns the default managed cache key of the repository
+func (r RepoIdentity) CacheKey() string {
+	return sanitizeCacheName(strings.ReplaceAll(r.String(), "/", "_"))
+}
+
+// Workspace returns the clone directory of the repository below WorkspaceRoot
+// Example: /tmp/ci/github.com/containifyci/temporal-worker
+func (r RepoIdentity) Workspace() string {
+	return filepath.Join(WorkspaceRoot, filepath.FromSlash(r.String()))
+}
+
+// RepoWorkflowID returns the workflow ID of the EngineCIRepoWorkflow of a repository
+// Example: engine-ci/github.com/containifyci/temporal-worker
+func RepoWorkflowID(repoURL string) string {
+	return RepoWorkflowIDPrefix + ParseRepoIdentity(repoURL).String()
+}
+
+// LegacyRepoWorkflowID returns the name-only workflow ID used by earlier releases
+// Repositories with the same name in different organisations share this ID
+func LegacyRepoWorkflowID(repoURL string) string {
+	return legacyRepoWorkflowIDPrefix + SanitizeRepoName(repoURL)
+}
+
+// legacyRepoWorkflowIDPattern matches the exact name-only IDs, engine-ci-<name> where the name is a
+// SanitizeRepoName result without slashes, so pipeline (engine-ci-pipeline/...) and job IDs never match
+var legacyRepoWorkflowIDPattern = regexp.MustCompile(`^` + legacyRepoWorkflowIDPrefix + `[^/]+$`)
+
+// IsLegacyRepoWorkflowID reports whether a workflow ID uses the name-only scheme of earlier releases
+func IsLegacyRepoWorkflowID(workflowID string) bool {
+	return legacyRepoWorkflowIDPattern.MatchString(workflowID) && !strings.HasPrefix(workflowID, jobWorkflowIDPrefix)
+}
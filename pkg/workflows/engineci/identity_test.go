@@ -0,0 +1,91 @@
+package engineci
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRepoIdentity(t *testing.T) {
+	tests := []struct {
+		name     string
+		repoURL  string
+		expected RepoIdentity
+	}{
+		{
+			name:     "HTTPS URL",
+			repoURL:  "https://github.com/containifyci/temporal-worker",
+			expected: RepoIdentity{Host: "github.com", Owner: "containifyci", Name: "temporal-worker"},
+		},
+		{
+			name:     "HTTPS URL with .git suffix and trailing slash",
+			repoURL:  "https://github.com/containifyci/temporal-worker.git/",
+			expected: RepoIdentity{Host: "github.com", Owner: "containifyci", Name: "temporal-worker"},
+		},
+		{
+			name:     "HTTPS URL with credentials and port",
+			repoURL:  "https://token@git.example.com:8443/Team/API.git",
+			expected: RepoIdentity{Host: "git.example.com", Owner: "team", Name: "api"},
+		},
+		{
+			name:     "SCP-like SSH URL",
+			repoURL:  "git@github.com:containifyci/temporal-worker.git",
+			expected: RepoIdentity{Host: "github.com", Owner: "containifyci", Name: "temporal-worker"},
+		},
+		{
+			name:     "SSH URL",
+			repoURL:  "ssh://git@github.com/containifyci/temporal-worker.git",
+			expected: RepoIdentity{Host: "github.com", Owner: "containifyci", Name: "temporal-worker"},
+		},
+		{
+			name:     "Nested groups",
+			repoURL:  "https://gitlab.com/group/sub group/project",
+			expected: RepoIdentity{Host: "gitlab.com", Owner: "group/sub-group", Name: "project"},
+		},
+		{
+			name:     "Local path",
+			repoURL:  "/srv/git/repo",
+			expected: RepoIdentity{Host: "local", Owner: "srv/git", Name: "repo"},
+		},
+		{
+			name:     "File URL",
+			repoURL:  "file:///srv/git/repo.git",
+			expected: RepoIdentity{Host: "local", Owner: "srv/git", Name: "repo"},
+		},
+		{
+			name:     "Path traversal",
+			repoURL:  "https://github.com/../../etc/passwd",
+			expected: RepoIdentity{Host: "github.com", Owner: "etc", Name: "passwd"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, ParseRepoIdentity(tt.repoURL))
+		})
+	}
+}
+
+func TestRepoIdentityNames(t *testing.T) {
+	a := ParseRepoIdentity("https://github.com/a/api")
+	b := ParseRepoIdentity("https://github.com/b/api")
+
+	assert.Equal(t, "github.com/a/api", a.String())
+	assert.Equal(t, "a/api", a.DisplayName())
+	assert.Equal(t, "github-com_a_api", a.CacheKey())
+	assert.Equal(t, "/tmp/ci/github.com/a/api", a.Workspace())
+
+	assert.NotEqual(t, a.Workspace(), b.Workspace())
+	assert.NotEqual(t, a.CacheKey(), b.CacheKey())
+	assert.NotEqual(t, RepoWorkflowID("https://github.com/a/api"), RepoWorkflowID("https://github.com/b/api"))
+	assert.Equal(t, "engine-ci/github.com/a/api", RepoWorkflowID("git@github.com:a/api.git"))
+}
+
+func TestIsLegacyRepoWorkflowID(t *testing.T) {
+	assert.True(t, IsLegacyRepoWorkflowID(LegacyRepoWorkflowID("https://github.com/a/api")))
+	assert.False(t, IsLegacyRepoWorkflowID(RepoWorkflowID("https://github.com/a/api")))
+	assert.False(t, IsLegacyRepoWorkflowID(JobWorkflowID("api-5f1c2a9b-1")))
+	assert.False(t, IsLegacyRepoWorkflowID("default-test-workflow-id"))
+	assert.False(t, IsLegacyRepoWorkflowID(PipelineWorkflowID("https://github.com/a/api", "1718000000")))
+	assert.False(t, IsLegacyRepoWorkflowID("engine-ci-"))
+}
@@ -0,0 +1,277 @@
+package engineci
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/containifyci/temporal-worker/pkg/activities/filesystem"
+	"github.com/containifyci/temporal-worker/pkg/activities/git"
+	"github.com/containifyci/temporal-worker/pkg/logging"
+
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+)
+
+// EngineCIJobWorkflow runs a single Engine-CI job
+// It is started by EngineCIRepoWorkflow as a child with the ID JobWorkflowID(job.JobID), which makes
+// every job its own execution to link to, search for, retry or cancel
+// Failed builds are reported in the returned details, not as workflow errors
+func EngineCIJobWorkflow(ctx workflow.Context, job EngineCIWorkflowInput) (EngineCIDetails, error) {
+	ctx = logging.WithRepository(ctx, job.RepoName)
+	logger := workflow.GetLogger(ctx)
+	logger.Info("Started Engine-CI job workflow", "repo", job.RepoName, "ref", job.GitRef, "jobID", job.JobID)
+
+	// Jobs may be started directly or by a signal, not only through the validated submission
+	if workflow.GetVersion(ctx, jobValidationChange, workflow.DefaultVersion, 1) != workflow.DefaultVersion {
+		if err := validateJobOnce(ctx, job); err != nil {
+			return EngineCIDetails{}, temporal.NewNonRetryableApplicationError(err.Error(), invalidJobErrorType, err)
+		}
+	}
+
+	details := processJob(ctx, job)
+	if job.CallbackURL != "" {
+		notifyCallback(ctx, job, details)
+	}
+
+	// Surface cancellation so the queue can tell it apart from a finished job
+	if err := ctx.Err(); err != nil {
+		return details, temporal.NewCanceledError(job.JobID)
+	}
+	return details, nil
+}
+
+// processJob runs a single Engine-CI job and retries it when the failure is caused by the infrastructure
+func processJob(ctx workflow.Context, job EngineCIWorkflowInput) EngineCIDetails {
+	logger := workflow.GetLogger(ctx)
+	settings := recordedSettings(ctx)
+
+	// Per-job activity options with longer timeout
+	jobOptions := workflow.ActivityOptions{
+		RetryPolicy: &temporal.RetryPolicy{
+			InitialInterval:    30 * time.Second,
+			BackoffCoefficient: 1.5,
+			MaximumInterval:    10 * time.Minute,
+			MaximumAttempts:    3,
+		},
+		StartToCloseTimeout: 15 * time.Minute,
+	}
+	// All activities of a job share the workspace, so they run on a worker advertising the job's labels
+	if len(job.Labels) > 0 {
+		jobOptions.TaskQueue = LabelTaskQueue(job.Labels)
+		jobOptions.ScheduleToStartTimeout = settings.LabelScheduleToStartTimeout
+		logger.Info("Routing Engine-CI job to labelled workers", "repo", job.RepoName, "taskQueue", jobOptions.TaskQueue)
+	}
+	jobCtx := workflow.WithActivityOptions(ctx, jobOptions)
+
+	// Reuse an earlier successful result for the same commit and arguments
+	if !job.Force {
+		if cached := lookupCachedResult(jobCtx, job); cached != nil {
+			logger.Info("Reusing cached Engine-CI result", "repo", job.RepoName, "commit", cached.CommitSHA)
+			cached.Reused = true
+			return *cached
+		}
+	}
+
+	backoff := settings.InfraRetryBackoff
+	var details EngineCIDetails
+	for attempt := 1; ; attempt++ {
+		details = runJobAttempt(jobCtx, job)
+		details.Attempts = attempt
+
+		if details.FailureClass != FailureClassInfrastructure || attempt > settings.MaxInfraRetries {
+			storeResult(jobCtx, job, details)
+			return details
+		}
+
+		logger.Warn("Engine-CI infrastructure failure, retrying job",
+			"repo", job.RepoName,
+			"attempt", attempt,
+			"backoff", backoff)
+		if err := workflow.Sleep(ctx, backoff); err != nil {
+			return details
+		}
+		backoff *= 2
+	}
+}
+
+// runJobAttempt clones the repository, runs engine-ci, uploads artifacts and cleans up on success
+func runJobAttempt(ctx workflow.Context, job EngineCIWorkflowInput) EngineCIDetails {
+	logger := workflow.GetLogger(ctx)
+
+	// Step 1: Clone repository
+	var workDir string
+	targetDir, cacheKey := jobWorkspace(ctx, job)
+	err := workflow.ExecuteActivity(ctx, git.CloneRepo, job.GitRepoURL, job.GitRef, targetDir).Get(ctx, &workDir)
+	if err != nil {
+		logger.Error("Git clone failed", "repo", job.RepoName, "error", err)
+		return failedDetails(err)
+	}
+
+	// Step 2: Skip the run when no relevant path changed
+	if skip, reason := shouldSkip(ctx, job, workDir); skip {
+		logger.Info("Engine-CI job skipped", "repo", job.RepoName, "reason", reason)
+		err = workflow.ExecuteActivity(ctx, filesystem.CleanupDirectory, workDir).Get(ctx, nil)
+		if err != nil {
+			logger.Warn("Cleanup failed (non-critical)", "repo", job.RepoName, "error", err)
+		}
+		return EngineCIDetails{Skipped: true, SkipReason: reason}
+	}
+
+	// Step 3: Run Engine-CI
+	cache := job.Cache
+	if !cache.IsEmpty() && cache.Key == "" {
+		cache.Key = cacheKey
+	}
+	var details *EngineCIDetails
+	err = workflow.ExecuteActivity(ctx, RunEngineCIJob, RunEngineCIInput{
+		WorkDir: workDir,
+		Runner:  job.Runner,
+		Args:    job.EngineArgs,
+		Env:     job.Env,
+		Cache:   cache,
+		Reports: job.Reports,
+	}).Get(ctx, &details)
+	if err != nil {
+		logger.Error("Engine-CI execution failed", "repo", job.RepoName, "error", err)
+		// Don't cleanup on error - preserve directory for debugging
+		return failedDetails(err)
+	}
+
+	// Step 4: Upload artifacts before the workspace can be removed
+	if len(job.Artifacts) > 0 {
+		details.Artifacts = collectArtifacts(ctx, job, workDir)
+	}
+
+	// Step 5: Cleanup if successful (exit code 0)
+	if details.ExitCode == 0 {
+		logger.Info("Engine-CI succeeded, cleaning up", "repo", job.RepoName)
+		var cleanupErr error
+		err = workflow.ExecuteActivity(ctx, filesystem.CleanupDirectory, workDir).Get(ctx, &cleanupErr)
+		if err != nil {
+			logger.Warn("Cleanup failed (non-critical)", "repo", job.RepoName, "error", err)
+		}
+	} else {
+		logger.Error("Engine-CI failed, preserving directory for debugging",
+			"repo", job.RepoName,
+			"exitCode", details.ExitCode,
+			"failureClass", details.FailureClass,
+			"workDir", workDir,
+			"last50Lines", details.Last50Lines)
+		if details.Tests != nil {
+			logger.Error("Engine-CI failing tests",
+				"repo", job.RepoName,
+				"total", details.Tests.Total,
+				"failed", details.Tests.Failed,
+				"failures", details.Tests.Failures)
+		}
+	}
+
+	return *details
+}
+
+// collectArtifacts uploads the job's artifacts, failures are logged and don't fail the job
+func collectArtifacts(ctx workflow.Context, job EngineCIWorkflowInput, workDir string) []ArtifactRef {
+	jobID := job.JobID
+	if jobID == "" {
+		jobID = workflow.GetInfo(ctx).WorkflowExecution.ID
+	}
+
+	var refs []ArtifactRef
+	err := workflow.ExecuteActivity(ctx, CollectArtifacts, CollectArtifactsInput{
+		WorkDir: workDir,
+		Globs:   job.Artifacts,
+		Key:     ArtifactKey(jobID),
+	}).Get(ctx, &refs)
+	if err != nil {
+		workflow.GetLogger(ctx).Warn("Artifact collection failed (non-critical)", "repo", job.RepoName, "error", err)
+		return nil
+	}
+	return refs
+}
+
+// jobWorkspace returns the clone directory and default cache key of a job
+// Jobs of queues still draining under a name-only workflow ID keep the name-only layout so they
+// never share a workspace with the queue under the new workflow ID
+func jobWorkspace(ctx workflow.Context, job EngineCIWorkflowInput) (string, string) {
+	if parent := workflow.GetInfo(ctx).ParentWorkflowExecution; parent != nil && IsLegacyRepoWorkflowID(parent.ID) {
+		return legacyCloneDirectory(job.GitRepoURL), SanitizeRepoName(job.GitRepoURL)
+	}
+	identity := ParseRepoIdentity(job.GitRepoURL)
+	dir := identity.Workspace()
+	if job.Workspace != "" {
+		dir += "@" + sanitizeCacheName(job.Workspace)
+	}
+	return dir, identity.CacheKey()
+}
+
+// failedDetails builds the job result for an activity that failed before engine-ci reported an exit code
+func failedDetails(err error) EngineCIDetails {
+	return EngineCIDetails{
+		ExitCode:     -1,
+		Last50Lines:  err.Error(),
+		FailureClass: ClassifyError(err),
+	}
+}
+
+// shouldSkip computes the files changed since the job's base revision and reports whether
+// none of them is relevant for the job's path filter
+// Errors while computing the changes never skip the job
+func shouldSkip(ctx workflow.Context, job EngineCIWorkflowInput, workDir string) (bool, string) {
+	if job.Paths.IsEmpty() || job.BaseRef == "" {
+		return false, ""
+	}
+
+	logger := workflow.GetLogger(ctx)
+
+	var changed []string
+	err := workflow.ExecuteActivity(ctx, git.ChangedFiles, git.ChangedFilesInputs{
+		RepoPath: workDir,
+		Base:     job.BaseRef,
+	}).Get(ctx, &changed)
+	if err != nil {
+		logger.Warn("Failed to compute changed files, running job", "repo", job.RepoName, "base", job.BaseRef, "error", err)
+		return false, ""
+	}
+
+	relevant := job.Paths.RelevantFiles(changed)
+	logger.Info("Computed changed files", "repo", job.RepoName, "changed", len(changed), "relevant", len(relevant))
+	if len(relevant) > 0 {
+		return false, ""
+	}
+	return true, fmt.Sprintf("none of the %d files changed since %s match the path filter", len(changed), job.BaseRef)
+}
+
+// lookupCachedResult resolves the job's ref to a commit and returns the cached successful result for it
+// Any error is treated as a cache miss
+func lookupCachedResult(ctx workflow.Context, job EngineCIWorkflowInput) *EngineCIDetails {
+	logger := workflow.GetLogger(ctx)
+
+	var commitSHA string
+	err := workflow.ExecuteActivity(ctx, git.ResolveRef, job.GitRepoURL, job.GitRef).Get(ctx, &commitSHA)
+	if err != nil {
+		logger.Warn("Failed to resolve ref, skipping result cache", "repo", job.RepoName, "ref", job.GitRef, "error", err)
+		return nil
+	}
+
+	var lookup JobResultLookup
+	err = workflow.ExecuteActivity(ctx, LookupJobResult, ResultCacheKey(job, commitSHA)).Get(ctx, &lookup)
+	if err != nil {
+		logger.Warn("Result cache lookup failed", "repo", job.RepoName, "error", err)
+		return nil
+	}
+	if !lookup.Found {
+		return nil
+	}
+	return &lookup.Details
+}
+
+// storeResult caches a successful job result under the commit that was built
+func storeResult(ctx workflow.Context, job EngineCIWorkflowInput, details EngineCIDetails) {
+	if details.ExitCode != 0 || details.Skipped || details.CommitSHA == "" {
+		return
+	}
+	err := workflow.ExecuteActivity(ctx, StoreJobResult, ResultCacheKey(job, details.CommitSHA), details).Get(ctx, nil)
+	if err != nil {
+		workflow.GetLogger(ctx).Warn("Failed to store result (non-critical)", "repo", job.RepoName, "error", err)
+	}
+}
@@ -0,0 +1,175 @@
+package engineci
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"runtime"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TaskQueue is polled by every Engine-CI worker, it runs the workflows and the activities of jobs without labels
+const TaskQueue = "engine-ci-queue"
+
+// LabelScheduleToStartTimeout fails a labelled job when no worker with its labels picks it up in time
+var LabelScheduleToStartTimeout = 30 * time.Minute
+
+// Worker label settings, set from the worker configuration
+var (
+	// Labels are the configured labels of this worker
+	Labels []string
+	// LabelDetection adds the detected labels to Labels, see DetectLabels
+	LabelDetection bool
+	// LabelSets are the label combinations jobs may require besides single labels, e.g. `arm64+docker`
+	// A worker polls one task queue per label and per label set it has all labels of
+	LabelSets []string
+)
+
+// Memory labels, a host gets every label whose threshold it reaches
+var memoryLabels = []struct {
+	label string
+	min   uint64
+}{
+	{"mem-large", 16 << 30},
+	{"mem-xlarge", 64 << 30},
+}
+
+var labelPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]*$`)
+
+// NormalizeLabels lowercases, deduplicates and sorts labels, empty labels are dropped
+func NormalizeLabels(labels []string) []string {
+	seen := make(map[string]bool, len(labels))
+	var normalized []string
+	for _, label := range labels {
+		label = strings.ToLower(strings.TrimSpace(label))
+		if label == "" || seen[label] {
+			continue
+		}
+		seen[label] = true
+		normalized = append(normalized, label)
+	}
+	sort.Strings(normalized)
+	return normalized
+}
+
+// validateLabels checks the labels a job requires, more than one label must be one of LabelSets
+func validateLabels(labels []string) []error {
+	var errs []error
+	labels = NormalizeLabels(labels)
+	for _, label := range labels {
+		if !labelPattern.MatchString(label) {
+			errs = append(errs, fmt.Errorf("invalid label %q", label))
+		}
+	}
+	if len(errs) == 0 && len(labels) > 1 && !slices.Contains(labelSetQueues(LabelSets), LabelTaskQueue(labels)) {
+		errs = append(errs, fmt.Errorf("label set %q is not offered by the workers, add it to engineCI.labelSets", strings.Join(labels, "+")))
+	}
+	return errs
+}
+
+// labelSetQueues returns the task queues of `+` separated label sets
+func labelSetQueues(sets []string) []string {
+	queues := make([]string, 0, len(sets))
+	for _, set := range sets {
+		queues = append(queues, LabelTaskQueue(strings.Split(set, "+")))
+	}
+	return queues
+}
+
+// LabelTaskQueue returns the task queue of the activities of a job requiring the labels
+// Example: [docker arm64] -> engine-ci-queue@arm64+docker
+func LabelTaskQueue(labels []string) string {
+	labels = NormalizeLabels(labels)
+	if len(labels) == 0 {
+		return TaskQueue
+	}
+	return TaskQueue + "@" + strings.Join(labels, "+")
+}
+
+// WorkerTaskQueues returns the label task queues a worker with the labels polls in addition to
+// TaskQueue: one per label and one per label set whose labels the worker all has
+func WorkerTaskQueues(labels, sets []string) []string {
+	labels = NormalizeLabels(labels)
+	queues := make([]string, 0, len(labels))
+	for _, label := range labels {
+		queues = append(queues, LabelTaskQueue([]string{label}))
+	}
+	for _, set := range sets {
+		required := NormalizeLabels(strings.Split(set, "+"))
+		if len(required) > 1 && !slices.Contains(queues, LabelTaskQueue(required)) &&
+			!slices.ContainsFunc(required, func(label string) bool { return !slices.Contains(labels, label) }) {
+			queues = append(queues, LabelTaskQueue(required))
+		}
+	}
+	sort.Strings(queues)
+	return queues
+}
+
+// WorkerLabels returns the labels this worker advertises: Labels plus, with LabelDetection, the detected ones
+// It probes the host on every call, the Engine-CI bundle calls it once when its worker starts
+func WorkerLabels() []string {
+	return ConfiguredLabels(Labels, LabelDetection)
+}
+
+// ConfiguredLabels returns the given labels plus the detected ones when detect is set
+func ConfiguredLabels(labels []string, detect bool) []string {
+	labels = append([]string(nil), labels...)
+	if detect {
+		labels = append(labels, DetectLabels()...)
+	}
+	return NormalizeLabels(labels)
+}
+
+// DetectLabels returns the labels of the host: the architecture (e.g. `amd64`), `docker` when a
+// docker daemon is reachable and the memory labels (`mem-large` from 16 GiB, `mem-xlarge` from 64 GiB)
+func DetectLabels() []string {
+	labels := []string{runtime.GOARCH}
+	if dockerAvailable() {
+		labels = append(labels, "docker")
+	}
+	if total := totalMemory(); total > 0 {
+		for _, m := range memoryLabels {
+			if total >= m.min {
+				labels = append(labels, m.label)
+			}
+		}
+	}
+	return labels
+}
+
+func dockerAvailable() bool {
+	if os.Getenv("DOCKER_HOST") != "" {
+		return true
+	}
+	info, err := os.Stat("/var/run/docker.sock")
+	return err == nil && info.Mode()&os.ModeSocket != 0
+}
+
+// totalMemory reads MemTotal from /proc/meminfo, 0 when unknown
+func totalMemory() uint64 {
+	f, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+	return parseMemTotal(bufio.NewScanner(f))
+}
+
+func parseMemTotal(scanner *bufio.Scanner) uint64 {
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "MemTotal:" {
+			kb, err := strconv.ParseUint(fields[1], 10, 64)
+			if err != nil {
+				return 0
+			}
+			return kb << 10
+		}
+	}
+	return 0
+}
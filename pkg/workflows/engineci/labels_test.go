@@ -0,0 +1,119 @@
+package engineci
+
+import (
+	"bufio"
+	"context"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/containifyci/temporal-worker/pkg/activities/filesystem"
+	"github.com/containifyci/temporal-worker/pkg/activities/git"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.temporal.io/sdk/activity"
+)
+
+func TestNormalizeLabels(t *testing.T) {
+	assert.Equal(t, []string{"arm64", "docker"}, NormalizeLabels([]string{" Docker", "arm64", "", "docker"}))
+	assert.Nil(t, NormalizeLabels(nil))
+}
+
+func TestLabelTaskQueue(t *testing.T) {
+	assert.Equal(t, "engine-ci-queue", LabelTaskQueue(nil))
+	assert.Equal(t, "engine-ci-queue@docker", LabelTaskQueue([]string{"docker"}))
+	assert.Equal(t, "engine-ci-queue@arm64+docker", LabelTaskQueue([]string{"docker", "ARM64"}))
+}
+
+func TestWorkerTaskQueues(t *testing.T) {
+	assert.Empty(t, WorkerTaskQueues(nil, nil))
+	assert.Equal(t, []string{
+		"engine-ci-queue@amd64",
+		"engine-ci-queue@docker",
+	}, WorkerTaskQueues([]string{"docker", "amd64"}, nil))
+
+	// One queue per label plus the label sets the worker has every label of
+	queues := WorkerTaskQueues([]string{"a", "b", "c", "d"}, []string{"b+a", "a+e", "c+d+b", "a"})
+	assert.Equal(t, []string{
+		"engine-ci-queue@a",
+		"engine-ci-queue@a+b",
+		"engine-ci-queue@b",
+		"engine-ci-queue@b+c+d",
+		"engine-ci-queue@c",
+		"engine-ci-queue@d",
+	}, queues)
+	assert.Contains(t, queues, LabelTaskQueue([]string{"d", "b", "c"}))
+}
+
+func TestValidateLabels(t *testing.T) {
+	defer func(orig []string) { LabelSets = orig }(LabelSets)
+	LabelSets = []string{"arm64+docker"}
+
+	assert.Empty(t, validateLabels([]string{"docker"}))
+	assert.Empty(t, validateLabels([]string{"docker", "ARM64"}))
+	errs := validateLabels([]string{"docker", "mem-large"})
+	assert.Len(t, errs, 1)
+	assert.EqualError(t, errs[0], `label set "docker+mem-large" is not offered by the workers, add it to engineCI.labelSets`)
+	errs = validateLabels([]string{"gpu+cuda"})
+	assert.Len(t, errs, 1)
+	assert.ErrorContains(t, errs[0], `invalid label "gpu+cuda"`)
+}
+
+func TestWorkerLabels(t *testing.T) {
+	defer func(labels []string, detect bool) { Labels, LabelDetection = labels, detect }(Labels, LabelDetection)
+	Labels = []string{"GPU", " docker"}
+
+	LabelDetection = false
+	assert.Equal(t, []string{"docker", "gpu"}, WorkerLabels())
+
+	LabelDetection = true
+	assert.Contains(t, WorkerLabels(), runtime.GOARCH)
+	assert.Contains(t, WorkerLabels(), "gpu")
+}
+
+func TestParseMemTotal(t *testing.T) {
+	meminfo := "MemTotal:       32780356 kB\nMemFree:         1234 kB\n"
+	assert.Equal(t, uint64(32780356)<<10, parseMemTotal(bufio.NewScanner(strings.NewReader(meminfo))))
+	assert.Zero(t, parseMemTotal(bufio.NewScanner(strings.NewReader("MemFree: 1 kB\n"))))
+}
+
+func (s *WorkflowTestSuite) TestEngineCIJobWorkflow_RoutesToLabelQueue() {
+	defer func(orig []string) { LabelSets = orig }(LabelSets)
+	LabelSets = []string{"arm64+docker"}
+	env := s.NewTestWorkflowEnvironment()
+
+	queues := map[string]string{}
+	record := func(ctx context.Context, name string) { queues[name] = activity.GetInfo(ctx).TaskQueue }
+	env.OnActivity(git.CloneRepo, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(func(ctx context.Context, _, _, dir string) (string, error) {
+			record(ctx, "clone")
+			return dir, nil
+		})
+	env.OnActivity(RunEngineCIJob, mock.Anything, mock.Anything).
+		Return(func(ctx context.Context, _ RunEngineCIInput) (*EngineCIDetails, error) {
+			record(ctx, "run")
+			return &EngineCIDetails{ExitCode: 0}, nil
+		})
+	env.OnActivity(filesystem.CleanupDirectory, mock.Anything, mock.Anything).
+		Return(func(ctx context.Context, _ string) error {
+			record(ctx, "cleanup")
+			return nil
+		})
+
+	env.ExecuteWorkflow(EngineCIJobWorkflow, EngineCIWorkflowInput{
+		GitRepoURL: "https://github.com/test/repo",
+		GitRef:     "main",
+		EngineArgs: []string{"run"},
+		Force:      true,
+		Labels:     []string{"docker", "arm64"},
+	})
+
+	s.True(env.IsWorkflowCompleted())
+	s.NoError(env.GetWorkflowError())
+	s.Equal(map[string]string{
+		"clone":   "engine-ci-queue@arm64+docker",
+		"run":     "engine-ci-queue@arm64+docker",
+		"cleanup": "engine-ci-queue@arm64+docker",
+	}, queues)
+}
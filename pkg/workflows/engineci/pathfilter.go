@@ -0,0 +1,72 @@
+package engineci
+
+import (
+	"regexp"
+	"strings"
+)
+
+// IsEmpty reports whether the filter declares no patterns
+func (f PathFilter) IsEmpty() bool {
+	return len(f.Include) == 0 && len(f.Exclude) == 0
+}
+
+// RelevantFiles returns the files that match an include pattern (or all files when there
+// are no include patterns) and do not match any exclude pattern
+func (f PathFilter) RelevantFiles(files []string) []string {
+	var relevant []string
+	for _, file := range files {
+		if len(f.Include) > 0 && !matchAny(f.Include, file) {
+			continue
+		}
+		if matchAny(f.Exclude, file) {
+			continue
+		}
+		relevant = append(relevant, file)
+	}
+	return relevant
+}
+
+func matchAny(patterns []string, file string) bool {
+	for _, pattern := range patterns {
+		if MatchPath(pattern, file) {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchPath reports whether a slash separated path matches a glob pattern
+// `*` and `?` do not cross directory boundaries, `**` matches any number of directories
+// Example: `services/**/*.go` matches `services/api/main.go` and `services/main.go`
+func MatchPath(pattern, path string) bool {
+	re, err := regexp.Compile(globToRegexp(strings.TrimPrefix(pattern, "/")))
+	if err != nil {
+		return false
+	}
+	return re.MatchString(strings.TrimPrefix(path, "/"))
+}
+
+func globToRegexp(pattern string) string {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			b.WriteString("(.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	// A pattern naming a directory matches everything below it
+	b.WriteString("(/.*)?$")
+	return b.String()
+}
@@ -0,0 +1,72 @@
+package engineci
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchPath(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		path     string
+		expected bool
+	}{
+		{"*.md", "README.md", true},
+		{"*.md", "docs/guide.md", false},
+		{"**/*.md", "docs/guide.md", true},
+		{"**/*.md", "README.md", true},
+		{"docs", "docs/guide.md", true},
+		{"docs/**", "docs/a/b/c.png", true},
+		{"services/**/*.go", "services/main.go", true},
+		{"services/**/*.go", "services/api/handler/main.go", true},
+		{"services/**/*.go", "services/api/go.mod", false},
+		{"services/api", "services/api-gateway/main.go", false},
+		{"/cmd/*.go", "cmd/main.go", true},
+		{"cmd/?ain.go", "cmd/main.go", true},
+		{"go.mod", "go.sum", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+"_"+tt.path, func(t *testing.T) {
+			assert.Equal(t, tt.expected, MatchPath(tt.pattern, tt.path))
+		})
+	}
+}
+
+func TestPathFilter_RelevantFiles(t *testing.T) {
+	files := []string{"README.md", "docs/guide.md", "services/api/main.go", "services/web/index.ts"}
+
+	tests := []struct {
+		name     string
+		filter   PathFilter
+		expected []string
+	}{
+		{
+			name:     "Exclude docs",
+			filter:   PathFilter{Exclude: []string{"**/*.md"}},
+			expected: []string{"services/api/main.go", "services/web/index.ts"},
+		},
+		{
+			name:     "Include service",
+			filter:   PathFilter{Include: []string{"services/api/**"}},
+			expected: []string{"services/api/main.go"},
+		},
+		{
+			name:     "Include and exclude",
+			filter:   PathFilter{Include: []string{"services/**"}, Exclude: []string{"**/*.ts"}},
+			expected: []string{"services/api/main.go"},
+		},
+		{
+			name:     "Nothing relevant",
+			filter:   PathFilter{Include: []string{"infra/**"}},
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.filter.RelevantFiles(files))
+		})
+	}
+}
@@ -0,0 +1,345 @@
+package engineci
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+
+	"github.com/containifyci/temporal-worker/pkg/logging"
+)
+
+// invalidPipelineErrorType is the application error type of pipelines rejected by ValidatePipeline
+const invalidPipelineErrorType = "InvalidPipeline"
+
+var stageNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*$`)
+
+// PipelineWorkflowID returns the workflow ID of a pipeline
+// Example: engine-ci-pipeline/github.com/containifyci/temporal-worker/1718000000
+func PipelineWorkflowID(repoURL, pipelineID string) string {
+	return "engine-ci-pipeline/" + ParseRepoIdentity(repoURL).String() + "/" + pipelineID
+}
+
+// ValidatePipeline checks stage names, needs, conditions and the stage jobs, and rejects cycles
+func ValidatePipeline(input PipelineInput) error {
+	var errs []error
+
+	if strings.TrimSpace(input.GitRepoURL) == "" {
+		errs = append(errs, errors.New("repository URL must not be empty"))
+	}
+	if len(input.Stages) == 0 {
+		errs = append(errs, errors.New("pipeline has no stages"))
+	}
+	if input.MaxParallel < 0 {
+		errs = append(errs, errors.New("max parallel stages must not be negative"))
+	}
+
+	stages := make(map[string]PipelineStage, len(input.Stages))
+	for _, stage := range input.Stages {
+		if !stageNamePattern.MatchString(stage.Name) {
+			errs = append(errs, fmt.Errorf("invalid stage name %q", stage.Name))
+			continue
+		}
+		if _, ok := stages[stage.Name]; ok {
+			errs = append(errs, fmt.Errorf("duplicate stage %q", stage.Name))
+			continue
+		}
+		stages[stage.Name] = stage
+	}
+
+	for _, stage := range input.Stages {
+		for _, need := range stage.Needs {
+			if _, ok := stages[need]; !ok || need == stage.Name {
+				errs = append(errs, fmt.Errorf("stage %q needs unknown stage %q", stage.Name, need))
+			}
+		}
+		switch stage.When.If {
+		case "", StageTriggerSuccess, StageTriggerFailure, StageTriggerAlways:
+		default:
+			errs = append(errs, fmt.Errorf("stage %q has unknown trigger %q", stage.Name, stage.When.If))
+		}
+		job := stageJob(input, stage, "")
+		if err := ValidateJob(job); err != nil {
+			errs = append(errs, fmt.Errorf("stage %q: %w", stage.Name, err))
+		}
+	}
+
+	if len(errs) == 0 {
+		if cycle := findCycle(input.Stages); cycle != nil {
+			errs = append(errs, fmt.Errorf("stages form a cycle: %s", strings.Join(cycle, " -> ")))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// findCycle returns the stages of a dependency cycle, nil when the stages form a DAG
+func findCycle(stages []PipelineStage) []string {
+	needs := make(map[string][]string, len(stages))
+	for _, stage := range stages {
+		needs[stage.Name] = stage.Needs
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[string]int, len(stages))
+	var path []string
+
+	var visit func(name string) []string
+	visit = func(name string) []string {
+		switch state[name] {
+		case visiting:
+			for i, n := range path {
+				if n == name {
+					return append(append([]string(nil), path[i:]...), name)
+				}
+			}
+		case done:
+			return nil
+		}
+		state[name] = visiting
+		path = append(path, name)
+		for _, need := range needs[name] {
+			if cycle := visit(need); cycle != nil {
+				return cycle
+			}
+		}
+		path = path[:len(path)-1]
+		state[name] = done
+		return nil
+	}
+
+	for _, stage := range stages {
+		if cycle := visit(stage.Name); cycle != nil {
+			return cycle
+		}
+	}
+	return nil
+}
+
+// stageJob builds the job of a stage, it inherits repository, ref, base revision and environment
+// from the pipeline and gets a workspace of its own so parallel stages don't share a clone
+func stageJob(input PipelineInput, stage PipelineStage, pipelineID string) EngineCIWorkflowInput {
+	job := stage.Job
+	job.GitRepoURL = input.GitRepoURL
+	job.GitRef = input.GitRef
+	if job.BaseRef == "" {
+		job.BaseRef = input.BaseRef
+	}
+	if len(input.Env) > 0 {
+		env := make(map[string]string, len(input.Env)+len(job.Env))
+		for k, v := range input.Env {
+			env[k] = v
+		}
+		for k, v := range job.Env {
+			env[k] = v
+		}
+		job.Env = env
+	}
+	if pipelineID != "" {
+		job.JobID = pipelineID + "/" + stage.Name
+		job.Workspace = job.JobID
+	}
+	if job.RepoName == "" {
+		job.RepoName = ParseRepoIdentity(input.GitRepoURL).DisplayName()
+	}
+	return job
+}
+
+// matchRef reports whether a ref matches one of the patterns, refs/heads/ and refs/tags/ are ignored
+func matchRef(patterns []string, ref string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	ref = strings.TrimPrefix(strings.TrimPrefix(ref, "refs/heads/"), "refs/tags/")
+	return matchAny(patterns, ref)
+}
+
+// stagePassed reports whether a finished stage counts as successful for the stages that need it
+func stagePassed(stage PipelineStage, result StageResult) bool {
+	return result.Status == StageStatusSucceeded || (result.Status == StageStatusFailed && stage.ContinueOnError)
+}
+
+// stageSucceeded reports whether a job result is a success, jobs skipped by a path filter succeed
+func stageSucceeded(details EngineCIDetails) bool {
+	return details.ExitCode == 0 && details.FailureClass == FailureClassNone
+}
+
+// EngineCIPipelineWorkflow runs the stages of a pipeline as EngineCIJobWorkflow children
+// A stage starts as soon as all stages it needs have finished and its condition holds, independent
+// stages run in parallel up to MaxParallel. The PipelineStatusQuery query reports the live status
+// Failed stages are part of the result, not workflow errors
+func EngineCIPipelineWorkflow(ctx workflow.Context, input PipelineInput) (PipelineResult, error) {
+	ctx = logging.WithRepository(ctx, ParseRepoIdentity(input.GitRepoURL).DisplayName())
+	logger := workflow.GetLogger(ctx)
+
+	if err := ValidatePipeline(input); err != nil {
+		return PipelineResult{}, temporal.NewNonRetryableApplicationError(err.Error(), invalidPipelineErrorType, err)
+	}
+
+	pipelineID := input.PipelineID
+	if pipelineID == "" {
+		pipelineID = workflow.GetInfo(ctx).WorkflowExecution.ID
+	}
+	logger.Info("Started Engine-CI pipeline", "pipelineID", pipelineID, "ref", input.GitRef, "stages", len(input.Stages))
+
+	result := PipelineResult{PipelineID: pipelineID, Status: PipelineStatusRunning}
+	index := make(map[string]int, len(input.Stages))
+	for i, stage := range input.Stages {
+		index[stage.Name] = i
+		result.Stages = append(result.Stages, StageResult{Name: stage.Name, Status: StageStatusPending})
+	}
+
+	err := workflow.SetQueryHandler(ctx, PipelineStatusQuery, func() (PipelineResult, error) {
+		return result, nil
+	})
+	if err != nil {
+		return result, err
+	}
+
+	selector := workflow.NewSelector(ctx)
+	running := map[string]workflow.CancelFunc{}
+	failed := false
+	stopped := "" // reason pending stages are canceled
+
+	finish := func(i int, details EngineCIDetails, err error) {
+		stage := input.Stages[i]
+		delete(running, stage.Name)
+
+		status := StageStatusSucceeded
+		if err != nil {
+			details = failedDetails(err)
+		}
+		switch {
+		case details.FailureClass == FailureClassCanceled:
+			status = StageStatusCanceled
+			if result.Stages[i].Reason == "" {
+				result.Stages[i].Reason = "canceled"
+			}
+		case !stageSucceeded(details):
+			status = StageStatusFailed
+		}
+		result.Stages[i].Status = status
+		result.Stages[i].Details = &details
+		logger.Info("Engine-CI pipeline stage finished", "pipelineID", pipelineID, "stage", stage.Name, "status", status)
+
+		if status == StageStatusFailed && !stage.ContinueOnError {
+			failed = true
+			if input.FailFast && stopped == "" {
+				stopped = fmt.Sprintf("fail-fast after stage %q failed", stage.Name)
+				for name, cancel := range running {
+					result.Stages[index[name]].Reason = stopped
+					cancel()
+				}
+			}
+		}
+	}
+
+	start := func(i int) {
+		stage := input.Stages[i]
+		job := stageJob(input, stage, pipelineID)
+		childID := JobWorkflowID(job.JobID)
+
+		childCtx, cancel := workflow.WithCancel(ctx)
+		childCtx = workflow.WithChildOptions(childCtx, workflow.ChildWorkflowOptions{WorkflowID: childID})
+		running[stage.Name] = cancel
+		result.Stages[i].Status = StageStatusRunning
+		result.Stages[i].WorkflowID = childID
+		logger.Info("Engine-CI pipeline stage started", "pipelineID", pipelineID, "stage", stage.Name, "workflowID", childID)
+
+		future := workflow.ExecuteChildWorkflow(childCtx, EngineCIJobWorkflow, job)
+		selector.AddFuture(future, func(f workflow.Future) {
+			var details EngineCIDetails
+			err := f.Get(ctx, &details)
+			finish(i, details, err)
+		})
+	}
+
+	// resolve settles pending stages whose needs have finished, it returns whether anything changed
+	resolve := func() bool {
+		changed := false
+		for i, stage := range input.Stages {
+			if result.Stages[i].Status != StageStatusPending {
+				continue
+			}
+			if stopped != "" {
+				result.Stages[i].Status = StageStatusCanceled
+				result.Stages[i].Reason = stopped
+				changed = true
+				continue
+			}
+
+			ready, allPassed, anyFailed := true, true, false
+			for _, need := range stage.Needs {
+				needed := result.Stages[index[need]]
+				switch needed.Status {
+				case StageStatusPending, StageStatusRunning:
+					ready = false
+				case StageStatusFailed:
+					anyFailed = true
+				}
+				if !stagePassed(input.Stages[index[need]], needed) {
+					allPassed = false
+				}
+			}
+			if !ready {
+				continue
+			}
+
+			reason := ""
+			switch {
+			case !matchRef(stage.When.Refs, input.GitRef):
+				reason = fmt.Sprintf("ref %q does not match %v", input.GitRef, stage.When.Refs)
+			case stage.When.If == StageTriggerFailure && !anyFailed:
+				reason = "no needed stage failed"
+			case (stage.When.If == "" || stage.When.If == StageTriggerSuccess) && !allPassed:
+				reason = "a needed stage did not succeed"
+			}
+			if reason != "" {
+				result.Stages[i].Status = StageStatusSkipped
+				result.Stages[i].Reason = reason
+				changed = true
+				continue
+			}
+
+			if input.MaxParallel > 0 && len(running) >= input.MaxParallel {
+				continue
+			}
+			start(i)
+			changed = true
+		}
+		return changed
+	}
+
+	for {
+		if ctx.Err() != nil && stopped == "" {
+			stopped = "pipeline canceled"
+		}
+		for resolve() {
+		}
+		if len(running) == 0 {
+			break
+		}
+		selector.Select(ctx)
+	}
+
+	switch {
+	case ctx.Err() != nil:
+		result.Status = PipelineStatusCanceled
+	case failed:
+		result.Status = PipelineStatusFailed
+	default:
+		result.Status = PipelineStatusSucceeded
+	}
+	logger.Info("Engine-CI pipeline finished", "pipelineID", pipelineID, "status", result.Status)
+
+	if result.Status == PipelineStatusCanceled {
+		return result, temporal.NewCanceledError(result)
+	}
+	return result, nil
+}
@@ -0,0 +1,336 @@
+package engineci
+
+import (
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/containifyci/temporal-worker/pkg/activities/filesystem"
+	"github.com/containifyci/temporal-worker/pkg/activities/git"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/testsuite"
+	"go.temporal.io/sdk/workflow"
+)
+
+func testStage(name string, needs ...string) PipelineStage {
+	return PipelineStage{Name: name, Needs: needs, Job: EngineCIWorkflowInput{EngineArgs: []string{"run", "-t", name}}}
+}
+
+func testPipeline(stages ...PipelineStage) PipelineInput {
+	return PipelineInput{
+		PipelineID: "pipe",
+		GitRepoURL: "https://github.com/test/repo",
+		GitRef:     "main",
+		Stages:     stages,
+	}
+}
+
+func TestValidatePipeline(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(input *PipelineInput)
+		errs   []string
+	}{
+		{
+			name:   "valid pipeline",
+			mutate: func(input *PipelineInput) {},
+		},
+		{
+			name:   "no stages",
+			mutate: func(input *PipelineInput) { input.Stages = nil },
+			errs:   []string{"pipeline has no stages"},
+		},
+		{
+			name:   "duplicate stage",
+			mutate: func(input *PipelineInput) { input.Stages = append(input.Stages, testStage("lint")) },
+			errs:   []string{`duplicate stage "lint"`},
+		},
+		{
+			name:   "invalid stage name",
+			mutate: func(input *PipelineInput) { input.Stages[0].Name = "lint/all" },
+			errs:   []string{`invalid stage name "lint/all"`},
+		},
+		{
+			name:   "unknown need",
+			mutate: func(input *PipelineInput) { input.Stages[2].Needs = []string{"build"} },
+			errs:   []string{`stage "publish" needs unknown stage "build"`},
+		},
+		{
+			name:   "cycle",
+			mutate: func(input *PipelineInput) { input.Stages[0].Needs = []string{"publish"} },
+			errs:   []string{"stages form a cycle: lint -> publish -> lint"},
+		},
+		{
+			name:   "unknown trigger",
+			mutate: func(input *PipelineInput) { input.Stages[2].When.If = "sometimes" },
+			errs:   []string{`stage "publish" has unknown trigger "sometimes"`},
+		},
+		{
+			name:   "invalid stage job",
+			mutate: func(input *PipelineInput) { input.Stages[1].Job.EngineArgs = nil },
+			errs:   []string{`stage "test": engine-ci arguments must not be empty`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := testPipeline(testStage("lint"), testStage("test"), testStage("publish", "lint", "test"))
+			tt.mutate(&input)
+
+			err := ValidatePipeline(input)
+			if len(tt.errs) == 0 {
+				assert.NoError(t, err)
+				return
+			}
+			assert.Error(t, err)
+			for _, msg := range tt.errs {
+				assert.Contains(t, err.Error(), msg)
+			}
+		})
+	}
+}
+
+func TestStageJob(t *testing.T) {
+	input := testPipeline(testStage("lint"))
+	input.BaseRef = "abc"
+	input.Env = map[string]string{"A": "pipeline", "B": "pipeline"}
+	stage := input.Stages[0]
+	stage.Job.Env = map[string]string{"B": "stage"}
+
+	job := stageJob(input, stage, "pipe")
+	assert.Equal(t, "pipe/lint", job.JobID)
+	assert.Equal(t, "pipe/lint", job.Workspace)
+	assert.Equal(t, "https://github.com/test/repo", job.GitRepoURL)
+	assert.Equal(t, "main", job.GitRef)
+	assert.Equal(t, "abc", job.BaseRef)
+	assert.Equal(t, "test/repo", job.RepoName)
+	assert.Equal(t, map[string]string{"A": "pipeline", "B": "stage"}, job.Env)
+}
+
+// stageResults maps stage names to their status
+func stageResults(result PipelineResult) map[string]StageStatus {
+	statuses := map[string]StageStatus{}
+	for _, stage := range result.Stages {
+		statuses[stage.Name] = stage.Status
+	}
+	return statuses
+}
+
+// mockStages mocks the job workflows of a pipeline, failing stages exit with 1
+// It returns the stage names in the order they were started
+func mockStages(env *testsuite.TestWorkflowEnvironment, failing ...string) *[]string {
+	var started []string
+	env.OnWorkflow(EngineCIJobWorkflow, mock.Anything, mock.Anything).
+		Return(func(ctx workflow.Context, job EngineCIWorkflowInput) (EngineCIDetails, error) {
+			name := job.EngineArgs[len(job.EngineArgs)-1]
+			started = append(started, name)
+			if err := workflow.Sleep(ctx, time.Minute); err != nil {
+				return EngineCIDetails{}, err
+			}
+			for _, f := range failing {
+				if f == name {
+					return EngineCIDetails{ExitCode: 1, FailureClass: FailureClassBuild}, nil
+				}
+			}
+			return EngineCIDetails{ExitCode: 0}, nil
+		})
+	return &started
+}
+
+// executePipeline runs a pipeline to completion and returns its result and the elapsed workflow time
+func (s *WorkflowTestSuite) executePipeline(env *testsuite.TestWorkflowEnvironment, input PipelineInput) (PipelineResult, time.Duration) {
+	start := env.Now()
+	env.ExecuteWorkflow(EngineCIPipelineWorkflow, input)
+	s.True(env.IsWorkflowCompleted())
+	s.NoError(env.GetWorkflowError())
+
+	var result PipelineResult
+	s.NoError(env.GetWorkflowResult(&result))
+	return result, env.Now().Sub(start)
+}
+
+func (s *WorkflowTestSuite) TestEngineCIPipelineWorkflow_RunsStagesInDependencyOrder() {
+	env := s.NewTestWorkflowEnvironment()
+	started := mockStages(env)
+
+	result, elapsed := s.executePipeline(env, testPipeline(testStage("publish", "lint", "test"), testStage("lint"), testStage("test")))
+
+	s.Equal(PipelineStatusSucceeded, result.Status)
+	s.Len(*started, 3)
+	first := append([]string(nil), (*started)[:2]...)
+	sort.Strings(first)
+	s.Equal([]string{"lint", "test"}, first, "independent stages start together")
+	s.Equal("publish", (*started)[2])
+	s.Equal(JobWorkflowID("pipe/publish"), result.Stages[0].WorkflowID)
+	// lint and test ran in parallel
+	s.Equal(2*time.Minute, elapsed)
+}
+
+func (s *WorkflowTestSuite) TestEngineCIPipelineWorkflow_MaxParallel() {
+	env := s.NewTestWorkflowEnvironment()
+	started := mockStages(env)
+
+	input := testPipeline(testStage("lint"), testStage("test"), testStage("vet"))
+	input.MaxParallel = 1
+	result, elapsed := s.executePipeline(env, input)
+
+	s.Equal(PipelineStatusSucceeded, result.Status)
+	s.Equal([]string{"lint", "test", "vet"}, *started)
+	s.Equal(3*time.Minute, elapsed)
+}
+
+func (s *WorkflowTestSuite) TestEngineCIPipelineWorkflow_FailureSkipsDependents() {
+	env := s.NewTestWorkflowEnvironment()
+	started := mockStages(env, "test")
+
+	notify := testStage("notify", "test")
+	notify.When.If = StageTriggerFailure
+	cleanup := testStage("cleanup", "publish")
+	cleanup.When.If = StageTriggerAlways
+	result, _ := s.executePipeline(env, testPipeline(testStage("lint"), testStage("test"), testStage("publish", "lint", "test"), notify, cleanup))
+
+	s.Equal(PipelineStatusFailed, result.Status)
+	s.Equal(map[string]StageStatus{
+		"lint":    StageStatusSucceeded,
+		"test":    StageStatusFailed,
+		"publish": StageStatusSkipped,
+		"notify":  StageStatusSucceeded,
+		"cleanup": StageStatusSucceeded,
+	}, stageResults(result))
+	s.NotContains(*started, "publish")
+	s.Equal("a needed stage did not succeed", result.Stages[2].Reason)
+}
+
+func (s *WorkflowTestSuite) TestEngineCIPipelineWorkflow_ContinueOnError() {
+	env := s.NewTestWorkflowEnvironment()
+	mockStages(env, "lint")
+
+	lint := testStage("lint")
+	lint.ContinueOnError = true
+	result, _ := s.executePipeline(env, testPipeline(lint, testStage("publish", "lint")))
+
+	s.Equal(PipelineStatusSucceeded, result.Status)
+	s.Equal(map[string]StageStatus{
+		"lint":    StageStatusFailed,
+		"publish": StageStatusSucceeded,
+	}, stageResults(result))
+}
+
+func (s *WorkflowTestSuite) TestEngineCIPipelineWorkflow_FailFastCancelsStages() {
+	env := s.NewTestWorkflowEnvironment()
+	// A registered stand-in instead of a mock, mocked child workflows don't observe cancellation
+	env.RegisterWorkflowWithOptions(func(ctx workflow.Context, job EngineCIWorkflowInput) (EngineCIDetails, error) {
+		if job.JobID == "pipe/lint" {
+			_ = workflow.Sleep(ctx, time.Minute)
+			return EngineCIDetails{ExitCode: 1, FailureClass: FailureClassBuild}, nil
+		}
+		if err := workflow.Sleep(ctx, time.Hour); err != nil {
+			return EngineCIDetails{}, err
+		}
+		return EngineCIDetails{ExitCode: 0}, nil
+	}, workflow.RegisterOptions{Name: "EngineCIJobWorkflow"})
+
+	input := testPipeline(testStage("lint"), testStage("test"), testStage("publish", "lint", "test"))
+	input.FailFast = true
+	result, elapsed := s.executePipeline(env, input)
+
+	s.Equal(PipelineStatusFailed, result.Status)
+	s.Equal(map[string]StageStatus{
+		"lint":    StageStatusFailed,
+		"test":    StageStatusCanceled,
+		"publish": StageStatusCanceled,
+	}, stageResults(result))
+	s.Equal(`fail-fast after stage "lint" failed`, result.Stages[1].Reason)
+	s.Less(elapsed, time.Hour)
+}
+
+func (s *WorkflowTestSuite) TestEngineCIPipelineWorkflow_RefConditionSkipsStage() {
+	env := s.NewTestWorkflowEnvironment()
+	started := mockStages(env)
+
+	publish := testStage("publish", "test")
+	publish.When.Refs = []string{"main", "release/*"}
+	input := testPipeline(testStage("test"), publish)
+	input.GitRef = "refs/heads/feature/x"
+	result, _ := s.executePipeline(env, input)
+
+	s.Equal(PipelineStatusSucceeded, result.Status)
+	s.Equal(StageStatusSkipped, result.Stages[1].Status)
+	s.Contains(result.Stages[1].Reason, "does not match")
+	s.Equal([]string{"test"}, *started)
+}
+
+func (s *WorkflowTestSuite) TestEngineCIPipelineWorkflow_StatusQuery() {
+	env := s.NewTestWorkflowEnvironment()
+	mockStages(env)
+
+	env.RegisterDelayedCallback(func() {
+		value, err := env.QueryWorkflow(PipelineStatusQuery)
+		s.NoError(err)
+		var status PipelineResult
+		s.NoError(value.Get(&status))
+		s.Equal(PipelineStatusRunning, status.Status)
+		s.Equal(map[string]StageStatus{
+			"lint":    StageStatusSucceeded,
+			"publish": StageStatusRunning,
+		}, stageResults(status))
+	}, 90*time.Second)
+
+	result, _ := s.executePipeline(env, testPipeline(testStage("lint"), testStage("publish", "lint")))
+	s.Equal(PipelineStatusSucceeded, result.Status)
+}
+
+func (s *WorkflowTestSuite) TestEngineCIPipelineWorkflow_RejectsInvalidPipeline() {
+	env := s.NewTestWorkflowEnvironment()
+
+	env.ExecuteWorkflow(EngineCIPipelineWorkflow, testPipeline(testStage("lint", "lint")))
+
+	s.True(env.IsWorkflowCompleted())
+	var appErr *temporal.ApplicationError
+	s.True(errors.As(env.GetWorkflowError(), &appErr))
+	s.Equal(invalidPipelineErrorType, appErr.Type())
+	s.True(appErr.NonRetryable())
+}
+
+func (s *WorkflowTestSuite) TestEngineCIPipelineWorkflow_StagesUseOwnWorkspace() {
+	env := s.NewTestWorkflowEnvironment()
+
+	dir := "/tmp/ci/github.com/test/repo@pipe-lint"
+	env.OnActivity(git.CloneRepo, mock.Anything, "https://github.com/test/repo", "main", dir).
+		Return(dir, nil)
+	env.OnActivity(RunEngineCIJob, mock.Anything, mock.MatchedBy(func(input RunEngineCIInput) bool { return input.WorkDir == dir })).
+		Return(&EngineCIDetails{ExitCode: 0}, nil)
+	env.OnActivity(filesystem.CleanupDirectory, mock.Anything, dir).Return(nil)
+	env.RegisterWorkflow(EngineCIJobWorkflow)
+
+	env.ExecuteWorkflow(EngineCIPipelineWorkflow, testPipeline(testStage("lint")))
+
+	s.True(env.IsWorkflowCompleted())
+	s.NoError(env.GetWorkflowError())
+	env.AssertExpectations(s.T())
+}
+
+func (s *WorkflowTestSuite) TestEngineCIPipelineWorkflow_StagesUseOwnWorkspaceUnderPipelineID() {
+	env := s.NewTestWorkflowEnvironment()
+	// The stages are children of the pipeline, its real workflow ID must not look like a legacy queue ID
+	env.SetStartWorkflowOptions(client.StartWorkflowOptions{ID: PipelineWorkflowID("https://github.com/test/repo", "pipe")})
+
+	dir := "/tmp/ci/github.com/test/repo@pipe-lint"
+	env.OnActivity(git.CloneRepo, mock.Anything, "https://github.com/test/repo", "main", dir).
+		Return(dir, nil)
+	env.OnActivity(RunEngineCIJob, mock.Anything, mock.MatchedBy(func(input RunEngineCIInput) bool { return input.WorkDir == dir })).
+		Return(&EngineCIDetails{ExitCode: 0}, nil)
+	env.OnActivity(filesystem.CleanupDirectory, mock.Anything, dir).Return(nil)
+	env.RegisterWorkflow(EngineCIJobWorkflow)
+
+	env.ExecuteWorkflow(EngineCIPipelineWorkflow, testPipeline(testStage("lint")))
+
+	s.True(env.IsWorkflowCompleted())
+	s.NoError(env.GetWorkflowError())
+	env.AssertExpectations(s.T())
+}
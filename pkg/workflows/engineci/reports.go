@@ -0,0 +1,278 @@
+package engineci
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Limits that keep the summary small enough for the workflow history
+const (
+	// MaxReportedFailures bounds the number of failing tests listed in a summary
+	MaxReportedFailures = 20
+	// maxFailureOutputLines bounds the output kept per failing test
+	maxFailureOutputLines = 30
+	// maxReportFileSize skips report files that are unreasonably large
+	maxReportFileSize = 50 << 20
+)
+
+// reportSkipDirs are never searched for report files
+var reportSkipDirs = map[string]bool{
+	".git":         true,
+	"node_modules": true,
+	"vendor":       true,
+}
+
+// testReport accumulates the results of all parsed reports
+// A test is identified by package and name, the output and report files often cover the same run, so a test
+// found again keeps its worst result instead of being counted twice
+type testReport struct {
+	order   []string
+	results map[string]testResult
+}
+
+type testResult struct {
+	result  string // pass, skip or fail
+	failure TestFailure
+}
+
+// resultRank orders the results, a failure is never replaced by a pass of the same test
+var resultRank = map[string]int{"skip": 0, "pass": 1, "fail": 2}
+
+func (r *testReport) add(pkg, name, result string, failure TestFailure) {
+	if r.results == nil {
+		r.results = map[string]testResult{}
+	}
+	key := pkg + "\x00" + name
+	previous, found := r.results[key]
+	if !found {
+		r.order = append(r.order, key)
+	} else if resultRank[previous.result] > resultRank[result] {
+		return
+	}
+	r.results[key] = testResult{result: result, failure: failure}
+}
+
+// result returns the summary, nil when no test was found
+func (r *testReport) result() *TestSummary {
+	if len(r.order) == 0 {
+		return nil
+	}
+	var summary TestSummary
+	var failures []TestFailure
+	for _, key := range r.order {
+		res := r.results[key]
+		summary.Total++
+		switch res.result {
+		case "pass":
+			summary.Passed++
+		case "skip":
+			summary.Skipped++
+		default:
+			summary.Failed++
+			failures = append(failures, res.failure)
+		}
+	}
+	sort.SliceStable(failures, func(i, j int) bool {
+		return failures[i].Package+"."+failures[i].Name < failures[j].Package+"."+failures[j].Name
+	})
+	if len(failures) > MaxReportedFailures {
+		failures = failures[:MaxReportedFailures]
+	}
+	summary.Failures = failures
+	return &summary
+}
+
+// CollectTestReports parses the `go test -json` events in the command output and the report
+// files matching the globs below workDir into a single summary
+// Files are recognised by content: JUnit XML or `go test -json` streams, tests found in several of them count once
+func CollectTestReports(workDir string, globs []string, output string) (*TestSummary, error) {
+	report := &testReport{}
+	parseGoTestJSON(report, output)
+
+	if len(globs) == 0 {
+		return report.result(), nil
+	}
+
+	var errs []string
+	err := filepath.WalkDir(workDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if reportSkipDirs[d.Name()] {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(workDir, path)
+		if err != nil || !matchAny(globs, filepath.ToSlash(rel)) {
+			return nil
+		}
+		if info, err := d.Info(); err != nil || info.Size() > maxReportFileSize {
+			errs = append(errs, fmt.Sprintf("%s: skipped", rel))
+			return nil
+		}
+		if err := parseReportFile(report, path); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", rel, err))
+		}
+		return nil
+	})
+	if err != nil {
+		return report.result(), err
+	}
+	if len(errs) > 0 {
+		return report.result(), fmt.Errorf("failed to parse test reports: %s", strings.Join(errs, "; "))
+	}
+	return report.result(), nil
+}
+
+func parseReportFile(report *testReport, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	trimmed := bytes.TrimSpace(data)
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("<")):
+		return parseJUnitXML(report, trimmed)
+	case bytes.HasPrefix(trimmed, []byte("{")):
+		parseGoTestJSON(report, string(trimmed))
+		return nil
+	case len(trimmed) == 0:
+		return nil
+	default:
+		return fmt.Errorf("unknown report format")
+	}
+}
+
+// goTestEvent is a single event of `go test -json` (see `go doc test2json`)
+type goTestEvent struct {
+	Action  string
+	Package string
+	Test    string
+	Output  string
+}
+
+// parseGoTestJSON parses `go test -json` events, lines that are no events are ignored
+func parseGoTestJSON(report *testReport, stream string) {
+	outputs := map[string][]string{}
+	scanner := bufio.NewScanner(strings.NewReader(stream))
+	scanner.Buffer(make([]byte, 0, 64*1024), 10<<20)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "{") {
+			continue
+		}
+		var event goTestEvent
+		if err := json.Unmarshal([]byte(line), &event); err != nil || event.Test == "" {
+			continue
+		}
+		key := event.Package + "\x00" + event.Test
+		switch event.Action {
+		case "output":
+			outputs[key] = append(outputs[key], strings.TrimRight(event.Output, "\n"))
+		case "pass", "skip":
+			report.add(event.Package, event.Test, event.Action, TestFailure{})
+			delete(outputs, key)
+		case "fail":
+			report.add(event.Package, event.Test, event.Action, TestFailure{
+				Package: event.Package,
+				Name:    event.Test,
+				Output:  lastLines(outputs[key], maxFailureOutputLines),
+			})
+			delete(outputs, key)
+		}
+	}
+}
+
+// junitSuites covers both a <testsuites> root and a single <testsuite> root
+type junitSuites struct {
+	XMLName xml.Name
+	Suites  []junitSuite `xml:"testsuite"`
+	junitSuite
+}
+
+type junitSuite struct {
+	Name   string       `xml:"name,attr"`
+	Cases  []junitCase  `xml:"testcase"`
+	Suites []junitSuite `xml:"testsuite"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitMessage `xml:"failure"`
+	Error     *junitMessage `xml:"error"`
+	Skipped   *junitMessage `xml:"skipped"`
+	SystemOut string        `xml:"system-out"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Body    string `xml:",chardata"`
+}
+
+func parseJUnitXML(report *testReport, data []byte) error {
+	var root junitSuites
+	if err := xml.Unmarshal(data, &root); err != nil {
+		return err
+	}
+	switch root.XMLName.Local {
+	case "testsuites":
+		for _, suite := range root.Suites {
+			addJUnitSuite(report, suite)
+		}
+	case "testsuite":
+		// Nested suites are decoded into the outer field
+		suite := root.junitSuite
+		suite.Suites = root.Suites
+		addJUnitSuite(report, suite)
+	default:
+		return fmt.Errorf("unexpected root element <%s>", root.XMLName.Local)
+	}
+	return nil
+}
+
+func addJUnitSuite(report *testReport, suite junitSuite) {
+	for _, c := range suite.Cases {
+		pkg := c.ClassName
+		if pkg == "" {
+			pkg = suite.Name
+		}
+		switch {
+		case c.Failure != nil || c.Error != nil:
+			msg := c.Failure
+			if msg == nil {
+				msg = c.Error
+			}
+			output := strings.TrimSpace(strings.Join([]string{msg.Message, msg.Body, c.SystemOut}, "\n"))
+			report.add(pkg, c.Name, "fail", TestFailure{
+				Package: pkg,
+				Name:    c.Name,
+				Output:  lastLines(strings.Split(output, "\n"), maxFailureOutputLines),
+			})
+		case c.Skipped != nil:
+			report.add(pkg, c.Name, "skip", TestFailure{})
+		default:
+			report.add(pkg, c.Name, "pass", TestFailure{})
+		}
+	}
+	for _, nested := range suite.Suites {
+		addJUnitSuite(report, nested)
+	}
+}
+
+func lastLines(lines []string, n int) string {
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,140 @@
+package engineci
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const goTestJSONStream = `{"Action":"run","Package":"example.com/api","Test":"TestOK"}
+{"Action":"pass","Package":"example.com/api","Test":"TestOK","Elapsed":0.01}
+{"Action":"run","Package":"example.com/api","Test":"TestBroken"}
+{"Action":"output","Package":"example.com/api","Test":"TestBroken","Output":"=== RUN   TestBroken\n"}
+{"Action":"output","Package":"example.com/api","Test":"TestBroken","Output":"    api_test.go:12: expected 1, got 2\n"}
+{"Action":"fail","Package":"example.com/api","Test":"TestBroken","Elapsed":0.02}
+{"Action":"skip","Package":"example.com/api","Test":"TestSlow","Elapsed":0}
+{"Action":"fail","Package":"example.com/api","Elapsed":0.05}
+`
+
+const junitReport = `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites>
+  <testsuite name="web" tests="3">
+    <testcase name="renders" classname="web.Page"/>
+    <testcase name="submits" classname="web.Form">
+      <failure message="expected 200">status was 500</failure>
+    </testcase>
+    <testcase name="later" classname="web.Form"><skipped/></testcase>
+  </testsuite>
+</testsuites>
+`
+
+func TestCollectTestReports_GoTestOutput(t *testing.T) {
+	output := "building...\n" + goTestJSONStream + "done\n"
+
+	summary, err := CollectTestReports(t.TempDir(), nil, output)
+	require.NoError(t, err)
+	require.NotNil(t, summary)
+
+	assert.Equal(t, 3, summary.Total)
+	assert.Equal(t, 1, summary.Passed)
+	assert.Equal(t, 1, summary.Failed)
+	assert.Equal(t, 1, summary.Skipped)
+	require.Len(t, summary.Failures, 1)
+	assert.Equal(t, "example.com/api", summary.Failures[0].Package)
+	assert.Equal(t, "TestBroken", summary.Failures[0].Name)
+	assert.Contains(t, summary.Failures[0].Output, "expected 1, got 2")
+}
+
+func TestCollectTestReports_Files(t *testing.T) {
+	dir := t.TempDir()
+	writeReport(t, dir, "reports/junit.xml", junitReport)
+	writeReport(t, dir, "reports/go/test.json", goTestJSONStream)
+	writeReport(t, dir, "reports/ignored.txt", "not a report")
+	writeReport(t, dir, "node_modules/pkg/junit.xml", junitReport)
+
+	summary, err := CollectTestReports(dir, []string{"**/*.xml", "reports/**/*.json"}, "")
+	require.NoError(t, err)
+	require.NotNil(t, summary)
+
+	assert.Equal(t, 6, summary.Total)
+	assert.Equal(t, 2, summary.Passed)
+	assert.Equal(t, 2, summary.Failed)
+	assert.Equal(t, 2, summary.Skipped)
+	require.Len(t, summary.Failures, 2)
+	assert.Equal(t, "TestBroken", summary.Failures[0].Name)
+	assert.Equal(t, "web.Form", summary.Failures[1].Package)
+	assert.Equal(t, "submits", summary.Failures[1].Name)
+	assert.Equal(t, "expected 200\nstatus was 500", summary.Failures[1].Output)
+}
+
+func TestCollectTestReports_SameTestsInOutputAndFiles(t *testing.T) {
+	dir := t.TempDir()
+	// go test -json | tee test.json and its go-junit-report conversion describe the run in the output
+	writeReport(t, dir, "test.json", goTestJSONStream)
+	writeReport(t, dir, "junit.xml", `<testsuites><testsuite name="example.com/api">
+  <testcase name="TestOK" classname="example.com/api"/>
+  <testcase name="TestBroken" classname="example.com/api"><failure message="failed"/></testcase>
+  <testcase name="TestSlow" classname="example.com/api"><skipped/></testcase>
+</testsuite></testsuites>`)
+
+	summary, err := CollectTestReports(dir, []string{"*.json", "*.xml"}, goTestJSONStream)
+	require.NoError(t, err)
+	require.NotNil(t, summary)
+
+	assert.Equal(t, TestSummary{Total: 3, Passed: 1, Failed: 1, Skipped: 1, Failures: summary.Failures}, *summary)
+	require.Len(t, summary.Failures, 1)
+	assert.Equal(t, "TestBroken", summary.Failures[0].Name)
+}
+
+func TestCollectTestReports_SingleSuiteRoot(t *testing.T) {
+	dir := t.TempDir()
+	writeReport(t, dir, "junit.xml", `<testsuite name="pkg"><testcase name="a"/><testcase name="b"><error message="panic"/></testcase></testsuite>`)
+
+	summary, err := CollectTestReports(dir, []string{"*.xml"}, "")
+	require.NoError(t, err)
+	require.NotNil(t, summary)
+	assert.Equal(t, 2, summary.Total)
+	require.Len(t, summary.Failures, 1)
+	assert.Equal(t, "pkg", summary.Failures[0].Package)
+	assert.Equal(t, "panic", summary.Failures[0].Output)
+}
+
+func TestCollectTestReports_InvalidReport(t *testing.T) {
+	dir := t.TempDir()
+	writeReport(t, dir, "broken.xml", "<testsuites><testsuite>")
+	writeReport(t, dir, "ok.xml", junitReport)
+
+	summary, err := CollectTestReports(dir, []string{"*.xml"}, "")
+	assert.ErrorContains(t, err, "broken.xml")
+	require.NotNil(t, summary)
+	assert.Equal(t, 3, summary.Total)
+}
+
+func TestCollectTestReports_NoTests(t *testing.T) {
+	summary, err := CollectTestReports(t.TempDir(), []string{"*.xml"}, "plain output")
+	assert.NoError(t, err)
+	assert.Nil(t, summary)
+}
+
+func TestCollectTestReports_LimitsFailures(t *testing.T) {
+	var b strings.Builder
+	for i := 0; i < MaxReportedFailures+5; i++ {
+		b.WriteString(`{"Action":"fail","Package":"p","Test":"TestF` + string(rune('a'+i)) + `"}` + "\n")
+	}
+
+	summary, err := CollectTestReports(t.TempDir(), nil, b.String())
+	require.NoError(t, err)
+	assert.Equal(t, MaxReportedFailures+5, summary.Failed)
+	assert.Len(t, summary.Failures, MaxReportedFailures)
+}
+
+func writeReport(t *testing.T, dir, name, content string) {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+}
@@ -0,0 +1,143 @@
+package engineci
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/containifyci/temporal-worker/pkg/artifacts"
+
+	"go.temporal.io/sdk/activity"
+)
+
+// ResultCacheTTL is how long a successful result is reused for the same commit and arguments
+var ResultCacheTTL = 24 * time.Hour
+
+// resultPrefix is the part of the artifact store holding the results of successful jobs, all workers of a
+// queue share it when the store is shared
+const resultPrefix = "engine-ci-results"
+
+// cachedResult is the stored representation of a cached job result
+type cachedResult struct {
+	StoredAt time.Time
+	Details  EngineCIDetails
+}
+
+// ResultCacheKey derives the result cache key of a job for the given commit
+// It covers the repository, commit, runner, arguments, required labels, a fingerprint of the environment and the
+// path filter, reports and artifacts the result has to contain
+func ResultCacheKey(job EngineCIWorkflowInput, commitSHA string) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "repo=%s\n", strings.TrimSuffix(strings.ToLower(strings.TrimRight(job.GitRepoURL, "/")), ".git"))
+	fmt.Fprintf(h, "commit=%s\n", commitSHA)
+	if job.Runner.Kind != "" && job.Runner.Kind != RunnerEngineCI {
+		fmt.Fprintf(h, "runner=%s command=%q\n", job.Runner.Kind, job.Runner.Command)
+	}
+	fmt.Fprintf(h, "args=%q\n", job.EngineArgs)
+	fmt.Fprintf(h, "env=%s\n", EnvFingerprint(job.Env))
+	if labels := NormalizeLabels(job.Labels); len(labels) > 0 {
+		fmt.Fprintf(h, "labels=%q\n", labels)
+	}
+	if !job.Paths.IsEmpty() {
+		fmt.Fprintf(h, "paths=%q exclude=%q\n", job.Paths.Include, job.Paths.Exclude)
+	}
+	if len(job.Reports) > 0 {
+		fmt.Fprintf(h, "reports=%q\n", job.Reports)
+	}
+	if len(job.Artifacts) > 0 {
+		fmt.Fprintf(h, "artifacts=%q\n", job.Artifacts)
+	}
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
+
+// EnvFingerprint hashes an environment map independent of its iteration order
+func EnvFingerprint(env map[string]string) string {
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	h := sha256.New()
+	for _, k := range keys {
+		fmt.Fprintf(h, "%s=%s\x00", k, env[k])
+	}
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
+
+// JobResultLookup is the outcome of a result cache lookup
+type JobResultLookup struct {
+	Found   bool
+	Details EngineCIDetails
+}
+
+// LookupJobResult returns the cached successful result for the key from the artifact store, Found is false
+// when there is none or it is older than ResultCacheTTL
+func LookupJobResult(ctx context.Context, key string) (JobResultLookup, error) {
+	logger := activity.GetLogger(ctx)
+
+	store, err := NewArtifactStore()
+	if err != nil {
+		return JobResultLookup{}, fmt.Errorf("failed to open artifact store: %w", err)
+	}
+	r, err := store.Get(ctx, resultKey(key))
+	if errors.Is(err, artifacts.ErrNotFound) {
+		logger.Info("Result cache miss", "key", key)
+		return JobResultLookup{}, nil
+	}
+	if err != nil {
+		return JobResultLookup{}, fmt.Errorf("failed to read cached result: %w", err)
+	}
+	defer func() { _ = r.Close() }()
+
+	var cached cachedResult
+	if err := json.NewDecoder(r).Decode(&cached); err != nil {
+		logger.Warn("Ignoring unreadable cached result", "key", key, "error", err)
+		return JobResultLookup{}, nil
+	}
+
+	if time.Since(cached.StoredAt) > ResultCacheTTL {
+		logger.Info("Result cache entry expired", "key", key, "storedAt", cached.StoredAt)
+		_ = store.Delete(ctx, resultKey(key))
+		return JobResultLookup{}, nil
+	}
+
+	logger.Info("Result cache hit", "key", key, "storedAt", cached.StoredAt)
+	return JobResultLookup{Found: true, Details: cached.Details}, nil
+}
+
+// StoreJobResult caches a successful job result under the key in the artifact store and prunes expired entries
+func StoreJobResult(ctx context.Context, key string, details EngineCIDetails) error {
+	logger := activity.GetLogger(ctx)
+
+	store, err := NewArtifactStore()
+	if err != nil {
+		return fmt.Errorf("failed to open artifact store: %w", err)
+	}
+	now := time.Now()
+	data, err := json.Marshal(cachedResult{StoredAt: now, Details: details})
+	if err != nil {
+		return fmt.Errorf("failed to encode result: %w", err)
+	}
+	if err := store.Put(ctx, resultKey(key), bytes.NewReader(data), int64(len(data))); err != nil {
+		return fmt.Errorf("failed to write cached result: %w", err)
+	}
+
+	pruned, err := artifacts.Prune(ctx, store, resultPrefix+"/", ResultCacheTTL, now)
+	if err != nil {
+		logger.Warn("Result cache pruning failed (non-critical)", "error", err)
+	}
+	logger.Info("Result cached", "key", key, "pruned", len(pruned))
+	return nil
+}
+
+func resultKey(key string) string {
+	return path.Join(resultPrefix, key+".json")
+}
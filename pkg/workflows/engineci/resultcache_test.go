@@ -0,0 +1,104 @@
+package engineci
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/containifyci/temporal-worker/pkg/artifacts"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/testsuite"
+)
+
+func setupResultCache(t *testing.T) (*testsuite.TestActivityEnvironment, *artifacts.FilesystemStore) {
+	oldTTL := ResultCacheTTL
+	t.Cleanup(func() { ResultCacheTTL = oldTTL })
+	store := useArtifactStore(t)
+
+	env := (&testsuite.WorkflowTestSuite{}).NewTestActivityEnvironment()
+	env.RegisterActivity(LookupJobResult)
+	env.RegisterActivity(StoreJobResult)
+	return env, store
+}
+
+func lookup(t *testing.T, env *testsuite.TestActivityEnvironment, key string) JobResultLookup {
+	val, err := env.ExecuteActivity(LookupJobResult, key)
+	require.NoError(t, err)
+
+	var result JobResultLookup
+	require.NoError(t, val.Get(&result))
+	return result
+}
+
+func TestResultCacheKey(t *testing.T) {
+	job := EngineCIWorkflowInput{
+		GitRepoURL: "https://github.com/test/repo",
+		EngineArgs: []string{"run", "-t", "all"},
+		Env:        map[string]string{"A": "1", "B": "2"},
+	}
+	key := ResultCacheKey(job, "abc")
+
+	// Equivalent URLs share the key
+	same := job
+	same.GitRepoURL = "https://github.com/test/Repo.git"
+	assert.Equal(t, key, ResultCacheKey(same, "abc"))
+
+	assert.NotEqual(t, key, ResultCacheKey(job, "def"))
+
+	otherArgs := job
+	otherArgs.EngineArgs = []string{"run", "-t", "test"}
+	assert.NotEqual(t, key, ResultCacheKey(otherArgs, "abc"))
+
+	otherEnv := job
+	otherEnv.Env = map[string]string{"A": "1", "B": "3"}
+	assert.NotEqual(t, key, ResultCacheKey(otherEnv, "abc"))
+
+	otherLabels := job
+	otherLabels.Labels = []string{"arm64"}
+	assert.NotEqual(t, key, ResultCacheKey(otherLabels, "abc"))
+
+	// A result without the requested reports, artifacts or path filter is not reused
+	withReports := job
+	withReports.Reports = []string{"reports/*.xml"}
+	assert.NotEqual(t, key, ResultCacheKey(withReports, "abc"))
+
+	withArtifacts := job
+	withArtifacts.Artifacts = []string{"dist/**"}
+	assert.NotEqual(t, key, ResultCacheKey(withArtifacts, "abc"))
+
+	withPaths := job
+	withPaths.Paths = PathFilter{Include: []string{"cmd/**"}}
+	assert.NotEqual(t, key, ResultCacheKey(withPaths, "abc"))
+}
+
+func TestJobResultCache(t *testing.T) {
+	env, _ := setupResultCache(t)
+
+	assert.False(t, lookup(t, env, "key").Found)
+
+	details := EngineCIDetails{ExitCode: 0, Last50Lines: "ok", CommitSHA: "abc", Attempts: 1}
+	_, err := env.ExecuteActivity(StoreJobResult, "key", details)
+	require.NoError(t, err)
+
+	assert.Equal(t, JobResultLookup{Found: true, Details: details}, lookup(t, env, "key"))
+
+	// Another worker sharing the artifact store finds the result
+	other := (&testsuite.WorkflowTestSuite{}).NewTestActivityEnvironment()
+	other.RegisterActivity(LookupJobResult)
+	assert.Equal(t, JobResultLookup{Found: true, Details: details}, lookup(t, other, "key"))
+}
+
+func TestJobResultCache_Expired(t *testing.T) {
+	env, store := setupResultCache(t)
+
+	_, err := env.ExecuteActivity(StoreJobResult, "key", EngineCIDetails{CommitSHA: "abc"})
+	require.NoError(t, err)
+
+	ResultCacheTTL = -1 * time.Second
+	assert.False(t, lookup(t, env, "key").Found)
+
+	_, err = store.Get(context.Background(), "engine-ci-results/key.json")
+	assert.ErrorIs(t, err, artifacts.ErrNotFound)
+}
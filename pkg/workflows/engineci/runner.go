@@ -0,0 +1,156 @@
+package engineci
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"go.temporal.io/sdk/temporal"
+)
+
+// RunnerKind selects how a job is executed
+type RunnerKind string
+
+const (
+	// RunnerEngineCI runs the engine-ci binary with the job arguments, it is the default
+	RunnerEngineCI RunnerKind = "engine-ci"
+	// RunnerCommand runs RunnerSpec.Command with the job arguments, the command must be allowlisted
+	RunnerCommand RunnerKind = "command"
+	// RunnerGoTest runs `go test -json` with the job arguments, `./...` when there are none
+	RunnerGoTest RunnerKind = "go-test"
+)
+
+// invalidRunnerErrorType is the application error type of jobs whose runner is rejected by the worker
+const invalidRunnerErrorType = "InvalidRunner"
+
+// AllowedCommands lists the commands the command runner may execute, either a binary name looked
+// up in PATH (e.g. `make`) or a path relative to the workspace (e.g. `./scripts/ci.sh`)
+// Set from the comma separated ENGINE_CI_ALLOWED_COMMANDS, the command runner is disabled when empty
+var AllowedCommands = allowedCommandsFromEnv()
+
+func allowedCommandsFromEnv() []string {
+	var commands []string
+	for _, command := range strings.Split(os.Getenv("ENGINE_CI_ALLOWED_COMMANDS"), ",") {
+		if command = strings.TrimSpace(command); command != "" {
+			commands = append(commands, command)
+		}
+	}
+	return commands
+}
+
+// Runner builds the command line of a job, the execution itself (workspace, environment, caches,
+// logging and classification) is shared by all runners
+type Runner interface {
+	// Name is used to prefix the streamed output
+	Name() string
+	// Command returns the binary and arguments to execute
+	Command(spec RunnerSpec, args []string) (string, []string, error)
+}
+
+// runners holds the available runners by kind
+var runners = map[RunnerKind]Runner{
+	RunnerEngineCI: engineCIRunner{},
+	RunnerCommand:  commandRunner{},
+	RunnerGoTest:   goTestRunner{},
+}
+
+// lookupRunner returns the runner of a spec, an empty kind selects engine-ci
+func lookupRunner(spec RunnerSpec) (Runner, error) {
+	kind := spec.Kind
+	if kind == "" {
+		kind = RunnerEngineCI
+	}
+	runner, ok := runners[kind]
+	if !ok {
+		return nil, fmt.Errorf("unknown runner %q", spec.Kind)
+	}
+	return runner, nil
+}
+
+// resolveCommand returns the command line of a job or a non-retryable error when the runner is rejected
+func resolveCommand(spec RunnerSpec, args []string) (Runner, string, []string, error) {
+	runner, err := lookupRunner(spec)
+	if err == nil {
+		var name string
+		var cmdArgs []string
+		if name, cmdArgs, err = runner.Command(spec, args); err == nil {
+			return runner, name, cmdArgs, nil
+		}
+	}
+	return nil, "", nil, temporal.NewNonRetryableApplicationError(err.Error(), invalidRunnerErrorType, err)
+}
+
+type engineCIRunner struct{}
+
+func (engineCIRunner) Name() string { return "engine-ci" }
+
+func (engineCIRunner) Command(_ RunnerSpec, args []string) (string, []string, error) {
+	if len(args) == 0 {
+		return "", nil, fmt.Errorf("engine-ci arguments must not be empty")
+	}
+	return "engine-ci", args, nil
+}
+
+type commandRunner struct{}
+
+func (commandRunner) Name() string { return "command" }
+
+func (commandRunner) Command(spec RunnerSpec, args []string) (string, []string, error) {
+	if spec.Command == "" {
+		return "", nil, fmt.Errorf("command runner requires a command")
+	}
+	if !isAllowedCommand(spec.Command) {
+		return "", nil, fmt.Errorf("command %q is not allowed on this worker", spec.Command)
+	}
+	return spec.Command, args, nil
+}
+
+func isAllowedCommand(command string) bool {
+	for _, allowed := range AllowedCommands {
+		if allowed == command {
+			return true
+		}
+	}
+	return false
+}
+
+// forbiddenGoTestFlags make `go test` run other binaries, they would bypass AllowedCommands
+var forbiddenGoTestFlags = []string{"exec", "toolexec"}
+
+type goTestRunner struct{}
+
+func (goTestRunner) Name() string { return "go-test" }
+
+func (goTestRunner) Command(_ RunnerSpec, args []string) (string, []string, error) {
+	for i, arg := range args {
+		// Arguments after -args go to the test binary
+		if arg == "-args" || arg == "--args" {
+			break
+		}
+		if !strings.HasPrefix(arg, "-") {
+			continue
+		}
+		flag, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if slices.Contains(forbiddenGoTestFlags, flag) {
+			return "", nil, fmt.Errorf("go test flag -%s is not allowed", flag)
+		}
+		// -vet=off only skips vet, a list of analyzers is not allowed
+		if flag == "vet" {
+			if !hasValue && i+1 < len(args) {
+				value = args[i+1]
+			}
+			if value != "off" {
+				return "", nil, fmt.Errorf("go test flag -vet=%s is not allowed, only -vet=off", value)
+			}
+		}
+	}
+	if len(args) == 0 {
+		args = []string{"./..."}
+	}
+	// The JSON events are parsed into the test report of the job
+	if !slices.Contains(args, "-json") && !slices.Contains(args, "--json") {
+		args = append([]string{"-json"}, args...)
+	}
+	return "go", append([]string{"test"}, args...), nil
+}
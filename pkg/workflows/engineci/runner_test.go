@@ -0,0 +1,133 @@
+package engineci
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveCommand(t *testing.T) {
+	defer func(orig []string) { AllowedCommands = orig }(AllowedCommands)
+	AllowedCommands = []string{"make", "./scripts/ci.sh"}
+
+	tests := []struct {
+		name         string
+		spec         RunnerSpec
+		args         []string
+		expectedName string
+		expectedArgs []string
+		expectedErr  string
+	}{
+		{
+			name:         "Default runner is engine-ci",
+			args:         []string{"run", "-t", "all"},
+			expectedName: "engine-ci",
+			expectedArgs: []string{"run", "-t", "all"},
+		},
+		{
+			name:        "engine-ci requires arguments",
+			spec:        RunnerSpec{Kind: RunnerEngineCI},
+			expectedErr: "engine-ci arguments must not be empty",
+		},
+		{
+			name:         "Go test defaults to all packages",
+			spec:         RunnerSpec{Kind: RunnerGoTest},
+			expectedName: "go",
+			expectedArgs: []string{"test", "-json", "./..."},
+		},
+		{
+			name:         "Go test with arguments",
+			spec:         RunnerSpec{Kind: RunnerGoTest},
+			args:         []string{"-race", "./pkg/..."},
+			expectedName: "go",
+			expectedArgs: []string{"test", "-json", "-race", "./pkg/..."},
+		},
+		{
+			name:        "Go test running another binary",
+			spec:        RunnerSpec{Kind: RunnerGoTest},
+			args:        []string{"-exec", "/bin/sh", "./..."},
+			expectedErr: "go test flag -exec is not allowed",
+		},
+		{
+			name:        "Go test with a tool wrapper",
+			spec:        RunnerSpec{Kind: RunnerGoTest},
+			args:        []string{"--toolexec=./evil", "./..."},
+			expectedErr: "go test flag -toolexec is not allowed",
+		},
+		{
+			name:         "Go test without vet",
+			spec:         RunnerSpec{Kind: RunnerGoTest},
+			args:         []string{"-vet=off", "./..."},
+			expectedName: "go",
+			expectedArgs: []string{"test", "-json", "-vet=off", "./..."},
+		},
+		{
+			name:        "Go test with other analyzers",
+			spec:        RunnerSpec{Kind: RunnerGoTest},
+			args:        []string{"-vet=atomic,printf"},
+			expectedErr: "go test flag -vet=atomic,printf is not allowed",
+		},
+		{
+			name:        "Go test with analyzers as separate argument",
+			spec:        RunnerSpec{Kind: RunnerGoTest},
+			args:        []string{"-vet", "all", "./..."},
+			expectedErr: "go test flag -vet=all is not allowed",
+		},
+		{
+			name:         "Go test passes test binary flags through",
+			spec:         RunnerSpec{Kind: RunnerGoTest},
+			args:         []string{"./...", "-args", "-exec=fixture"},
+			expectedName: "go",
+			expectedArgs: []string{"test", "-json", "./...", "-args", "-exec=fixture"},
+		},
+		{
+			name:         "Allowed command",
+			spec:         RunnerSpec{Kind: RunnerCommand, Command: "make"},
+			args:         []string{"test"},
+			expectedName: "make",
+			expectedArgs: []string{"test"},
+		},
+		{
+			name:         "Allowed script",
+			spec:         RunnerSpec{Kind: RunnerCommand, Command: "./scripts/ci.sh"},
+			expectedName: "./scripts/ci.sh",
+		},
+		{
+			name:        "Command not allowed",
+			spec:        RunnerSpec{Kind: RunnerCommand, Command: "curl"},
+			expectedErr: `command "curl" is not allowed on this worker`,
+		},
+		{
+			name:        "Command runner without command",
+			spec:        RunnerSpec{Kind: RunnerCommand},
+			expectedErr: "command runner requires a command",
+		},
+		{
+			name:        "Unknown runner",
+			spec:        RunnerSpec{Kind: "bazel"},
+			expectedErr: `unknown runner "bazel"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, name, args, err := resolveCommand(tt.spec, tt.args)
+			if tt.expectedErr != "" {
+				assert.ErrorContains(t, err, tt.expectedErr)
+				assert.Equal(t, FailureClassInvalid, ClassifyError(err))
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedName, name)
+			assert.Equal(t, tt.expectedArgs, args)
+		})
+	}
+}
+
+func TestResolveCommand_NoAllowlist(t *testing.T) {
+	defer func(orig []string) { AllowedCommands = orig }(AllowedCommands)
+	AllowedCommands = nil
+
+	_, _, _, err := resolveCommand(RunnerSpec{Kind: RunnerCommand, Command: "make"}, nil)
+	assert.ErrorContains(t, err, `command "make" is not allowed on this worker`)
+}
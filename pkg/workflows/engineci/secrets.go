@@ -0,0 +1,339 @@
+package engineci
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.temporal.io/sdk/temporal"
+	"gopkg.in/yaml.v3"
+)
+
+// SecretRefPrefix marks job environment values that reference a secret, e.g. `secret://NPM_TOKEN`
+// Only the reference is stored in the workflow history, RunEngineCIJob resolves it on the worker
+const SecretRefPrefix = "secret://"
+
+// Secret providers selected with ENGINE_CI_SECRETS_PROVIDER
+const (
+	// SecretProviderEnv reads secret NAME from the worker's ENGINE_CI_SECRET_NAME variable
+	SecretProviderEnv = "env"
+	// SecretProviderDotenv reads secrets from the dotenv file ENGINE_CI_SECRETS_FILE (default `.env`)
+	SecretProviderDotenv = "dotenv"
+	// SecretProviderTeller reads secrets from the dotenv maps of the teller config ENGINE_CI_SECRETS_FILE (default `.teller.yml`)
+	SecretProviderTeller = "teller"
+)
+
+// secretEnvPrefix prefixes the worker variables of the env provider, other worker variables are never exposed
+const secretEnvPrefix = "ENGINE_CI_SECRET_"
+
+// secretNotFoundErrorType is the application error type of jobs referencing secrets the worker doesn't have
+const secretNotFoundErrorType = "SecretNotFound"
+
+// minRedactLength is the shortest secret value that is redacted, shorter values would mangle the output
+const minRedactLength = 4
+
+// redactedValue replaces secret values in the job output
+const redactedValue = "***"
+
+// ErrSecretNotFound is returned by a SecretProvider that doesn't have the requested secret
+var ErrSecretNotFound = errors.New("secret not found")
+
+var secretNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// SecretProvider looks up the secrets referenced by a job's environment
+type SecretProvider interface {
+	// Secret returns the value of the named secret, ErrSecretNotFound when the provider doesn't have it
+	Secret(name string) (string, error)
+}
+
+// Secrets resolves secret references in RunEngineCIJob
+// Set from ENGINE_CI_SECRETS_PROVIDER (env, dotenv or teller) and ENGINE_CI_SECRETS_FILE
+var Secrets = secretProviderFromEnv()
+
+func secretProviderFromEnv() SecretProvider {
+	provider, err := NewSecretProvider(os.Getenv("ENGINE_CI_SECRETS_PROVIDER"), os.Getenv("ENGINE_CI_SECRETS_FILE"))
+	if err != nil {
+		return failingSecretProvider{err: err}
+	}
+	return provider
+}
+
+// NewSecretProvider returns the provider of the given kind, the env provider when kind is empty
+// File based providers read their file on every lookup so rotated secrets are picked up without a restart
+func NewSecretProvider(kind, path string) (SecretProvider, error) {
+	switch kind {
+	case "", SecretProviderEnv:
+		return envSecretProvider{}, nil
+	case SecretProviderDotenv:
+		if path == "" {
+			path = ".env"
+		}
+		return dotenvSecretProvider{path: path}, nil
+	case SecretProviderTeller:
+		if path == "" {
+			path = ".teller.yml"
+		}
+		return tellerSecretProvider{path: path}, nil
+	default:
+		return nil, fmt.Errorf("unknown secret provider %q", kind)
+	}
+}
+
+// SecretRef returns the secret name referenced by an environment value
+func SecretRef(value string) (string, bool) {
+	return strings.CutPrefix(value, SecretRefPrefix)
+}
+
+func validateSecretRef(key, value string) error {
+	name, ok := SecretRef(value)
+	if !ok || secretNamePattern.MatchString(name) {
+		return nil
+	}
+	return fmt.Errorf("environment variable %q references invalid secret name %q", key, name)
+}
+
+type envSecretProvider struct{}
+
+func (envSecretProvider) Secret(name string) (string, error) {
+	value, ok := os.LookupEnv(secretEnvPrefix + name)
+	if !ok {
+		return "", ErrSecretNotFound
+	}
+	return value, nil
+}
+
+type dotenvSecretProvider struct {
+	path string
+}
+
+func (p dotenvSecretProvider) Secret(name string) (string, error) {
+	values, err := readDotenv(p.path)
+	if err != nil {
+		return "", err
+	}
+	value, ok := values[name]
+	if !ok {
+		return "", ErrSecretNotFound
+	}
+	return value, nil
+}
+
+// tellerConfig is the subset of the teller config layout the worker understands
+type tellerConfig struct {
+	Providers map[string]struct {
+		Kind string `yaml:"kind"`
+		Maps []struct {
+			ID   string            `yaml:"id"`
+			Path string            `yaml:"path"`
+			Keys map[string]string `yaml:"keys"` // key in the store -> exposed name, all keys when empty
+		} `yaml:"maps"`
+	} `yaml:"providers"`
+}
+
+// tellerSecretProvider serves the secrets of the teller config's dotenv providers
+// Map paths are relative to the config file, keys of other provider kinds are reported as unavailable
+type tellerSecretProvider struct {
+	path string
+}
+
+func (p tellerSecretProvider) Secret(name string) (string, error) {
+	data, err := os.ReadFile(p.path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read teller config: %w", err)
+	}
+	var config tellerConfig
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return "", fmt.Errorf("failed to parse teller config %s: %w", p.path, err)
+	}
+
+	providers := make([]string, 0, len(config.Providers))
+	for provider := range config.Providers {
+		providers = append(providers, provider)
+	}
+	sort.Strings(providers)
+
+	var unsupported []string
+	for _, provider := range providers {
+		cfg := config.Providers[provider]
+		for _, m := range cfg.Maps {
+			source, mapped := tellerSource(m.Keys, name)
+			if !mapped {
+				continue
+			}
+			if cfg.Kind != SecretProviderDotenv {
+				unsupported = append(unsupported, fmt.Sprintf("%s (%s)", provider, cfg.Kind))
+				continue
+			}
+			path := m.Path
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(filepath.Dir(p.path), path)
+			}
+			values, err := readDotenv(path)
+			if err != nil {
+				return "", fmt.Errorf("teller provider %s: %w", provider, err)
+			}
+			if value, ok := values[source]; ok {
+				return value, nil
+			}
+		}
+	}
+	if len(unsupported) > 0 {
+		return "", fmt.Errorf("%w, only provided by %s which the worker cannot read", ErrSecretNotFound, strings.Join(unsupported, ", "))
+	}
+	return "", ErrSecretNotFound
+}
+
+// tellerSource returns the store key exposed as name, maps without keys expose everything under its own name
+func tellerSource(keys map[string]string, name string) (string, bool) {
+	if len(keys) == 0 {
+		return name, true
+	}
+	for source, target := range keys {
+		if target == name {
+			return source, true
+		}
+	}
+	return "", false
+}
+
+type failingSecretProvider struct {
+	err error
+}
+
+func (p failingSecretProvider) Secret(string) (string, error) {
+	return "", p.err
+}
+
+// readDotenv parses KEY=VALUE lines, with optional `export`, comments and single or double quoted values
+func readDotenv(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read secrets file: %w", err)
+	}
+	values := map[string]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("%s:%d: expected KEY=VALUE", path, n)
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		switch {
+		case strings.HasPrefix(value, `"`):
+			if value, err = strconv.Unquote(value); err != nil {
+				return nil, fmt.Errorf("%s:%d: invalid quoted value for %s", path, n, key)
+			}
+		case strings.HasPrefix(value, "'") && strings.HasSuffix(value, "'") && len(value) > 1:
+			value = value[1 : len(value)-1]
+		default:
+			if i := strings.Index(value, " #"); i >= 0 {
+				value = strings.TrimSpace(value[:i])
+			}
+		}
+		values[key] = value
+	}
+	return values, scanner.Err()
+}
+
+// resolveSecrets replaces the secret references of env with their values
+// It returns a redactor for the resolved values, references that can't be resolved fail the job without retry
+func resolveSecrets(provider SecretProvider, env map[string]string) (map[string]string, *redactor, error) {
+	keys := make([]string, 0, len(env))
+	for key := range env {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	resolved := make(map[string]string, len(env))
+	var values, missing []string
+	for _, key := range keys {
+		name, ok := SecretRef(env[key])
+		if !ok {
+			resolved[key] = env[key]
+			continue
+		}
+		value, err := provider.Secret(name)
+		if errors.Is(err, ErrSecretNotFound) {
+			if err != ErrSecretNotFound {
+				name = fmt.Sprintf("%s (%v)", name, err)
+			}
+			missing = append(missing, name)
+			continue
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to resolve secret %s: %w", name, err)
+		}
+		resolved[key] = value
+		values = append(values, value)
+	}
+	if len(missing) > 0 {
+		err := fmt.Errorf("unresolved secrets: %s", strings.Join(missing, ", "))
+		return nil, nil, temporal.NewNonRetryableApplicationError(err.Error(), secretNotFoundErrorType, err)
+	}
+	return resolved, newRedactor(values), nil
+}
+
+// redactor masks secret values in the job output
+type redactor struct {
+	replacer *strings.Replacer
+}
+
+// newRedactor returns nil when there is nothing to redact
+// Multi-line values are also redacted line by line, the output is logged in lines
+func newRedactor(values []string) *redactor {
+	seen := map[string]bool{}
+	var terms []string
+	add := func(term string) {
+		if len(term) >= minRedactLength && !seen[term] {
+			seen[term] = true
+			terms = append(terms, term)
+		}
+	}
+	for _, value := range values {
+		add(value)
+		for _, line := range strings.Split(value, "\n") {
+			add(strings.TrimSpace(line))
+		}
+	}
+	if len(terms) == 0 {
+		return nil
+	}
+	// The replacer prefers earlier terms, longer ones must win over their substrings
+	sort.SliceStable(terms, func(i, j int) bool { return len(terms[i]) > len(terms[j]) })
+	pairs := make([]string, 0, 2*len(terms))
+	for _, term := range terms {
+		pairs = append(pairs, term, redactedValue)
+	}
+	return &redactor{replacer: strings.NewReplacer(pairs...)}
+}
+
+// Redact masks the secret values in s
+func (r *redactor) Redact(s string) string {
+	if r == nil {
+		return s
+	}
+	return r.replacer.Replace(s)
+}
+
+// redactTests masks secret values in the reported test failures
+func (r *redactor) redactTests(summary *TestSummary) {
+	if r == nil || summary == nil {
+		return
+	}
+	for i := range summary.Failures {
+		summary.Failures[i].Name = r.Redact(summary.Failures[i].Name)
+		summary.Failures[i].Output = r.Redact(summary.Failures[i].Output)
+	}
+}
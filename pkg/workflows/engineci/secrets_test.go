@@ -0,0 +1,156 @@
+package engineci
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/testsuite"
+)
+
+// staticSecrets serves secrets from a map
+type staticSecrets map[string]string
+
+func (s staticSecrets) Secret(name string) (string, error) {
+	value, ok := s[name]
+	if !ok {
+		return "", ErrSecretNotFound
+	}
+	return value, nil
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+}
+
+func TestSecretProviders(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, ".env"), `# local secrets
+export NPM_TOKEN=npm_123 # inline comment
+QUOTED="line1\nline2"
+SINGLE='a b#c'
+`)
+	writeFile(t, filepath.Join(dir, ".teller.yml"), `providers:
+  gsm:
+    kind: google_secretmanager
+    maps:
+    - id: shared
+      path: projects/test
+      keys:
+        DUNEBOT_GITHUB_TOKEN: GITHUB_TOKEN
+  dotenv:
+    kind: dotenv
+    maps:
+      - id: local
+        path: .env
+  renamed:
+    kind: dotenv
+    maps:
+      - id: renamed
+        path: .env
+        keys:
+          NPM_TOKEN: REGISTRY_TOKEN
+`)
+	t.Setenv("ENGINE_CI_SECRET_DEPLOY_KEY", "deploy_456")
+
+	env, err := NewSecretProvider("", "")
+	require.NoError(t, err)
+	dotenv, err := NewSecretProvider(SecretProviderDotenv, filepath.Join(dir, ".env"))
+	require.NoError(t, err)
+	teller, err := NewSecretProvider(SecretProviderTeller, filepath.Join(dir, ".teller.yml"))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		provider SecretProvider
+		secret   string
+		expected string
+		err      string
+	}{
+		{name: "env", provider: env, secret: "DEPLOY_KEY", expected: "deploy_456"},
+		{name: "env without prefix", provider: env, secret: "PATH", err: "secret not found"},
+		{name: "dotenv export", provider: dotenv, secret: "NPM_TOKEN", expected: "npm_123"},
+		{name: "dotenv double quoted", provider: dotenv, secret: "QUOTED", expected: "line1\nline2"},
+		{name: "dotenv single quoted", provider: dotenv, secret: "SINGLE", expected: "a b#c"},
+		{name: "dotenv missing", provider: dotenv, secret: "OTHER", err: "secret not found"},
+		{name: "teller dotenv map", provider: teller, secret: "NPM_TOKEN", expected: "npm_123"},
+		{name: "teller renamed key", provider: teller, secret: "REGISTRY_TOKEN", expected: "npm_123"},
+		{name: "teller unsupported provider", provider: teller, secret: "GITHUB_TOKEN", err: "only provided by gsm (google_secretmanager)"},
+		{name: "teller missing", provider: teller, secret: "OTHER", err: "secret not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, err := tt.provider.Secret(tt.secret)
+			if tt.err != "" {
+				assert.ErrorContains(t, err, tt.err)
+				assert.True(t, errors.Is(err, ErrSecretNotFound))
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, value)
+		})
+	}
+
+	_, err = NewSecretProvider("vault", "")
+	assert.ErrorContains(t, err, `unknown secret provider "vault"`)
+}
+
+func TestResolveSecrets(t *testing.T) {
+	provider := staticSecrets{"NPM_TOKEN": "npm_123", "KEY": "-----BEGIN KEY-----\nabcdef\n-----END KEY-----"}
+
+	env, redact, err := resolveSecrets(provider, map[string]string{
+		"CI":        "true",
+		"NPM_TOKEN": "secret://NPM_TOKEN",
+		"SSH_KEY":   "secret://KEY",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"CI": "true", "NPM_TOKEN": "npm_123", "SSH_KEY": provider["KEY"]}, env)
+	assert.Equal(t, "token *** ok", redact.Redact("token npm_123 ok"))
+	assert.Equal(t, "got ***\nend", redact.Redact("got abcdef\nend"), "lines of multi-line secrets are redacted")
+
+	_, _, err = resolveSecrets(provider, map[string]string{"A": "secret://MISSING", "B": "secret://OTHER"})
+	var appErr *temporal.ApplicationError
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, secretNotFoundErrorType, appErr.Type())
+	assert.True(t, appErr.NonRetryable())
+	assert.ErrorContains(t, err, "unresolved secrets: MISSING, OTHER")
+
+	_, redact, err = resolveSecrets(provider, map[string]string{"CI": "true"})
+	require.NoError(t, err)
+	assert.Nil(t, redact)
+	assert.Equal(t, "npm_123", redact.Redact("npm_123"))
+}
+
+func TestRunEngineCI_ResolvesAndRedactsSecrets(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not found in PATH, skipping test")
+	}
+	defer func(orig []string) { AllowedCommands = orig }(AllowedCommands)
+	AllowedCommands = []string{"sh"}
+	defer func(orig SecretProvider) { Secrets = orig }(Secrets)
+	Secrets = staticSecrets{"NPM_TOKEN": "npm_secret_value"}
+
+	testSuite := &testsuite.WorkflowTestSuite{}
+	env := testSuite.NewTestActivityEnvironment()
+	env.RegisterActivity(RunEngineCIJob)
+
+	val, err := env.ExecuteActivity(RunEngineCIJob, RunEngineCIInput{
+		WorkDir: t.TempDir(),
+		Runner:  RunnerSpec{Kind: RunnerCommand, Command: "sh"},
+		Args:    []string{"-c", `printf 'token=%s' "$NPM_TOKEN"; test "$NPM_TOKEN" = npm_secret_value`},
+		Env:     map[string]string{"NPM_TOKEN": "secret://NPM_TOKEN"},
+	})
+	require.NoError(t, err)
+
+	var details *EngineCIDetails
+	require.NoError(t, val.Get(&details))
+	assert.Equal(t, 0, details.ExitCode, "the command sees the resolved value")
+	assert.Equal(t, "token=***", details.Last50Lines)
+}
@@ -0,0 +1,49 @@
+package engineci
+
+import (
+	"time"
+
+	"go.temporal.io/sdk/workflow"
+)
+
+// workerSettingsChange versions reading the worker settings through a recorded side effect
+const workerSettingsChange = "worker-settings"
+
+// workflowSettings are the settings of the worker that workflow code depends on
+type workflowSettings struct {
+	IdleTimeout                 time.Duration
+	MaxInfraRetries             int
+	InfraRetryBackoff           time.Duration
+	LabelScheduleToStartTimeout time.Duration
+	CallbackDeliveryTimeout     time.Duration
+}
+
+// currentSettings returns the settings of this worker
+func currentSettings() workflowSettings {
+	return workflowSettings{
+		IdleTimeout:                 IdleTimeout,
+		MaxInfraRetries:             MaxInfraRetries,
+		InfraRetryBackoff:           InfraRetryBackoff,
+		LabelScheduleToStartTimeout: LabelScheduleToStartTimeout,
+		CallbackDeliveryTimeout:     CallbackDeliveryTimeout,
+	}
+}
+
+// recordedSettings returns the worker settings as recorded in the history, a replay on a worker with a different
+// configuration sees the settings of the worker that ran the workflow. Changed settings are recorded again
+func recordedSettings(ctx workflow.Context) workflowSettings {
+	if workflow.GetVersion(ctx, workerSettingsChange, workflow.DefaultVersion, 1) == workflow.DefaultVersion {
+		return currentSettings()
+	}
+
+	var settings workflowSettings
+	err := workflow.MutableSideEffect(ctx, workerSettingsChange,
+		func(workflow.Context) any { return currentSettings() },
+		func(a, b any) bool { return a.(workflowSettings) == b.(workflowSettings) },
+	).Get(&settings)
+	if err != nil {
+		// Fails the workflow task, the recorded value is always a workflowSettings
+		panic(err)
+	}
+	return settings
+}
@@ -0,0 +1,37 @@
+package engineci
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/testsuite"
+	"go.temporal.io/sdk/workflow"
+)
+
+func TestRecordedSettings(t *testing.T) {
+	defer func(orig int) { MaxInfraRetries = orig }(MaxInfraRetries)
+	MaxInfraRetries = 2
+
+	// Reads the settings before and after the worker configuration changes
+	readTwice := func(ctx workflow.Context) ([]int, error) {
+		first := recordedSettings(ctx)
+		if err := workflow.Sleep(ctx, time.Minute); err != nil {
+			return nil, err
+		}
+		second := recordedSettings(ctx)
+		return []int{first.MaxInfraRetries, second.MaxInfraRetries}, nil
+	}
+
+	env := (&testsuite.WorkflowTestSuite{}).NewTestWorkflowEnvironment()
+	env.RegisterWorkflowWithOptions(readTwice, workflow.RegisterOptions{Name: "readTwice"})
+	env.RegisterDelayedCallback(func() { MaxInfraRetries = 5 }, 30*time.Second)
+	env.ExecuteWorkflow("readTwice")
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	var retries []int
+	require.NoError(t, env.GetWorkflowResult(&retries))
+	assert.Equal(t, []int{2, 5}, retries)
+}
@@ -1,16 +1,221 @@
 package engineci
 
+import "time"
+
 // EngineCIWorkflowInput contains all the information needed to run an Engine-CI job
 type EngineCIWorkflowInput struct {
+	JobID      string // assigned by the queue when empty, the job's workflow ID is derived from it
 	GitRepoURL string
 	GitRef     string
 	RepoName   string
-	EngineArgs []string
+	EngineArgs []string   // arguments of the runner
+	Runner     RunnerSpec // engine-ci when empty
 	Env        map[string]string
+	Cache      CacheSpec
+	Paths      PathFilter
+	BaseRef    string   // revision the changed files are computed against, path filtering is disabled when empty
+	Force      bool     // run even if a successful result for the same commit and arguments is cached
+	Reports    []string // globs of test report files (JUnit XML, `go test -json`) relative to the workspace
+	Artifacts  []string // globs of files uploaded to the artifact store before the workspace is removed
+	Workspace  string   // name of a separate clone directory next to the repository's default one
+	Labels     []string // capabilities the worker must advertise (e.g. `docker`, `arm64`, `mem-large`), any worker when empty
+
+	CallbackURL    string // receives a CallbackPayload once the job finished, failed or was canceled
+	CallbackSecret string // `secret://NAME` reference of the HMAC-SHA256 key signing callbacks, see CallbackSignatureHeader
+}
+
+// RunnerSpec selects the runner that executes a job
+type RunnerSpec struct {
+	Kind    RunnerKind
+	Command string // binary or script of the command runner, must be listed in AllowedCommands
+}
+
+// PathFilter selects the changed files that should trigger a job
+type PathFilter struct {
+	Include []string // glob patterns, all files are relevant when empty
+	Exclude []string // glob patterns removed from the relevant files
+}
+
+// CacheSpec declares the managed caches a job wants to reuse between runs
+// Caches are stored per key under CacheRoot, the key defaults to the repository
+type CacheSpec struct {
+	Key          string
+	GoModCache   bool     // exported as GOMODCACHE
+	GoBuildCache bool     // exported as GOCACHE
+	Directories  []string // named directories, exported as ENGINE_CI_CACHE_<NAME>
+}
+
+// CacheStatus reports whether a cache already had content when the job started
+type CacheStatus struct {
+	Name string
+	Hit  bool
+}
+
+// RunEngineCIInput contains the parameters for the RunEngineCIJob activity
+type RunEngineCIInput struct {
+	WorkDir string
+	Runner  RunnerSpec
+	Args    []string
+	Env     map[string]string
+	Cache   CacheSpec
+	Reports []string
+}
+
+// CollectArtifactsInput contains the parameters for the CollectArtifacts activity
+type CollectArtifactsInput struct {
+	WorkDir string
+	Globs   []string
+	Key     string // store key of the archive, see ArtifactKey
 }
 
+// ArtifactRef points at an artifact archive in the artifact store
+type ArtifactRef struct {
+	Key       string
+	Files     int
+	Size      int64
+	SHA256    string
+	ExpiresAt time.Time
+}
+
+// FailureClass describes why an Engine-CI job did not succeed
+type FailureClass string
+
+const (
+	// FailureClassNone is used for successful jobs
+	FailureClassNone FailureClass = ""
+	// FailureClassBuild means engine-ci ran and the code under test failed
+	FailureClassBuild FailureClass = "build"
+	// FailureClassInfrastructure means the runner failed (clone, network, docker daemon)
+	FailureClassInfrastructure FailureClass = "infrastructure"
+	// FailureClassTimeout means the job exceeded its activity timeout
+	FailureClassTimeout FailureClass = "timeout"
+	// FailureClassCanceled means the job was canceled before it finished
+	FailureClassCanceled FailureClass = "canceled"
+	// FailureClassInvalid means the worker rejected the job's runner or command or lacks a referenced secret
+	FailureClassInvalid FailureClass = "invalid"
+)
+
 // EngineCIDetails contains the results of an Engine-CI execution
 type EngineCIDetails struct {
-	ExitCode    int
-	Last50Lines string
+	ExitCode     int
+	Last50Lines  string
+	FailureClass FailureClass
+	Attempts     int
+	Caches       []CacheStatus
+	Skipped      bool
+	SkipReason   string
+	CommitSHA    string
+	Reused       bool         // result was taken from the result cache
+	Tests        *TestSummary // parsed test reports, nil when none were found
+	Artifacts    []ArtifactRef
+}
+
+// TestSummary summarises the test reports of a job
+type TestSummary struct {
+	Total    int
+	Passed   int
+	Failed   int
+	Skipped  int
+	Failures []TestFailure // at most MaxReportedFailures
+}
+
+// TestFailure describes a failing test
+type TestFailure struct {
+	Package string // Go package or JUnit class name
+	Name    string
+	Output  string // last lines of the test output
+}
+
+// EngineCISubmission is returned to the submitter of a job accepted by EngineCISubmitUpdate
+type EngineCISubmission struct {
+	JobID         string
+	WorkflowID    string // ID of the job's EngineCIJobWorkflow execution
+	QueuePosition int    // number of jobs that run before this one, 0 means it starts immediately
+}
+
+// PipelineInput describes a pipeline of Engine-CI stages for one repository and ref
+type PipelineInput struct {
+	PipelineID  string // defaults to the workflow ID
+	GitRepoURL  string
+	GitRef      string
+	BaseRef     string            // passed to every stage for path filtering
+	Env         map[string]string // shared by all stages, stage values take precedence
+	Stages      []PipelineStage
+	FailFast    bool // cancel running and pending stages after the first failure
+	MaxParallel int  // maximum number of stages running at the same time, unlimited when 0
+}
+
+// PipelineStage is a node of the pipeline DAG
+// The stage's job inherits repository, ref and base revision from the pipeline
+type PipelineStage struct {
+	Name            string
+	Needs           []string // stages that must finish before this one starts
+	When            StageCondition
+	ContinueOnError bool // a failure neither fails the pipeline nor blocks dependent stages
+	Job             EngineCIWorkflowInput
+}
+
+// StageCondition decides whether a stage runs once its needs have finished
+type StageCondition struct {
+	Refs []string     // glob patterns the ref must match (e.g. `main`, `release/*`), any ref when empty
+	If   StageTrigger // which results of the needed stages trigger the stage, StageTriggerSuccess when empty
+}
+
+// StageTrigger selects the needed stages' results that let a stage run
+type StageTrigger string
+
+const (
+	// StageTriggerSuccess runs the stage when all needed stages succeeded
+	StageTriggerSuccess StageTrigger = "success"
+	// StageTriggerFailure runs the stage when a needed stage failed, e.g. for notifications
+	StageTriggerFailure StageTrigger = "failure"
+	// StageTriggerAlways runs the stage regardless of the needed stages' results
+	StageTriggerAlways StageTrigger = "always"
+)
+
+// StageStatus is the state of a pipeline stage
+type StageStatus string
+
+const (
+	StageStatusPending   StageStatus = "pending"
+	StageStatusRunning   StageStatus = "running"
+	StageStatusSucceeded StageStatus = "succeeded"
+	StageStatusFailed    StageStatus = "failed"
+	StageStatusSkipped   StageStatus = "skipped"
+	StageStatusCanceled  StageStatus = "canceled"
+)
+
+// PipelineStatus is the overall state of a pipeline
+type PipelineStatus string
+
+const (
+	PipelineStatusRunning   PipelineStatus = "running"
+	PipelineStatusSucceeded PipelineStatus = "succeeded"
+	PipelineStatusFailed    PipelineStatus = "failed"
+	PipelineStatusCanceled  PipelineStatus = "canceled"
+)
+
+// StageResult is the status of a pipeline stage
+type StageResult struct {
+	Name       string
+	Status     StageStatus
+	Reason     string // why the stage was skipped or canceled
+	WorkflowID string // ID of the stage's EngineCIJobWorkflow execution
+	Details    *EngineCIDetails
+}
+
+// PipelineResult is the status of a pipeline and all of its stages
+type PipelineResult struct {
+	PipelineID string
+	Status     PipelineStatus
+	Stages     []StageResult // in the order of PipelineInput.Stages
+}
+
+// EngineCIJobResult records the outcome of a processed Engine-CI job
+type EngineCIJobResult struct {
+	JobID      string
+	WorkflowID string // ID of the job's EngineCIJobWorkflow execution
+	RepoName   string
+	GitRef     string
+	Details    EngineCIDetails
 }
@@ -1,6 +1,7 @@
 package engineci
 
 import (
+	"fmt"
 	"path/filepath"
 	"strings"
 )
@@ -28,7 +29,33 @@ func SanitizeRepoName(repoURL string) string {
 }
 
 // GetCloneDirectory returns the full path for the clone directory
+// Example: https://github.com/containifyci/temporal-worker -> /tmp/ci/github.com/containifyci/temporal-worker
 func GetCloneDirectory(repoURL string) string {
-	repoName := SanitizeRepoName(repoURL)
-	return filepath.Join("/tmp", "ci-"+repoName)
+	return ParseRepoIdentity(repoURL).Workspace()
+}
+
+// legacyCloneDirectory returns the name-only clone directory used by earlier releases
+func legacyCloneDirectory(repoURL string) string {
+	return filepath.Join("/tmp", "ci-"+SanitizeRepoName(repoURL))
+}
+
+// NewJobID derives a readable job ID from the repository, the queue workflow's run ID and
+// the position of the job within that run
+// Example: github.com/containifyci/temporal-worker/5f1c2a9b-3
+func NewJobID(repoURL, runID string, seq int) string {
+	return fmt.Sprintf("%s/%s-%d", ParseRepoIdentity(repoURL), shortRunID(runID), seq)
+}
+
+func shortRunID(runID string) string {
+	if len(runID) > 8 {
+		return runID[:8]
+	}
+	return runID
+}
+
+const jobWorkflowIDPrefix = "engine-ci-job-"
+
+// JobWorkflowID returns the workflow ID of the EngineCIJobWorkflow execution for a job
+func JobWorkflowID(jobID string) string {
+	return jobWorkflowIDPrefix + jobID
 }
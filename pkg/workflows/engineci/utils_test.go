@@ -66,17 +66,22 @@ func TestGetCloneDirectory(t *testing.T) {
 		{
 			name:     "Simple GitHub repo",
 			repoURL:  "https://github.com/containifyci/temporal-worker",
-			expected: "/tmp/ci-temporal-worker",
+			expected: "/tmp/ci/github.com/containifyci/temporal-worker",
 		},
 		{
 			name:     "Repo with .git suffix",
 			repoURL:  "https://github.com/containifyci/temporal-worker.git",
-			expected: "/tmp/ci-temporal-worker",
+			expected: "/tmp/ci/github.com/containifyci/temporal-worker",
 		},
 		{
 			name:     "Repo with special characters",
-			repoURL:  "https://github.com/containifyci/my.repo.name",
-			expected: "/tmp/ci-my-repo-name",
+			repoURL:  "https://github.com/containifyci/My Repo",
+			expected: "/tmp/ci/github.com/containifyci/my-repo",
+		},
+		{
+			name:     "Same name in another owner",
+			repoURL:  "git@github.com:other/temporal-worker.git",
+			expected: "/tmp/ci/github.com/other/temporal-worker",
 		},
 	}
 
@@ -89,3 +94,13 @@ func TestGetCloneDirectory(t *testing.T) {
 		})
 	}
 }
+
+func TestNewJobID(t *testing.T) {
+	jobID := NewJobID("https://github.com/containifyci/temporal-worker.git", "5f1c2a9b-0d3e-4c7a-9f11-2b3c4d5e6f70", 3)
+	if jobID != "github.com/containifyci/temporal-worker/5f1c2a9b-3" {
+		t.Errorf("NewJobID() = %q, want %q", jobID, "github.com/containifyci/temporal-worker/5f1c2a9b-3")
+	}
+	if id := JobWorkflowID(jobID); id != "engine-ci-job-github.com/containifyci/temporal-worker/5f1c2a9b-3" {
+		t.Errorf("JobWorkflowID(%q) = %q", jobID, id)
+	}
+}
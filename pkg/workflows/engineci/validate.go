@@ -0,0 +1,160 @@
+package engineci
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"go.temporal.io/sdk/workflow"
+)
+
+// invalidJobErrorType is the application error type of jobs rejected by ValidateJob
+const invalidJobErrorType = "InvalidJob"
+
+// jobValidationChange versions the validation of jobs sent by signal or started without the queue
+const jobValidationChange = "job-validation"
+
+// AllowedEnvKeys restricts the environment variables a submitted job may set
+// Entries ending in `*` allow a prefix, e.g. `CI_*`; an empty list allows every key that is not reserved
+// Set from the comma separated ENGINE_CI_ALLOWED_ENV_KEYS
+var AllowedEnvKeys = allowedEnvKeysFromEnv()
+
+// reservedEnvKeys are controlled by the worker and can never be set by a job
+var reservedEnvKeys = map[string]bool{
+	"PATH":                  true,
+	"HOME":                  true,
+	"USER":                  true,
+	"SHELL":                 true,
+	"LD_PRELOAD":            true,
+	"LD_LIBRARY_PATH":       true,
+	"DYLD_INSERT_LIBRARIES": true,
+	"GIT_CONFIG_GLOBAL":     true,
+	"GOMODCACHE":            true,
+	"GOCACHE":               true,
+	"GOFLAGS":               true, // e.g. -toolexec, see forbiddenGoTestFlags
+	"GOTOOLCHAIN":           true,
+	"GOENV":                 true,
+	"GOROOT":                true,
+	"GOEXPERIMENT":          true,
+	"CC":                    true, // compilers and tools run by cgo builds
+	"CXX":                   true,
+	"AR":                    true,
+	"PKG_CONFIG":            true,
+}
+
+// reservedEnvPrefixes reserve whole families of variables, e.g. CGO_CFLAGS and CGO_LDFLAGS reach the compiler and linker
+var reservedEnvPrefixes = []string{"ENGINE_CI_CACHE_", "CGO_"}
+
+var envKeyPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+func allowedEnvKeysFromEnv() []string {
+	var keys []string
+	for _, key := range strings.Split(os.Getenv("ENGINE_CI_ALLOWED_ENV_KEYS"), ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// ValidateJob checks a submitted job before it is accepted into the queue
+func ValidateJob(job EngineCIWorkflowInput) error {
+	var errs []error
+
+	if strings.TrimSpace(job.GitRepoURL) == "" {
+		errs = append(errs, errors.New("repository URL must not be empty"))
+	}
+	if err := validateRef("ref", job.GitRef); err != nil {
+		errs = append(errs, err)
+	}
+	if err := validateRef("base ref", job.BaseRef); err != nil {
+		errs = append(errs, err)
+	}
+	if runner, err := lookupRunner(job.Runner); err != nil {
+		errs = append(errs, err)
+	} else if _, _, err := runner.Command(job.Runner, job.EngineArgs); err != nil {
+		errs = append(errs, err)
+	}
+	errs = append(errs, validateLabels(job.Labels)...)
+	if job.CallbackURL != "" {
+		if err := validateCallbackURL(job.CallbackURL); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if job.CallbackSecret != "" {
+		if err := validateCallbackSecret(job.CallbackSecret); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	keys := make([]string, 0, len(job.Env))
+	for key := range job.Env {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if err := validateEnvKey(key); err != nil {
+			errs = append(errs, err)
+		}
+		if err := validateSecretRef(key, job.Env[key]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// validateJobOnce runs ValidateJob as a side effect, the checks depend on the configuration of the worker, so the
+// recorded outcome keeps a replay on a differently configured worker deterministic
+func validateJobOnce(ctx workflow.Context, job EngineCIWorkflowInput) error {
+	var msg string
+	err := workflow.SideEffect(ctx, func(workflow.Context) any {
+		if err := ValidateJob(job); err != nil {
+			return err.Error()
+		}
+		return ""
+	}).Get(&msg)
+	if err != nil {
+		return err
+	}
+	if msg != "" {
+		return errors.New(msg)
+	}
+	return nil
+}
+
+// validateRef rejects refs git would read as an option
+func validateRef(name, ref string) error {
+	if strings.HasPrefix(ref, "-") {
+		return fmt.Errorf("%s %q must not start with \"-\"", name, ref)
+	}
+	return nil
+}
+
+func validateEnvKey(key string) error {
+	if !envKeyPattern.MatchString(key) {
+		return fmt.Errorf("invalid environment variable name %q", key)
+	}
+	if reservedEnvKeys[key] {
+		return fmt.Errorf("environment variable %q is reserved by the worker", key)
+	}
+	for _, prefix := range reservedEnvPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return fmt.Errorf("environment variable %q is reserved by the worker", key)
+		}
+	}
+	if len(AllowedEnvKeys) == 0 {
+		return nil
+	}
+	for _, allowed := range AllowedEnvKeys {
+		if prefix, ok := strings.CutSuffix(allowed, "*"); ok && strings.HasPrefix(key, prefix) {
+			return nil
+		}
+		if allowed == key {
+			return nil
+		}
+	}
+	return fmt.Errorf("environment variable %q is not allowed", key)
+}
@@ -0,0 +1,145 @@
+package engineci
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateJob(t *testing.T) {
+	valid := EngineCIWorkflowInput{
+		GitRepoURL: "https://github.com/test/repo",
+		EngineArgs: []string{"run"},
+	}
+
+	tests := []struct {
+		name    string
+		allowed []string
+		mutate  func(job *EngineCIWorkflowInput)
+		errs    []string
+	}{
+		{
+			name:   "valid job",
+			mutate: func(job *EngineCIWorkflowInput) {},
+		},
+		{
+			name:   "empty repository URL",
+			mutate: func(job *EngineCIWorkflowInput) { job.GitRepoURL = " " },
+			errs:   []string{"repository URL must not be empty"},
+		},
+		{
+			name:   "ref read as an option",
+			mutate: func(job *EngineCIWorkflowInput) { job.GitRef = "--upload-pack=touch /tmp/pwned" },
+			errs:   []string{`ref "--upload-pack=touch /tmp/pwned" must not start with "-"`},
+		},
+		{
+			name:   "base ref read as an option",
+			mutate: func(job *EngineCIWorkflowInput) { job.BaseRef = "--upload-pack=touch /tmp/pwned" },
+			errs:   []string{`base ref "--upload-pack=touch /tmp/pwned" must not start with "-"`},
+		},
+		{
+			name:   "empty args",
+			mutate: func(job *EngineCIWorkflowInput) { job.EngineArgs = nil },
+			errs:   []string{"engine-ci arguments must not be empty"},
+		},
+		{
+			name: "reserved env key",
+			mutate: func(job *EngineCIWorkflowInput) {
+				job.Env = map[string]string{"PATH": "/tmp", "ENGINE_CI_CACHE_NPM": "/x"}
+			},
+			errs: []string{`"ENGINE_CI_CACHE_NPM" is reserved`, `"PATH" is reserved`},
+		},
+		{
+			name: "go environment bypassing the runner",
+			mutate: func(job *EngineCIWorkflowInput) {
+				job.Env = map[string]string{"GOFLAGS": "-toolexec=./evil", "GOTOOLCHAIN": "go1.99.0", "GOENV": "./go.env"}
+			},
+			errs: []string{`"GOENV" is reserved`, `"GOFLAGS" is reserved`, `"GOTOOLCHAIN" is reserved`},
+		},
+		{
+			name: "cgo environment",
+			mutate: func(job *EngineCIWorkflowInput) {
+				job.Env = map[string]string{"CC": "./evil-cc", "CGO_LDFLAGS": "-Wl,--wrap", "CGO_ENABLED": "1", "GOROOT": "/tmp/go", "GOEXPERIMENT": "x"}
+			},
+			errs: []string{`"CC" is reserved`, `"CGO_ENABLED" is reserved`, `"CGO_LDFLAGS" is reserved`, `"GOEXPERIMENT" is reserved`, `"GOROOT" is reserved`},
+		},
+		{
+			name:   "invalid env key",
+			mutate: func(job *EngineCIWorkflowInput) { job.Env = map[string]string{"A-B": "1"} },
+			errs:   []string{`invalid environment variable name "A-B"`},
+		},
+		{
+			name:    "allowed env keys",
+			allowed: []string{"CI_*", "GOPRIVATE"},
+			mutate:  func(job *EngineCIWorkflowInput) { job.Env = map[string]string{"CI_TOKEN": "x", "GOPRIVATE": "x"} },
+		},
+		{
+			name:    "env key not allowed",
+			allowed: []string{"CI_*"},
+			mutate:  func(job *EngineCIWorkflowInput) { job.Env = map[string]string{"AWS_SECRET": "x"} },
+			errs:    []string{`"AWS_SECRET" is not allowed`},
+		},
+		{
+			name:   "secret reference",
+			mutate: func(job *EngineCIWorkflowInput) { job.Env = map[string]string{"NPM_TOKEN": "secret://NPM_TOKEN"} },
+		},
+		{
+			name:   "invalid secret reference",
+			mutate: func(job *EngineCIWorkflowInput) { job.Env = map[string]string{"NPM_TOKEN": "secret://npm/token"} },
+			errs:   []string{`"NPM_TOKEN" references invalid secret name "npm/token"`},
+		},
+		{
+			name:   "go-test runner without args",
+			mutate: func(job *EngineCIWorkflowInput) { job.Runner = RunnerSpec{Kind: RunnerGoTest}; job.EngineArgs = nil },
+		},
+		{
+			name:   "command not allowed",
+			mutate: func(job *EngineCIWorkflowInput) { job.Runner = RunnerSpec{Kind: RunnerCommand, Command: "bash"} },
+			errs:   []string{`command "bash" is not allowed`},
+		},
+		{
+			name:   "callback URL",
+			mutate: func(job *EngineCIWorkflowInput) { job.CallbackURL = "https://ci.example.com/hook" },
+		},
+		{
+			name:   "invalid callback URL",
+			mutate: func(job *EngineCIWorkflowInput) { job.CallbackURL = "ftp://ci.example.com/hook" },
+			errs:   []string{`invalid callback URL "ftp://ci.example.com/hook"`},
+		},
+		{
+			name:   "callback secret reference",
+			mutate: func(job *EngineCIWorkflowInput) { job.CallbackSecret = "secret://DEPLOY_HOOK" },
+		},
+		{
+			name:   "plain callback secret",
+			mutate: func(job *EngineCIWorkflowInput) { job.CallbackSecret = "s3cret" },
+			errs:   []string{"callback secret must be a secret://NAME reference"},
+		},
+		{
+			name: "multiple errors",
+			mutate: func(job *EngineCIWorkflowInput) {
+				job.GitRepoURL = ""
+				job.EngineArgs = nil
+			},
+			errs: []string{"repository URL must not be empty", "engine-ci arguments must not be empty"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func(orig []string) { AllowedEnvKeys = orig }(AllowedEnvKeys)
+			AllowedEnvKeys = tt.allowed
+
+			job := valid
+			tt.mutate(&job)
+			err := ValidateJob(job)
+			if len(tt.errs) == 0 {
+				assert.NoError(t, err)
+				return
+			}
+			for _, msg := range tt.errs {
+				assert.ErrorContains(t, err, msg)
+			}
+		})
+	}
+}
@@ -1,124 +1,189 @@
 package engineci
 
 import (
+	"fmt"
 	"time"
 
-	"github.com/containifyci/temporal-worker/pkg/activities/filesystem"
-	"github.com/containifyci/temporal-worker/pkg/activities/git"
-
-	"go.temporal.io/sdk/temporal"
 	"go.temporal.io/sdk/workflow"
 )
 
 // EngineCIRepoWorkflow processes Engine-CI jobs for a single repository sequentially
-// It uses signals to queue jobs and exits after an idle timeout
+// Jobs are submitted with the EngineCISubmitUpdate update (validated, returns job ID and queue position)
+// or the EngineCISignal signal. Every job runs as an EngineCIJobWorkflow child, the workflow exits
+// after an idle timeout
+// Queues started by the previous release, which ran the jobs inline, finish on legacyRepoWorkflow
+// Running queues move to new worker versions (auto-upgrade), so every change must be gated with workflow.GetVersion
 func EngineCIRepoWorkflow(ctx workflow.Context) error {
+	if workflow.GetVersion(ctx, jobWorkflowChange, workflow.DefaultVersion, 1) == workflow.DefaultVersion {
+		return legacyRepoWorkflow(ctx)
+	}
+
 	logger := workflow.GetLogger(ctx)
 	logger.Info("Started Engine-CI queue workflow")
 
-	// Signal channel for incoming jobs
-	signalCh := workflow.GetSignalChannel(ctx, EngineCISignal)
 	var jobQueue []EngineCIWorkflowInput
+	var results []EngineCIJobResult
+	running := ""
+
+	// Sequence number for job IDs assigned by this run
+	execution := workflow.GetInfo(ctx).WorkflowExecution
+	runID := execution.RunID
+	jobSeq := 0
+
+	var enqueue func(job EngineCIWorkflowInput) EngineCISubmission
+	// Signals aren't validated before they are written to the history, invalid jobs are dropped
+	receiveSignal := func(ctx workflow.Context, job EngineCIWorkflowInput) {
+		if workflow.GetVersion(ctx, jobValidationChange, workflow.DefaultVersion, 1) != workflow.DefaultVersion {
+			if err := validateJobOnce(ctx, job); err != nil {
+				logger.Error("Dropping invalid Engine-CI job", "repo", job.RepoName, "jobID", job.JobID, "error", err)
+				return
+			}
+		}
+		enqueue(job)
+	}
 
-	// Global activity options
-	ao := workflow.ActivityOptions{
-		RetryPolicy: &temporal.RetryPolicy{
-			InitialInterval:    30 * time.Second,
-			BackoffCoefficient: 1.5,
-			MaximumInterval:    10 * time.Minute,
-			MaximumAttempts:    2,
-		},
-		StartToCloseTimeout: 45 * time.Minute,
+	enqueue = func(job EngineCIWorkflowInput) EngineCISubmission {
+		if job.JobID == "" {
+			jobSeq++
+			job.JobID = NewJobID(job.GitRepoURL, runID, jobSeq)
+		}
+		if job.RepoName == "" {
+			job.RepoName = ParseRepoIdentity(job.GitRepoURL).DisplayName()
+		}
+		jobQueue = append(jobQueue, job)
+
+		// Jobs ahead of this one, including the one that is currently running
+		position := len(jobQueue) - 1
+		if running != "" {
+			position++
+		}
+		logger.Info("Received Engine-CI job", "repo", job.RepoName, "jobID", job.JobID, "queueSize", len(jobQueue))
+		return EngineCISubmission{
+			JobID:         job.JobID,
+			WorkflowID:    JobWorkflowID(job.JobID),
+			QueuePosition: position,
+		}
 	}
-	ctx = workflow.WithActivityOptions(ctx, ao)
 
-	for {
-		// Setup a timer for the idle timeout
-		timerCtx, cancelTimer := workflow.WithCancel(ctx)
-		timerFuture := workflow.NewTimer(timerCtx, IdleTimeout)
+	// Expose the outcome of processed jobs, including their failure class
+	err := workflow.SetQueryHandler(ctx, EngineCIResultsQuery, func() ([]EngineCIJobResult, error) {
+		return results, nil
+	})
+	if err != nil {
+		return err
+	}
 
-		// Wait for a signal or timeout
-		selector := workflow.NewSelector(timerCtx)
-		selector.AddReceive(signalCh, func(c workflow.ReceiveChannel, more bool) {
-			var job EngineCIWorkflowInput
-			c.Receive(ctx, &job)
-			jobQueue = append(jobQueue, job)
-			logger.Info("Received Engine-CI job", "repo", job.RepoName, "queueSize", len(jobQueue))
-			// Reset idle timer since we received a job
-			cancelTimer()
-		})
+	// Synchronous submission, invalid jobs are rejected before they are written to the history
+	err = workflow.SetUpdateHandlerWithOptions(ctx, EngineCISubmitUpdate,
+		func(ctx workflow.Context, job EngineCIWorkflowInput) (EngineCISubmission, error) {
+			return enqueue(job), nil
+		},
+		workflow.UpdateHandlerOptions{
+			Validator: func(ctx workflow.Context, job EngineCIWorkflowInput) error {
+				if err := ValidateJob(job); err != nil {
+					return err
+				}
+				if job.JobID != "" && isKnownJob(job.JobID, running, jobQueue) {
+					return fmt.Errorf("job %s is already queued", job.JobID)
+				}
+				return nil
+			},
+		},
+	)
+	if err != nil {
+		return err
+	}
 
-		// Listen for timeout
-		selector.AddFuture(timerFuture, func(f workflow.Future) {
-			logger.Info("No Engine-CI job received within timeout, exiting workflow.")
-		})
+	// Fire-and-forget submission via signal
+	signalCh := workflow.GetSignalChannel(ctx, EngineCISignal)
+	workflow.Go(ctx, func(ctx workflow.Context) {
+		for {
+			var job EngineCIWorkflowInput
+			signalCh.Receive(ctx, &job)
+			receiveSignal(ctx, job)
+		}
+	})
+
+	// A queue under a name-only workflow ID may mix repositories of different owners, once it runs
+	// this release it only waits LegacyDrainTimeout for new jobs so that clients move over to the
+	// host/owner/name workflow ID
+	idleTimeout := func() time.Duration {
+		if IsLegacyRepoWorkflowID(execution.ID) &&
+			workflow.GetVersion(ctx, legacyQueueDrainChange, workflow.DefaultVersion, 1) != workflow.DefaultVersion {
+			return LegacyDrainTimeout
+		}
+		return recordedSettings(ctx).IdleTimeout
+	}
 
-		// Wait for a job signal to arrive
-		selector.Select(ctx)
+	for {
+		// Wait for a job or the idle timeout
+		ok, err := workflow.AwaitWithTimeout(ctx, idleTimeout(), func() bool { return len(jobQueue) > 0 })
+		if err != nil {
+			return err
+		}
 
 		// If the timer fired (no jobs received), exit workflow
-		if len(jobQueue) == 0 {
-			logger.Info("Shutting down workflow due to inactivity.")
-			cancelTimer()
-			return nil
+		if !ok {
+			// Signals delivered together with the timer have not been received yet
+			var job EngineCIWorkflowInput
+			for signalCh.ReceiveAsync(&job) {
+				receiveSignal(ctx, job)
+				job = EngineCIWorkflowInput{}
+			}
+			if len(jobQueue) == 0 {
+				logger.Info("Shutting down workflow due to inactivity.")
+				return nil
+			}
 		}
 
 		// Process jobs sequentially
 		for len(jobQueue) > 0 {
 			job := jobQueue[0]
 			jobQueue = jobQueue[1:] // Dequeue
+			running = job.JobID
+			childID := JobWorkflowID(job.JobID)
 
-			logger.Info("Engine-CI job started", "repo", job.RepoName, "ref", job.GitRef)
-
-			// Per-job activity options with longer timeout
-			jobOptions := workflow.ActivityOptions{
-				RetryPolicy: &temporal.RetryPolicy{
-					InitialInterval:    30 * time.Second,
-					BackoffCoefficient: 1.5,
-					MaximumInterval:    10 * time.Minute,
-					MaximumAttempts:    3,
-				},
-				StartToCloseTimeout: 15 * time.Minute,
-			}
-			jobCtx := workflow.WithActivityOptions(ctx, jobOptions)
+			logger.Info("Engine-CI job started", "repo", job.RepoName, "ref", job.GitRef, "jobID", job.JobID, "workflowID", childID)
 
-			// Step 1: Clone repository
-			var workDir string
-			targetDir := GetCloneDirectory(job.GitRepoURL)
-			err := workflow.ExecuteActivity(jobCtx, git.CloneRepo, job.GitRepoURL, job.GitRef, targetDir).Get(ctx, &workDir)
+			// Run the job as its own execution and wait for it, so the queue stays strictly sequential
+			childCtx := workflow.WithChildOptions(ctx, workflow.ChildWorkflowOptions{
+				WorkflowID: childID,
+			})
+			var details EngineCIDetails
+			err := workflow.ExecuteChildWorkflow(childCtx, EngineCIJobWorkflow, job).Get(ctx, &details)
 			if err != nil {
-				logger.Error("Git clone failed", "repo", job.RepoName, "error", err)
-				continue
+				logger.Error("Engine-CI job workflow failed", "repo", job.RepoName, "jobID", job.JobID, "error", err)
+				details = failedDetails(err)
 			}
-
-			// Step 2: Run Engine-CI
-			var details *EngineCIDetails
-			err = workflow.ExecuteActivity(jobCtx, RunEngineCI, workDir, job.EngineArgs, job.Env).Get(ctx, &details)
-			if err != nil {
-				logger.Error("Engine-CI execution failed", "repo", job.RepoName, "error", err)
-				// Don't cleanup on error - preserve directory for debugging
-				continue
-			}
-
-			// Step 3: Cleanup if successful (exit code 0)
-			if details.ExitCode == 0 {
-				logger.Info("Engine-CI succeeded, cleaning up", "repo", job.RepoName)
-				var cleanupErr error
-				err = workflow.ExecuteActivity(jobCtx, filesystem.CleanupDirectory, workDir).Get(ctx, &cleanupErr)
-				if err != nil {
-					logger.Warn("Cleanup failed (non-critical)", "repo", job.RepoName, "error", err)
-				}
-			} else {
-				logger.Error("Engine-CI failed, preserving directory for debugging",
-					"repo", job.RepoName,
-					"exitCode", details.ExitCode,
-					"workDir", workDir,
-					"last50Lines", details.Last50Lines)
+			running = ""
+
+			results = append(results, EngineCIJobResult{
+				JobID:      job.JobID,
+				WorkflowID: childID,
+				RepoName:   job.RepoName,
+				GitRef:     job.GitRef,
+				Details:    details,
+			})
+			if len(results) > MaxJobResults {
+				results = results[len(results)-MaxJobResults:]
 			}
 
-			logger.Info("Engine-CI job completed", "repo", job.RepoName, "remainingJobs", len(jobQueue))
+			logger.Info("Engine-CI job completed", "repo", job.RepoName, "failureClass", details.FailureClass, "attempts", details.Attempts, "remainingJobs", len(jobQueue))
 		}
 
 		logger.Info("No more Engine-CI jobs, waiting for new signals")
 	}
 }
+
+// isKnownJob reports whether a job ID is running or waiting in the queue
+func isKnownJob(jobID, running string, queue []EngineCIWorkflowInput) bool {
+	if jobID == running {
+		return true
+	}
+	for _, job := range queue {
+		if job.JobID == jobID {
+			return true
+		}
+	}
+	return false
+}
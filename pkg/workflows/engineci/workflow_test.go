@@ -1,6 +1,7 @@
 package engineci
 
 import (
+	"context"
 	"testing"
 	"time"
 
@@ -9,7 +10,10 @@ import (
 
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/temporal"
 	"go.temporal.io/sdk/testsuite"
+	"go.temporal.io/sdk/workflow"
 )
 
 type WorkflowTestSuite struct {
@@ -25,15 +29,16 @@ func (s *WorkflowTestSuite) TestEngineCIRepoWorkflow_SingleJob() {
 	env := s.NewTestWorkflowEnvironment()
 
 	// Mock activities
-	env.OnActivity(git.CloneRepo, mock.Anything, "https://github.com/test/repo", "main", "/tmp/ci-repo").
-		Return("/tmp/ci-repo", nil)
-	env.OnActivity(RunEngineCI, mock.Anything, "/tmp/ci-repo", []string{"run", "-t", "all"}, map[string]string{}).
+	env.OnActivity(git.CloneRepo, mock.Anything, "https://github.com/test/repo", "main", "/tmp/ci/github.com/test/repo").
+		Return("/tmp/ci/github.com/test/repo", nil)
+	env.OnActivity(RunEngineCIJob, mock.Anything, RunEngineCIInput{WorkDir: "/tmp/ci/github.com/test/repo", Args: []string{"run", "-t", "all"}, Env: map[string]string{}}).
 		Return(&EngineCIDetails{ExitCode: 0, Last50Lines: "Success"}, nil)
-	env.OnActivity(filesystem.CleanupDirectory, mock.Anything, "/tmp/ci-repo").
+	env.OnActivity(filesystem.CleanupDirectory, mock.Anything, "/tmp/ci/github.com/test/repo").
 		Return(nil)
 
 	// Register workflow
 	env.RegisterWorkflow(EngineCIRepoWorkflow)
+	env.RegisterWorkflow(EngineCIJobWorkflow)
 
 	// Start workflow
 	env.RegisterDelayedCallback(func() {
@@ -57,14 +62,15 @@ func (s *WorkflowTestSuite) TestEngineCIRepoWorkflow_MultipleJobs() {
 
 	// Mock activities for multiple jobs
 	env.OnActivity(git.CloneRepo, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
-		Return("/tmp/ci-repo", nil).Times(2)
-	env.OnActivity(RunEngineCI, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return("/tmp/ci/github.com/test/repo", nil).Times(2)
+	env.OnActivity(RunEngineCIJob, mock.Anything, mock.Anything).
 		Return(&EngineCIDetails{ExitCode: 0, Last50Lines: "Success"}, nil).Times(2)
 	env.OnActivity(filesystem.CleanupDirectory, mock.Anything, mock.Anything).
 		Return(nil).Times(2)
 
 	// Register workflow
 	env.RegisterWorkflow(EngineCIRepoWorkflow)
+	env.RegisterWorkflow(EngineCIJobWorkflow)
 
 	// Send two signals
 	env.RegisterDelayedCallback(func() {
@@ -96,10 +102,10 @@ func (s *WorkflowTestSuite) TestEngineCIRepoWorkflow_MultipleJobs() {
 func (s *WorkflowTestSuite) TestEngineCIRepoWorkflow_FailedJobNoCleanup() {
 	env := s.NewTestWorkflowEnvironment()
 
-	// Mock activities - RunEngineCI returns non-zero exit code
-	env.OnActivity(git.CloneRepo, mock.Anything, "https://github.com/test/repo", "main", "/tmp/ci-repo").
-		Return("/tmp/ci-repo", nil)
-	env.OnActivity(RunEngineCI, mock.Anything, "/tmp/ci-repo", []string{"run", "-t", "all"}, map[string]string{}).
+	// Mock activities - RunEngineCIJob returns non-zero exit code
+	env.OnActivity(git.CloneRepo, mock.Anything, "https://github.com/test/repo", "main", "/tmp/ci/github.com/test/repo").
+		Return("/tmp/ci/github.com/test/repo", nil)
+	env.OnActivity(RunEngineCIJob, mock.Anything, RunEngineCIInput{WorkDir: "/tmp/ci/github.com/test/repo", Args: []string{"run", "-t", "all"}, Env: map[string]string{}}).
 		Return(&EngineCIDetails{ExitCode: 1, Last50Lines: "Build failed"}, nil)
 	// CleanupDirectory should NOT be called when job fails
 	env.OnActivity(filesystem.CleanupDirectory, mock.Anything, mock.Anything).
@@ -107,6 +113,7 @@ func (s *WorkflowTestSuite) TestEngineCIRepoWorkflow_FailedJobNoCleanup() {
 
 	// Register workflow
 	env.RegisterWorkflow(EngineCIRepoWorkflow)
+	env.RegisterWorkflow(EngineCIJobWorkflow)
 
 	// Start workflow
 	env.RegisterDelayedCallback(func() {
@@ -130,6 +137,7 @@ func (s *WorkflowTestSuite) TestEngineCIRepoWorkflow_IdleTimeout() {
 
 	// Register workflow
 	env.RegisterWorkflow(EngineCIRepoWorkflow)
+	env.RegisterWorkflow(EngineCIJobWorkflow)
 
 	// Don't send any signals - workflow should timeout after IdleTimeout
 	env.ExecuteWorkflow(EngineCIRepoWorkflow)
@@ -137,3 +145,625 @@ func (s *WorkflowTestSuite) TestEngineCIRepoWorkflow_IdleTimeout() {
 	s.True(env.IsWorkflowCompleted())
 	s.NoError(env.GetWorkflowError())
 }
+
+func (s *WorkflowTestSuite) TestEngineCIRepoWorkflow_InfrastructureFailureRetried() {
+	env := s.NewTestWorkflowEnvironment()
+
+	// First run hits a docker daemon error, the retry succeeds
+	env.OnActivity(git.CloneRepo, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return("/tmp/ci/github.com/test/repo", nil).Times(2)
+	env.OnActivity(RunEngineCIJob, mock.Anything, mock.Anything).
+		Return(&EngineCIDetails{ExitCode: 1, Last50Lines: "Cannot connect to the Docker daemon", FailureClass: FailureClassInfrastructure}, nil).Once()
+	env.OnActivity(RunEngineCIJob, mock.Anything, mock.Anything).
+		Return(&EngineCIDetails{ExitCode: 0, Last50Lines: "Success"}, nil).Once()
+	env.OnActivity(filesystem.CleanupDirectory, mock.Anything, "/tmp/ci/github.com/test/repo").
+		Return(nil).Once()
+
+	env.RegisterWorkflow(EngineCIRepoWorkflow)
+	env.RegisterWorkflow(EngineCIJobWorkflow)
+
+	env.RegisterDelayedCallback(func() {
+		env.SignalWorkflow(EngineCISignal, EngineCIWorkflowInput{
+			GitRepoURL: "https://github.com/test/repo",
+			GitRef:     "main",
+			RepoName:   "repo",
+			EngineArgs: []string{"run", "-t", "all"},
+			Env:        map[string]string{},
+		})
+	}, 100*time.Millisecond)
+
+	env.ExecuteWorkflow(EngineCIRepoWorkflow)
+
+	s.True(env.IsWorkflowCompleted())
+	s.NoError(env.GetWorkflowError())
+	env.AssertExpectations(s.T())
+
+	results := s.queryResults(env)
+	s.Require().Len(results, 1)
+	s.Equal(0, results[0].Details.ExitCode)
+	s.Equal(FailureClassNone, results[0].Details.FailureClass)
+	s.Equal(2, results[0].Details.Attempts)
+}
+
+func (s *WorkflowTestSuite) TestEngineCIRepoWorkflow_BuildFailureNotRetried() {
+	env := s.NewTestWorkflowEnvironment()
+
+	env.OnActivity(git.CloneRepo, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return("/tmp/ci/github.com/test/repo", nil).Once()
+	env.OnActivity(RunEngineCIJob, mock.Anything, mock.Anything).
+		Return(&EngineCIDetails{ExitCode: 1, Last50Lines: "--- FAIL: TestX", FailureClass: FailureClassBuild}, nil).Once()
+
+	env.RegisterWorkflow(EngineCIRepoWorkflow)
+	env.RegisterWorkflow(EngineCIJobWorkflow)
+
+	env.RegisterDelayedCallback(func() {
+		env.SignalWorkflow(EngineCISignal, EngineCIWorkflowInput{
+			GitRepoURL: "https://github.com/test/repo",
+			GitRef:     "main",
+			RepoName:   "repo",
+			EngineArgs: []string{"run", "-t", "all"},
+			Env:        map[string]string{},
+		})
+	}, 100*time.Millisecond)
+
+	env.ExecuteWorkflow(EngineCIRepoWorkflow)
+
+	s.True(env.IsWorkflowCompleted())
+	s.NoError(env.GetWorkflowError())
+	env.AssertExpectations(s.T())
+
+	results := s.queryResults(env)
+	s.Require().Len(results, 1)
+	s.Equal(FailureClassBuild, results[0].Details.FailureClass)
+	s.Equal(1, results[0].Details.Attempts)
+}
+
+func (s *WorkflowTestSuite) TestEngineCIRepoWorkflow_CloneFailureExhaustsRetries() {
+	env := s.NewTestWorkflowEnvironment()
+
+	// Every clone fails, the job is attempted 1 + MaxInfraRetries times and recorded as infrastructure failure
+	env.OnActivity(git.CloneRepo, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return("", temporal.NewNonRetryableApplicationError("git clone failed", "CloneError", nil))
+
+	env.RegisterWorkflow(EngineCIRepoWorkflow)
+	env.RegisterWorkflow(EngineCIJobWorkflow)
+
+	env.RegisterDelayedCallback(func() {
+		env.SignalWorkflow(EngineCISignal, EngineCIWorkflowInput{
+			GitRepoURL: "https://github.com/test/repo",
+			GitRef:     "main",
+			RepoName:   "repo",
+			EngineArgs: []string{"run", "-t", "all"},
+			Env:        map[string]string{},
+		})
+	}, 100*time.Millisecond)
+
+	env.ExecuteWorkflow(EngineCIRepoWorkflow)
+
+	s.True(env.IsWorkflowCompleted())
+	s.NoError(env.GetWorkflowError())
+	env.AssertNumberOfCalls(s.T(), "CloneRepo", 1+MaxInfraRetries)
+
+	results := s.queryResults(env)
+	s.Require().Len(results, 1)
+	s.Equal(-1, results[0].Details.ExitCode)
+	s.Equal(FailureClassInfrastructure, results[0].Details.FailureClass)
+	s.Equal(1+MaxInfraRetries, results[0].Details.Attempts)
+}
+
+func (s *WorkflowTestSuite) queryResults(env *testsuite.TestWorkflowEnvironment) []EngineCIJobResult {
+	val, err := env.QueryWorkflow(EngineCIResultsQuery)
+	s.Require().NoError(err)
+
+	var results []EngineCIJobResult
+	s.Require().NoError(val.Get(&results))
+	return results
+}
+
+func (s *WorkflowTestSuite) TestEngineCIRepoWorkflow_CacheKeyDefaultsToRepo() {
+	env := s.NewTestWorkflowEnvironment()
+
+	env.OnActivity(git.CloneRepo, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return("/tmp/ci/github.com/test/repo", nil)
+	env.OnActivity(RunEngineCIJob, mock.Anything, RunEngineCIInput{
+		WorkDir: "/tmp/ci/github.com/test/repo",
+		Args:    []string{"run", "-t", "all"},
+		Env:     map[string]string{},
+		Cache:   CacheSpec{Key: "github-com_test_repo", GoModCache: true},
+	}).Return(&EngineCIDetails{ExitCode: 0, Caches: []CacheStatus{{Name: "gomod", Hit: true}}}, nil).Once()
+	env.OnActivity(filesystem.CleanupDirectory, mock.Anything, mock.Anything).
+		Return(nil)
+
+	env.RegisterWorkflow(EngineCIRepoWorkflow)
+	env.RegisterWorkflow(EngineCIJobWorkflow)
+
+	env.RegisterDelayedCallback(func() {
+		env.SignalWorkflow(EngineCISignal, EngineCIWorkflowInput{
+			GitRepoURL: "https://github.com/test/repo",
+			GitRef:     "main",
+			RepoName:   "repo",
+			EngineArgs: []string{"run", "-t", "all"},
+			Env:        map[string]string{},
+			Cache:      CacheSpec{GoModCache: true},
+		})
+	}, 100*time.Millisecond)
+
+	env.ExecuteWorkflow(EngineCIRepoWorkflow)
+
+	s.True(env.IsWorkflowCompleted())
+	s.NoError(env.GetWorkflowError())
+	env.AssertExpectations(s.T())
+
+	results := s.queryResults(env)
+	s.Require().Len(results, 1)
+	s.Equal([]CacheStatus{{Name: "gomod", Hit: true}}, results[0].Details.Caches)
+}
+
+func (s *WorkflowTestSuite) TestEngineCIRepoWorkflow_SkippedWhenNoRelevantChanges() {
+	env := s.NewTestWorkflowEnvironment()
+
+	env.OnActivity(git.CloneRepo, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return("/tmp/ci/github.com/test/repo", nil)
+	env.OnActivity(git.ChangedFiles, mock.Anything, git.ChangedFilesInputs{RepoPath: "/tmp/ci/github.com/test/repo", Base: "abc123"}).
+		Return([]string{"README.md", "docs/guide.md"}, nil)
+	env.OnActivity(filesystem.CleanupDirectory, mock.Anything, "/tmp/ci/github.com/test/repo").
+		Return(nil).Once()
+
+	env.RegisterWorkflow(EngineCIRepoWorkflow)
+	env.RegisterWorkflow(EngineCIJobWorkflow)
+	env.RegisterActivity(RunEngineCIJob)
+
+	env.RegisterDelayedCallback(func() {
+		env.SignalWorkflow(EngineCISignal, EngineCIWorkflowInput{
+			GitRepoURL: "https://github.com/test/repo",
+			GitRef:     "main",
+			RepoName:   "repo",
+			EngineArgs: []string{"run", "-t", "all"},
+			Paths:      PathFilter{Exclude: []string{"**/*.md"}},
+			BaseRef:    "abc123",
+		})
+	}, 100*time.Millisecond)
+
+	env.ExecuteWorkflow(EngineCIRepoWorkflow)
+
+	s.True(env.IsWorkflowCompleted())
+	s.NoError(env.GetWorkflowError())
+	env.AssertExpectations(s.T())
+	env.AssertNotCalled(s.T(), "RunEngineCIJob", mock.Anything, mock.Anything)
+
+	results := s.queryResults(env)
+	s.Require().Len(results, 1)
+	s.True(results[0].Details.Skipped)
+	s.Contains(results[0].Details.SkipReason, "abc123")
+}
+
+func (s *WorkflowTestSuite) TestEngineCIRepoWorkflow_RunsWhenRelevantChanges() {
+	env := s.NewTestWorkflowEnvironment()
+
+	env.OnActivity(git.CloneRepo, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return("/tmp/ci/github.com/test/repo", nil)
+	env.OnActivity(git.ChangedFiles, mock.Anything, mock.Anything).
+		Return([]string{"README.md", "services/api/main.go"}, nil)
+	env.OnActivity(RunEngineCIJob, mock.Anything, mock.Anything).
+		Return(&EngineCIDetails{ExitCode: 0}, nil).Once()
+	env.OnActivity(filesystem.CleanupDirectory, mock.Anything, "/tmp/ci/github.com/test/repo").
+		Return(nil).Once()
+
+	env.RegisterWorkflow(EngineCIRepoWorkflow)
+	env.RegisterWorkflow(EngineCIJobWorkflow)
+
+	env.RegisterDelayedCallback(func() {
+		env.SignalWorkflow(EngineCISignal, EngineCIWorkflowInput{
+			GitRepoURL: "https://github.com/test/repo",
+			GitRef:     "main",
+			RepoName:   "repo",
+			EngineArgs: []string{"run", "-t", "all"},
+			Paths:      PathFilter{Include: []string{"services/api/**"}},
+			BaseRef:    "abc123",
+		})
+	}, 100*time.Millisecond)
+
+	env.ExecuteWorkflow(EngineCIRepoWorkflow)
+
+	s.True(env.IsWorkflowCompleted())
+	s.NoError(env.GetWorkflowError())
+	env.AssertExpectations(s.T())
+
+	results := s.queryResults(env)
+	s.Require().Len(results, 1)
+	s.False(results[0].Details.Skipped)
+}
+
+func (s *WorkflowTestSuite) TestEngineCIRepoWorkflow_ReusesCachedResult() {
+	env := s.NewTestWorkflowEnvironment()
+
+	job := EngineCIWorkflowInput{
+		GitRepoURL: "https://github.com/test/repo",
+		GitRef:     "v1.0.0",
+		RepoName:   "repo",
+		EngineArgs: []string{"run", "-t", "all"},
+	}
+	key := ResultCacheKey(job, "abc123")
+
+	env.OnActivity(git.ResolveRef, mock.Anything, job.GitRepoURL, job.GitRef).
+		Return("abc123", nil)
+	env.OnActivity(LookupJobResult, mock.Anything, key).
+		Return(JobResultLookup{Found: true, Details: EngineCIDetails{ExitCode: 0, CommitSHA: "abc123", Attempts: 1}}, nil)
+
+	env.RegisterWorkflow(EngineCIRepoWorkflow)
+	env.RegisterWorkflow(EngineCIJobWorkflow)
+	env.RegisterActivity(git.CloneRepo)
+
+	env.RegisterDelayedCallback(func() {
+		env.SignalWorkflow(EngineCISignal, job)
+	}, 100*time.Millisecond)
+
+	env.ExecuteWorkflow(EngineCIRepoWorkflow)
+
+	s.True(env.IsWorkflowCompleted())
+	s.NoError(env.GetWorkflowError())
+	env.AssertExpectations(s.T())
+	env.AssertNotCalled(s.T(), "CloneRepo", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+
+	results := s.queryResults(env)
+	s.Require().Len(results, 1)
+	s.True(results[0].Details.Reused)
+	s.Equal("abc123", results[0].Details.CommitSHA)
+}
+
+func (s *WorkflowTestSuite) TestEngineCIRepoWorkflow_ForceBypassesResultCache() {
+	env := s.NewTestWorkflowEnvironment()
+
+	job := EngineCIWorkflowInput{
+		GitRepoURL: "https://github.com/test/repo",
+		GitRef:     "main",
+		RepoName:   "repo",
+		EngineArgs: []string{"run", "-t", "all"},
+		Force:      true,
+	}
+
+	env.OnActivity(git.CloneRepo, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return("/tmp/ci/github.com/test/repo", nil).Once()
+	env.OnActivity(RunEngineCIJob, mock.Anything, mock.Anything).
+		Return(&EngineCIDetails{ExitCode: 0, CommitSHA: "def456"}, nil).Once()
+	env.OnActivity(filesystem.CleanupDirectory, mock.Anything, mock.Anything).
+		Return(nil)
+	// The fresh result is stored under the commit that was built
+	env.OnActivity(StoreJobResult, mock.Anything, ResultCacheKey(job, "def456"), mock.Anything).
+		Return(nil).Once()
+
+	env.RegisterWorkflow(EngineCIRepoWorkflow)
+	env.RegisterWorkflow(EngineCIJobWorkflow)
+	env.RegisterActivity(git.ResolveRef)
+	env.RegisterActivity(LookupJobResult)
+
+	env.RegisterDelayedCallback(func() {
+		env.SignalWorkflow(EngineCISignal, job)
+	}, 100*time.Millisecond)
+
+	env.ExecuteWorkflow(EngineCIRepoWorkflow)
+
+	s.True(env.IsWorkflowCompleted())
+	s.NoError(env.GetWorkflowError())
+	env.AssertExpectations(s.T())
+	env.AssertNotCalled(s.T(), "ResolveRef", mock.Anything, mock.Anything, mock.Anything)
+	env.AssertNotCalled(s.T(), "LookupJobResult", mock.Anything, mock.Anything)
+
+	results := s.queryResults(env)
+	s.Require().Len(results, 1)
+	s.False(results[0].Details.Reused)
+}
+
+func (s *WorkflowTestSuite) TestEngineCIRepoWorkflow_RunsJobsAsChildWorkflows() {
+	env := s.NewTestWorkflowEnvironment()
+
+	var started []string
+	env.OnWorkflow(EngineCIJobWorkflow, mock.Anything, mock.Anything).
+		Return(func(ctx workflow.Context, job EngineCIWorkflowInput) (EngineCIDetails, error) {
+			started = append(started, workflow.GetInfo(ctx).WorkflowExecution.ID)
+			return EngineCIDetails{ExitCode: 0, Attempts: 1}, nil
+		})
+
+	env.RegisterWorkflow(EngineCIRepoWorkflow)
+
+	env.RegisterDelayedCallback(func() {
+		env.SignalWorkflow(EngineCISignal, EngineCIWorkflowInput{GitRepoURL: "https://github.com/test/repo", GitRef: "main", EngineArgs: []string{"run"}})
+		env.SignalWorkflow(EngineCISignal, EngineCIWorkflowInput{GitRepoURL: "https://github.com/test/repo", GitRef: "feature", EngineArgs: []string{"run"}, JobID: "delivery-42"})
+	}, 100*time.Millisecond)
+
+	env.ExecuteWorkflow(EngineCIRepoWorkflow)
+
+	s.True(env.IsWorkflowCompleted())
+	s.NoError(env.GetWorkflowError())
+
+	jobID := NewJobID("https://github.com/test/repo", "default-test-run-id", 1)
+	s.Equal([]string{JobWorkflowID(jobID), JobWorkflowID("delivery-42")}, started)
+
+	results := s.queryResults(env)
+	s.Require().Len(results, 2)
+	s.Equal(jobID, results[0].JobID)
+	s.Equal(JobWorkflowID(jobID), results[0].WorkflowID)
+	s.Equal("delivery-42", results[1].JobID)
+	s.Equal("feature", results[1].GitRef)
+}
+
+func (s *WorkflowTestSuite) TestEngineCIRepoWorkflow_CanceledJobDoesNotBlockQueue() {
+	env := s.NewTestWorkflowEnvironment()
+
+	env.OnWorkflow(EngineCIJobWorkflow, mock.Anything, mock.MatchedBy(func(job EngineCIWorkflowInput) bool { return job.GitRef == "main" })).
+		Return(EngineCIDetails{}, temporal.NewCanceledError())
+	env.OnWorkflow(EngineCIJobWorkflow, mock.Anything, mock.MatchedBy(func(job EngineCIWorkflowInput) bool { return job.GitRef == "feature" })).
+		Return(EngineCIDetails{ExitCode: 0}, nil)
+
+	env.RegisterWorkflow(EngineCIRepoWorkflow)
+
+	env.RegisterDelayedCallback(func() {
+		env.SignalWorkflow(EngineCISignal, EngineCIWorkflowInput{GitRepoURL: "https://github.com/test/repo", GitRef: "main", EngineArgs: []string{"run"}})
+		env.SignalWorkflow(EngineCISignal, EngineCIWorkflowInput{GitRepoURL: "https://github.com/test/repo", GitRef: "feature", EngineArgs: []string{"run"}})
+	}, 100*time.Millisecond)
+
+	env.ExecuteWorkflow(EngineCIRepoWorkflow)
+
+	s.True(env.IsWorkflowCompleted())
+	s.NoError(env.GetWorkflowError())
+
+	results := s.queryResults(env)
+	s.Require().Len(results, 2)
+	s.Equal(FailureClassCanceled, results[0].Details.FailureClass)
+	s.Equal(FailureClassNone, results[1].Details.FailureClass)
+}
+
+func (s *WorkflowTestSuite) TestEngineCIJobWorkflow_ReturnsDetails() {
+	env := s.NewTestWorkflowEnvironment()
+
+	env.OnActivity(git.CloneRepo, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return("/tmp/ci/github.com/test/repo", nil)
+	env.OnActivity(RunEngineCIJob, mock.Anything, mock.Anything).
+		Return(&EngineCIDetails{ExitCode: 1, Last50Lines: "--- FAIL", FailureClass: FailureClassBuild}, nil)
+
+	env.ExecuteWorkflow(EngineCIJobWorkflow, EngineCIWorkflowInput{
+		JobID:      "repo-1",
+		GitRepoURL: "https://github.com/test/repo",
+		GitRef:     "main",
+		RepoName:   "repo",
+		EngineArgs: []string{"run"},
+		Force:      true,
+	})
+
+	s.True(env.IsWorkflowCompleted())
+	s.NoError(env.GetWorkflowError())
+
+	var details EngineCIDetails
+	s.NoError(env.GetWorkflowResult(&details))
+	s.Equal(1, details.ExitCode)
+	s.Equal(FailureClassBuild, details.FailureClass)
+	s.Equal(1, details.Attempts)
+}
+
+func (s *WorkflowTestSuite) TestEngineCIRepoWorkflow_SubmitUpdateReturnsPosition() {
+	env := s.NewTestWorkflowEnvironment()
+
+	env.OnWorkflow(EngineCIJobWorkflow, mock.Anything, mock.Anything).
+		Return(EngineCIDetails{ExitCode: 0}, nil)
+
+	env.RegisterWorkflow(EngineCIRepoWorkflow)
+
+	var submissions []EngineCISubmission
+	submit := func(id string, job EngineCIWorkflowInput) {
+		env.UpdateWorkflow(EngineCISubmitUpdate, id, &testsuite.TestUpdateCallback{
+			OnReject: func(err error) { s.Fail("update rejected", err) },
+			OnAccept: func() {},
+			OnComplete: func(result interface{}, err error) {
+				s.NoError(err)
+				submissions = append(submissions, result.(EngineCISubmission))
+			},
+		}, job)
+	}
+
+	env.RegisterDelayedCallback(func() {
+		submit("first", EngineCIWorkflowInput{GitRepoURL: "https://github.com/test/repo", GitRef: "main", EngineArgs: []string{"run"}})
+		submit("second", EngineCIWorkflowInput{GitRepoURL: "https://github.com/test/repo", GitRef: "feature", EngineArgs: []string{"run"}, JobID: "delivery-7"})
+	}, 100*time.Millisecond)
+
+	env.ExecuteWorkflow(EngineCIRepoWorkflow)
+
+	s.True(env.IsWorkflowCompleted())
+	s.NoError(env.GetWorkflowError())
+
+	jobID := NewJobID("https://github.com/test/repo", "default-test-run-id", 1)
+	s.Equal([]EngineCISubmission{
+		{JobID: jobID, WorkflowID: JobWorkflowID(jobID), QueuePosition: 0},
+		{JobID: "delivery-7", WorkflowID: JobWorkflowID("delivery-7"), QueuePosition: 1},
+	}, submissions)
+	s.Len(s.queryResults(env), 2)
+}
+
+func (s *WorkflowTestSuite) TestEngineCIRepoWorkflow_SubmitUpdateRejectsInvalidJob() {
+	env := s.NewTestWorkflowEnvironment()
+
+	env.RegisterWorkflow(EngineCIRepoWorkflow)
+	env.RegisterWorkflow(EngineCIJobWorkflow)
+
+	var rejected error
+	env.RegisterDelayedCallback(func() {
+		env.UpdateWorkflow(EngineCISubmitUpdate, "invalid", &testsuite.TestUpdateCallback{
+			OnReject:   func(err error) { rejected = err },
+			OnAccept:   func() { s.Fail("invalid job accepted") },
+			OnComplete: func(interface{}, error) {},
+		}, EngineCIWorkflowInput{GitRef: "main", Env: map[string]string{"LD_PRELOAD": "evil.so"}})
+	}, 100*time.Millisecond)
+
+	env.ExecuteWorkflow(EngineCIRepoWorkflow)
+
+	s.True(env.IsWorkflowCompleted())
+	s.NoError(env.GetWorkflowError())
+	s.Require().Error(rejected)
+	s.ErrorContains(rejected, "repository URL must not be empty")
+	s.ErrorContains(rejected, `"LD_PRELOAD" is reserved`)
+	s.Empty(s.queryResults(env))
+}
+
+func (s *WorkflowTestSuite) TestEngineCIRepoWorkflow_LegacyWorkflowIDDrains() {
+	env := s.NewTestWorkflowEnvironment()
+	env.SetStartWorkflowOptions(client.StartWorkflowOptions{ID: LegacyRepoWorkflowID("https://github.com/test/repo")})
+
+	// Jobs of a queue under the legacy ID keep the name-only workspace
+	env.OnActivity(git.CloneRepo, mock.Anything, "https://github.com/test/repo", "main", "/tmp/ci-repo").
+		Return("/tmp/ci-repo", nil)
+	env.OnActivity(RunEngineCIJob, mock.Anything, mock.Anything).
+		Return(&EngineCIDetails{ExitCode: 0}, nil)
+	env.OnActivity(filesystem.CleanupDirectory, mock.Anything, "/tmp/ci-repo").
+		Return(nil)
+
+	env.RegisterWorkflow(EngineCIRepoWorkflow)
+	env.RegisterWorkflow(EngineCIJobWorkflow)
+
+	env.RegisterDelayedCallback(func() {
+		env.SignalWorkflow(EngineCISignal, EngineCIWorkflowInput{GitRepoURL: "https://github.com/test/repo", GitRef: "main", EngineArgs: []string{"run"}, Force: true})
+	}, time.Second)
+
+	start := env.Now()
+	env.ExecuteWorkflow(EngineCIRepoWorkflow)
+
+	s.True(env.IsWorkflowCompleted())
+	s.NoError(env.GetWorkflowError())
+	s.Len(s.queryResults(env), 1)
+	s.Less(env.Now().Sub(start), IdleTimeout)
+	env.AssertExpectations(s.T())
+}
+
+func (s *WorkflowTestSuite) TestEngineCIRepoWorkflow_SignalDropsInvalidJob() {
+	env := s.NewTestWorkflowEnvironment()
+
+	var started []string
+	env.OnWorkflow(EngineCIJobWorkflow, mock.Anything, mock.Anything).
+		Return(func(ctx workflow.Context, job EngineCIWorkflowInput) (EngineCIDetails, error) {
+			started = append(started, job.GitRef)
+			return EngineCIDetails{ExitCode: 0}, nil
+		})
+
+	env.RegisterWorkflow(EngineCIRepoWorkflow)
+
+	// Signals skip the update validator, the queue validates them itself
+	env.RegisterDelayedCallback(func() {
+		env.SignalWorkflow(EngineCISignal, EngineCIWorkflowInput{
+			GitRepoURL: "https://github.com/test/repo",
+			GitRef:     "curl",
+			Runner:     RunnerSpec{Kind: RunnerCommand, Command: "curl"},
+		})
+		env.SignalWorkflow(EngineCISignal, EngineCIWorkflowInput{
+			GitRepoURL: "https://github.com/test/repo",
+			GitRef:     "preload",
+			EngineArgs: []string{"run"},
+			Env:        map[string]string{"LD_PRELOAD": "evil.so"},
+		})
+		env.SignalWorkflow(EngineCISignal, EngineCIWorkflowInput{GitRepoURL: "https://github.com/test/repo", GitRef: "main", EngineArgs: []string{"run"}})
+	}, 100*time.Millisecond)
+
+	env.ExecuteWorkflow(EngineCIRepoWorkflow)
+
+	s.True(env.IsWorkflowCompleted())
+	s.NoError(env.GetWorkflowError())
+	s.Equal([]string{"main"}, started)
+	s.Len(s.queryResults(env), 1)
+}
+
+func (s *WorkflowTestSuite) TestEngineCIJobWorkflow_RejectsInvalidJob() {
+	env := s.NewTestWorkflowEnvironment()
+
+	// A job workflow started directly, not through the queue
+	env.ExecuteWorkflow(EngineCIJobWorkflow, EngineCIWorkflowInput{
+		JobID:      "direct-1",
+		GitRepoURL: "https://github.com/test/repo",
+		GitRef:     "main",
+		EngineArgs: []string{"run"},
+		Env:        map[string]string{"GOFLAGS": "-toolexec=/tmp/evil"},
+	})
+
+	s.True(env.IsWorkflowCompleted())
+	err := env.GetWorkflowError()
+	s.Require().Error(err)
+	var appErr *temporal.ApplicationError
+	s.Require().ErrorAs(err, &appErr)
+	s.Equal(invalidJobErrorType, appErr.Type())
+	s.ErrorContains(err, `"GOFLAGS" is reserved`)
+	s.Equal(FailureClassInvalid, ClassifyError(err))
+}
+
+func (s *WorkflowTestSuite) TestEngineCIRepoWorkflow_CollectsArtifactsBeforeCleanup() {
+	env := s.NewTestWorkflowEnvironment()
+
+	var order []string
+	env.OnActivity(git.CloneRepo, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return("/tmp/ci/github.com/test/repo", nil)
+	env.OnActivity(RunEngineCIJob, mock.Anything, mock.Anything).
+		Return(&EngineCIDetails{ExitCode: 0}, nil)
+	env.OnActivity(CollectArtifacts, mock.Anything, CollectArtifactsInput{
+		WorkDir: "/tmp/ci/github.com/test/repo",
+		Globs:   []string{"dist/**"},
+		Key:     ArtifactKey("delivery-1"),
+	}).Return(func(context.Context, CollectArtifactsInput) ([]ArtifactRef, error) {
+		order = append(order, "collect")
+		return []ArtifactRef{{Key: ArtifactKey("delivery-1"), Files: 1}}, nil
+	})
+	env.OnActivity(filesystem.CleanupDirectory, mock.Anything, mock.Anything).
+		Return(func(context.Context, string) error {
+			order = append(order, "cleanup")
+			return nil
+		})
+
+	env.RegisterWorkflow(EngineCIRepoWorkflow)
+	env.RegisterWorkflow(EngineCIJobWorkflow)
+
+	env.RegisterDelayedCallback(func() {
+		env.SignalWorkflow(EngineCISignal, EngineCIWorkflowInput{
+			JobID:      "delivery-1",
+			GitRepoURL: "https://github.com/test/repo",
+			GitRef:     "main",
+			EngineArgs: []string{"run"},
+			Artifacts:  []string{"dist/**"},
+			Force:      true,
+		})
+	}, 100*time.Millisecond)
+
+	env.ExecuteWorkflow(EngineCIRepoWorkflow)
+
+	s.True(env.IsWorkflowCompleted())
+	s.NoError(env.GetWorkflowError())
+	s.Equal([]string{"collect", "cleanup"}, order)
+
+	results := s.queryResults(env)
+	s.Require().Len(results, 1)
+	s.Equal([]ArtifactRef{{Key: "engine-ci/delivery-1/artifacts.tar.gz", Files: 1}}, results[0].Details.Artifacts)
+}
+
+func (s *WorkflowTestSuite) TestEngineCIRepoWorkflow_PreviousReleaseQueue() {
+	env := s.NewTestWorkflowEnvironment()
+	// A queue started by the previous release has no marker, it keeps running the jobs inline
+	env.OnGetVersion(jobWorkflowChange, workflow.DefaultVersion, 1).Return(workflow.DefaultVersion)
+
+	env.OnActivity(git.CloneRepo, mock.Anything, "https://github.com/test/repo", "main", "/tmp/ci-repo").
+		Return("/tmp/ci-repo", nil)
+	env.OnActivity(RunEngineCI, mock.Anything, "/tmp/ci-repo", []string{"run", "-t", "all"}, map[string]string{}).
+		Return(&EngineCIDetails{ExitCode: 0, Last50Lines: "Success"}, nil)
+	env.OnActivity(filesystem.CleanupDirectory, mock.Anything, "/tmp/ci-repo").
+		Return(nil)
+	env.RegisterWorkflow(EngineCIRepoWorkflow)
+
+	env.RegisterDelayedCallback(func() {
+		env.SignalWorkflow(EngineCISignal, EngineCIWorkflowInput{
+			GitRepoURL: "https://github.com/test/repo",
+			GitRef:     "main",
+			RepoName:   "repo",
+			EngineArgs: []string{"run", "-t", "all"},
+			Env:        map[string]string{},
+		})
+	}, 100*time.Millisecond)
+
+	env.ExecuteWorkflow(EngineCIRepoWorkflow)
+
+	s.True(env.IsWorkflowCompleted())
+	s.NoError(env.GetWorkflowError())
+	env.AssertExpectations(s.T())
+	env.AssertNotCalled(s.T(), "RunEngineCIJob", mock.Anything, mock.Anything)
+}
@@ -13,6 +13,8 @@ import (
 
 	"github.com/containifyci/dunebot/pkg/config"
 	"github.com/containifyci/dunebot/pkg/review"
+
+	"github.com/containifyci/temporal-worker/pkg/logging"
 )
 
 type (
@@ -52,6 +54,7 @@ func PullRequestReviewWorkflow(ctx workflow.Context, input review.PullRequestRev
 		StartToCloseTimeout: 10 * time.Minute,
 	}
 	ctx = workflow.WithActivityOptions(ctx, ao)
+	ctx = logging.WithRepository(ctx, input.Repository.GetFullName())
 
 	logger := workflow.GetLogger(ctx)
 	logger.Info("PullRequestReviewWorkflow workflow started", "pull_request", input)
@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/containifyci/dunebot/pkg/review"
+	"github.com/containifyci/temporal-worker/pkg/logging"
 	"go.temporal.io/sdk/temporal"
 	"go.temporal.io/sdk/workflow"
 )
@@ -85,6 +86,7 @@ func PullRequestQueueWorkflow(ctx workflow.Context) error {
 				StartToCloseTimeout: 15 * time.Minute,
 			}
 			actCtx := workflow.WithActivityOptions(ctx, options)
+			actCtx = logging.WithRepository(actCtx, task.Repository.GetFullName())
 			err := workflow.ExecuteActivity(actCtx, a.PullRequestReviewActivity, task).Get(ctx, &result)
 			if err != nil {
 				logger.Error("Activity failed.", "Error", err)
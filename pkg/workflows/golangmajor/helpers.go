@@ -6,8 +6,29 @@ import (
 	"os"
 
 	golangactivity "github.com/containifyci/temporal-worker/pkg/activities/golang"
+	"go.temporal.io/sdk/workflow"
 )
 
+// recordedDefaultsChange versions filling in the input defaults as a recorded side effect
+const recordedDefaultsChange = "recorded-defaults"
+
+// applyDefaults fills in the defaults of the inputs, they come from the configuration and environment of the worker,
+// so they are recorded and a replay on a differently configured worker sees the same inputs
+func applyDefaults[T any, P interface {
+	*T
+	Defaults()
+}](ctx workflow.Context, inputs P) error {
+	if workflow.GetVersion(ctx, recordedDefaultsChange, workflow.DefaultVersion, 1) == workflow.DefaultVersion {
+		inputs.Defaults()
+		return nil
+	}
+	return workflow.SideEffect(ctx, func(workflow.Context) any {
+		defaulted := *inputs
+		P(&defaulted).Defaults()
+		return defaulted
+	}).Get(inputs)
+}
+
 // generateBranchName creates a DependaBot-style branch name for the upgrade
 // Format: dependabot/go_modules/major-<hash>
 // Hash is deterministic based on module name and version change
@@ -4,6 +4,16 @@ import (
 	"os"
 )
 
+// Defaults of the workflow inputs, the worker may override them from its configuration
+var (
+	// DefaultOrganization is used when neither the input nor GITHUB_ORGANIZATION name an organization
+	DefaultOrganization = "containifyci"
+	// DefaultMaxConcurrency bounds the repositories a sweep upgrades in parallel
+	DefaultMaxConcurrency = 10
+	// DefaultOpenPullRequestsLimit bounds the open major upgrade PRs per repository
+	DefaultOpenPullRequestsLimit = 5
+)
+
 // GoMajorSweepWorkflowInputs contains the parameters for the sweep workflow
 type GoMajorSweepWorkflowInputs struct {
 	Organization   string
@@ -50,14 +60,14 @@ func (i *GoMajorSweepWorkflowInputs) Defaults() {
 	if i.Organization == "" {
 		i.Organization = os.Getenv("GITHUB_ORGANIZATION")
 		if i.Organization == "" {
-			i.Organization = "containifyci"
+			i.Organization = DefaultOrganization
 		}
 	}
 	if i.Language == "" {
 		i.Language = "Go"
 	}
 	if i.MaxConcurrency == 0 {
-		i.MaxConcurrency = 10
+		i.MaxConcurrency = DefaultMaxConcurrency
 	}
 }
 
@@ -66,11 +76,11 @@ func (i *GoMajorUpgradeRepoWorkflowInputs) Defaults() {
 	if i.Organization == "" {
 		i.Organization = os.Getenv("GITHUB_ORGANIZATION")
 		if i.Organization == "" {
-			i.Organization = "containifyci"
+			i.Organization = DefaultOrganization
 		}
 	}
 	if i.OpenPullRequestsLimit == 0 {
-		i.OpenPullRequestsLimit = 5
+		i.OpenPullRequestsLimit = DefaultOpenPullRequestsLimit
 	}
 	if i.Directory == "" {
 		i.Directory = "/"
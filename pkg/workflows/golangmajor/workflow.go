@@ -8,6 +8,7 @@ import (
 
 	gitactivity "github.com/containifyci/temporal-worker/pkg/activities/git"
 	golangactivity "github.com/containifyci/temporal-worker/pkg/activities/golang"
+	"github.com/containifyci/temporal-worker/pkg/logging"
 	"go.temporal.io/sdk/log"
 	"go.temporal.io/sdk/temporal"
 	"go.temporal.io/sdk/workflow"
@@ -15,7 +16,9 @@ import (
 
 // GoMajorSweepWorkflow orchestrates major upgrades across all Go repositories
 func GoMajorSweepWorkflow(ctx workflow.Context, inputs GoMajorSweepWorkflowInputs) (GoMajorSweepWorkflowOutputs, error) {
-	inputs.Defaults()
+	if err := applyDefaults(ctx, &inputs); err != nil {
+		return GoMajorSweepWorkflowOutputs{}, err
+	}
 
 	logger, sessionCtx, err := newSession(&ctx)
 	if err != nil {
@@ -203,7 +206,10 @@ func GoMajorSweepWorkflow(ctx workflow.Context, inputs GoMajorSweepWorkflowInput
 
 // GoMajorUpgradeRepoWorkflow processes a single repository for major upgrades
 func GoMajorUpgradeRepoWorkflow(ctx workflow.Context, inputs GoMajorUpgradeRepoWorkflowInputs) (GoMajorUpgradeRepoWorkflowOutputs, error) {
-	inputs.Defaults()
+	if err := applyDefaults(ctx, &inputs); err != nil {
+		return GoMajorUpgradeRepoWorkflowOutputs{}, err
+	}
+	ctx = logging.WithRepository(ctx, inputs.Organization+"/"+inputs.Repository)
 
 	logger, sessionCtx, err := newSession(&ctx)
 	if err != nil {
@@ -5,6 +5,9 @@ import (
 
 	golangactivity "github.com/containifyci/temporal-worker/pkg/activities/golang"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/testsuite"
+	"go.temporal.io/sdk/workflow"
 )
 
 func TestGoMajorSweepWorkflowInputsDefaults(t *testing.T) {
@@ -147,4 +150,23 @@ func TestSkippedRepo(t *testing.T) {
 	}
 	assert.Equal(t, "my-repo", skip.Repository)
 	assert.Equal(t, "no dependabot config found", skip.Reason)
-}
\ No newline at end of file
+}
+func TestApplyDefaults(t *testing.T) {
+	defaults := func(ctx workflow.Context, inputs GoMajorUpgradeRepoWorkflowInputs) (GoMajorUpgradeRepoWorkflowInputs, error) {
+		err := applyDefaults(ctx, &inputs)
+		return inputs, err
+	}
+
+	env := (&testsuite.WorkflowTestSuite{}).NewTestWorkflowEnvironment()
+	env.RegisterWorkflowWithOptions(defaults, workflow.RegisterOptions{Name: "defaults"})
+	env.ExecuteWorkflow("defaults", GoMajorUpgradeRepoWorkflowInputs{Repository: "repo", Organization: "custom-org"})
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	var inputs GoMajorUpgradeRepoWorkflowInputs
+	require.NoError(t, env.GetWorkflowResult(&inputs))
+	assert.Equal(t, "custom-org", inputs.Organization)
+	assert.Equal(t, "repo", inputs.Repository)
+	assert.Equal(t, DefaultOpenPullRequestsLimit, inputs.OpenPullRequestsLimit)
+	assert.Equal(t, "/", inputs.Directory)
+}
@@ -0,0 +1,430 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/log"
+	"go.temporal.io/sdk/worker"
+
+	"github.com/containifyci/go-self-update/pkg/systemd"
+	"github.com/containifyci/go-self-update/pkg/updater"
+	"github.com/containifyci/temporal-worker/pkg/autoupdate"
+	"github.com/containifyci/temporal-worker/pkg/bundle"
+	"github.com/containifyci/temporal-worker/pkg/codec"
+	"github.com/containifyci/temporal-worker/pkg/config"
+	"github.com/containifyci/temporal-worker/pkg/connection"
+	"github.com/containifyci/temporal-worker/pkg/drain"
+	"github.com/containifyci/temporal-worker/pkg/health"
+	"github.com/containifyci/temporal-worker/pkg/logging"
+	"github.com/containifyci/temporal-worker/pkg/metrics"
+	"github.com/containifyci/temporal-worker/pkg/tracing"
+	"github.com/containifyci/temporal-worker/pkg/versioning"
+)
+
+var (
+	version = "dev"
+	commit  = "none"
+	date    = "unknown"
+)
+
+// systemdUnit is the unit `update` restarts after installing a new release
+const systemdUnit = "temporal-worker"
+
+// legacyInstall is a worker installed under the name of one of the workers it replaced, until it is migrated it runs
+// that worker's bundles by default and updates from that worker's release asset and systemd unit
+type legacyInstall struct {
+	bundles []string
+	unit    string
+}
+
+// legacyInstalls are the replaced workers by binary name, the transition releases still publish their assets
+var legacyInstalls = map[string]legacyInstall{
+	"temporal-engine-ci-worker": {bundles: []string{"engineci"}, unit: "temporal-worker-engine-ci"},
+	"temporal-dunebot-worker":   {bundles: []string{"golangmajor", "prreview", "diagnostics"}, unit: systemdUnit},
+}
+
+// installName returns the release asset and systemd unit of this installation
+func installName() (binary, unit string) {
+	binary = filepath.Base(os.Args[0])
+	if legacy, ok := legacyInstalls[binary]; ok {
+		return binary, legacy.unit
+	}
+	return "temporal-worker", systemdUnit
+}
+
+const usage = `Usage:
+  temporal-worker [start] [--bundles engineci,golangmajor,...] [--config file]
+  temporal-worker doctor [--bundles engineci,golangmajor,...] [--config file]
+  temporal-worker bundles
+  temporal-worker config print [file]
+  temporal-worker update
+  temporal-worker version`
+
+func main() {
+	fmt.Printf("temporal-worker %s, commit %s, built at %s\n", version, commit, date)
+	// Check for command-line arguments, flags without a command start the worker
+	command, args := "start", os.Args[1:]
+	if len(args) >= 1 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
+	}
+
+	// Get the command
+	switch command {
+	case "start":
+		// The supervisor starts the worker again once it installed an update
+		if start(args) {
+			os.Exit(autoupdate.ExitRestart)
+		}
+	case "version":
+		// The version is printed above, the updater runs it to check a downloaded release
+	case "doctor":
+		doctor(args)
+	case "bundles":
+		listBundles()
+	case "config":
+		printConfig(args)
+	case "update":
+		binary, unit := installName()
+		u := updater.NewUpdater(
+			binary, "containifyci", "temporal-worker", version,
+			updater.WithUpdateHook(systemd.SystemdRestartHook(unit)),
+		)
+		updated, err := u.SelfUpdate()
+		if err != nil {
+			fmt.Printf("Update failed %+v\n", err)
+		}
+		if updated {
+			fmt.Println("Update completed successfully!")
+			return
+		}
+		fmt.Println("Already up-to-date")
+	default:
+		fmt.Println(usage)
+		os.Exit(2)
+	}
+}
+
+// printConfig implements `config print [file]`, it shows the effective configuration with secrets masked
+func printConfig(args []string) {
+	if len(args) == 0 || args[0] != "print" || len(args) > 2 {
+		fmt.Println(usage)
+		os.Exit(2)
+	}
+	path := ""
+	if len(args) == 2 {
+		path = args[1]
+	}
+	cfg, err := config.Load(path, config.Default())
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if err := cfg.Print(os.Stdout); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+// listBundles implements `bundles`, it shows the bundles with their default task queue and requirements
+func listBundles() {
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "BUNDLE\tTASK QUEUE\tTOOLS\tSECRETS\tDESCRIPTION")
+	for _, b := range bundle.All() {
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", b.Name, b.TaskQueue,
+			strings.Join(b.Tools, ","), strings.Join(b.Secrets, ","), b.Description)
+	}
+	_ = tw.Flush()
+}
+
+// doctor implements `doctor`, it runs the preflight checks of the enabled bundles like the start of the worker
+func doctor(args []string) {
+	cfg := loadConfig("doctor", args)
+	if err := cfg.Apply(); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	enabled, err := bundle.Select(cfg.Worker.Bundles, cfg.Worker.TaskQueues)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	report := bundle.Prepare(context.Background(), enabled)
+	_ = report.Print(os.Stdout)
+	if report.Err() != nil {
+		os.Exit(1)
+	}
+}
+
+// loadConfig parses the flags of start and doctor and loads the configuration
+func loadConfig(command string, args []string) *config.Config {
+	flags := flag.NewFlagSet(command, flag.ExitOnError)
+	bundles := flags.String("bundles", "", "Comma separated bundles to run, overrides worker.bundles: "+strings.Join(bundle.Names(), ", "))
+	path := flags.String("config", "", "Configuration file, defaults to TEMPORAL_WORKER_CONFIG")
+	_ = flags.Parse(args)
+
+	// The configuration file is named by TEMPORAL_WORKER_CONFIG, see `config print`
+	cfg, err := config.Load(*path, config.Default())
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if *bundles != "" {
+		cfg.Worker.Bundles = strings.Split(strings.ReplaceAll(*bundles, " ", ""), ",")
+	}
+	// A replaced worker that was updated in place keeps running its workflows without a configuration change
+	if binary, _ := installName(); len(cfg.Worker.Bundles) == 0 && legacyInstalls[binary].bundles != nil {
+		cfg.Worker.Bundles = legacyInstalls[binary].bundles
+	}
+	return cfg
+}
+
+// start runs the worker until it is interrupted, it returns true when the worker installed an update and must be
+// started again
+func start(args []string) bool {
+	exe, err := autoupdate.Executable()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	cfg := loadConfig("start", args)
+
+	logger, level, err := logging.New(os.Stdout, cfg.Logging.Settings())
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	slog.SetDefault(logger)
+	// With automatic updates the worker runs as the child of a supervisor, which rolls back a release that crashes or
+	// isn't ready within its grace period, the release doesn't have to start for that
+	if cfg.Update.Enabled && !autoupdate.Supervised() {
+		s := &autoupdate.Supervisor{Executable: exe, Args: os.Args[1:], StopTimeout: cfg.Worker.DrainTimeout + time.Minute, Logger: logger}
+		code, err := s.Run()
+		if err != nil {
+			logger.Error("Supervisor failed", "error", err)
+			code = max(code, 1)
+		}
+		os.Exit(code)
+	}
+	// SIGUSR1 toggles debug logging, the HTTP endpoint sets any level
+	logCtx, stopLogging := context.WithCancel(context.Background())
+	defer stopLogging()
+	logging.ToggleOnSignal(logCtx, level, logger)
+
+	if binary, _ := installName(); binary != "temporal-worker" {
+		logger.Warn("Running under the name of a replaced worker, see Migrating from the Separate Workers in the README", "binary", binary, "bundles", cfg.Worker.Bundles)
+	}
+	if err := cfg.Apply(); err != nil {
+		logger.Error("Invalid config", "error", err)
+		os.Exit(1)
+	}
+
+	// Every bundle declares the tools, secrets and further requirements it needs, check them before connecting
+	enabled, err := bundle.Select(cfg.Worker.Bundles, cfg.Worker.TaskQueues)
+	if err != nil {
+		logger.Error("Invalid bundles", "error", err)
+		os.Exit(1)
+	}
+	report := bundle.Prepare(context.Background(), enabled)
+	if err := report.Err(); err != nil {
+		_ = report.Print(os.Stderr)
+		logger.Error("Preflight failed, `temporal-worker doctor` runs the checks again", "error", err)
+		os.Exit(1)
+	}
+	for _, result := range report {
+		logger.Info("Preflight passed", "bundle", result.Bundle, "check", result.Check, "detail", result.Detail)
+	}
+	tools := bundle.Tools(enabled)
+
+	// The client and workers are heavyweight objects that should be created once per process
+	clientOptions := client.Options{
+		Logger: log.NewStructuredLogger(logger),
+	}
+	conn := cfg.Temporal.Settings()
+	if err := connection.Configure(&clientOptions, conn); err != nil {
+		logger.Error("Invalid Temporal connection config", "error", err)
+		os.Exit(1)
+	}
+	// Workflows tag the log lines of their activities with the repository they work on
+	logging.Configure(&clientOptions)
+	logger.Info("Connecting to Temporal", "hostPort", clientOptions.HostPort, "namespace", clientOptions.Namespace,
+		"tls", conn.TLSEnabled(), "auth", conn.Auth())
+	if cfg.HTTP.Listen != "" {
+		metricsHandler, closeMetrics := metrics.NewTemporalHandler(nil)
+		defer func() { _ = closeMetrics.Close() }()
+		clientOptions.MetricsHandler = metricsHandler
+	}
+	payloadCodec, err := codec.Configure(&clientOptions, cfg.Codec.Settings())
+	if err != nil {
+		logger.Error("Invalid payload encryption config", "error", err)
+		os.Exit(1)
+	}
+	if payloadCodec != nil {
+		logger.Info("Payload encryption enabled", "keyID", payloadCodec.KeyID())
+	}
+	traceSettings := cfg.Tracing.Settings(version)
+	shutdownTracing, err := tracing.Setup(context.Background(), traceSettings)
+	if err != nil {
+		logger.Error("Invalid tracing config", "error", err)
+		os.Exit(1)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			logger.Warn("Failed to flush spans", "error", err)
+		}
+	}()
+	if traceSettings.Enabled() {
+		if err := tracing.Configure(&clientOptions); err != nil {
+			logger.Error("Invalid tracing config", "error", err)
+			os.Exit(1)
+		}
+		logger.Info("Tracing enabled", "exporter", traceSettings.Exporter)
+	}
+	c, err := client.Dial(clientOptions)
+	if err != nil {
+		logger.Error("Unable to create client", "error", err)
+		os.Exit(1)
+	}
+	defer c.Close()
+
+	// One worker per task queue, the drainer lets running activities finish on shutdown
+	drainer := drain.New()
+	workerOptions := cfg.Worker.Options()
+	workerOptions.Interceptors = append(workerOptions.Interceptors, drainer)
+	// The build ID of a versioned worker is the release version baked in at build time
+	versionSettings := cfg.Worker.Versioning.Settings(version)
+	if err := versioning.Configure(&workerOptions, versionSettings); err != nil {
+		logger.Error("Invalid versioning config", "error", err)
+		os.Exit(1)
+	}
+	if versionSettings.Enabled {
+		logger.Info("Worker versioning enabled", "deployment", versionSettings.DeploymentName,
+			"buildID", versionSettings.BuildID, "defaultBehavior", versionSettings.DefaultBehavior)
+	}
+	queues := bundle.NewWorkers(c, enabled, workerOptions)
+
+	logger.Info("Worker configuration",
+		"namespace", cfg.Temporal.Namespace,
+		"bundles", cfg.Worker.Bundles,
+		"maxConcurrentWorkflows", cfg.Worker.MaxConcurrentWorkflows,
+		"maxConcurrentActivities", cfg.Worker.MaxConcurrentActivities,
+		"stickyExecutionTimeout", cfg.Worker.StickyScheduleToStartTimeout)
+
+	// Serve the health and metrics endpoints, ready once the workers poll
+	polling := health.NewStatus("worker is not polling")
+	checks := []health.Check{
+		health.TemporalCheck(c),
+		polling.Check("polling"),
+		health.ToolsCheck(tools...),
+	}
+	if cfg.HTTP.Listen != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/", health.NewHandler(checks...))
+		server, err := health.Serve(cfg.HTTP.Listen, mux)
+		if err != nil {
+			logger.Error("Unable to serve health endpoints", "addr", cfg.HTTP.Listen, "error", err)
+			os.Exit(1)
+		}
+		defer server.Close()
+		logger.Info("Serving health and metrics endpoints", "addr", cfg.HTTP.Listen)
+	}
+	// The log level endpoint changes the worker without authentication, it has an address of its own
+	if cfg.HTTP.AdminListen != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/loglevel", logging.LevelHandler(level, logger))
+		server, err := health.Serve(cfg.HTTP.AdminListen, mux)
+		if err != nil {
+			logger.Error("Unable to serve admin endpoints", "addr", cfg.HTTP.AdminListen, "error", err)
+			os.Exit(1)
+		}
+		defer server.Close()
+		logger.Info("Serving log level endpoint", "addr", cfg.HTTP.AdminListen)
+	}
+
+	var workers []drain.Worker
+	for _, q := range queues {
+		if err := q.Worker.Start(); err != nil {
+			logger.Error("Unable to start worker", "queue", q.Name, "error", err)
+			os.Exit(1)
+		}
+		workers = append(workers, q.Worker)
+		logger.Info("Polling task queue", "queue", q.Name, "bundles", q.Bundles, "activityOnly", q.ActivityOnly)
+	}
+	polling.Set(true)
+	logger.Info("Worker started successfully")
+
+	// A new release confirms it gets ready within its grace period, the updater looks for the next one
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		ready := func(ctx context.Context) bool { return health.Evaluate(ctx, checks...).Status == "ok" }
+		err := autoupdate.Confirm(ctx, exe, version, ready)
+		switch {
+		case errors.Is(err, autoupdate.ErrNotConfirmed):
+			logger.Error("Release not ready within its grace period, the supervisor restores the previous version", "error", err)
+		case err != nil && ctx.Err() == nil:
+			logger.Warn("Unable to confirm the release", "error", err)
+		}
+	}()
+	updates := make(chan *autoupdate.Update, 1)
+	if cfg.Update.Enabled {
+		binary, _ := installName()
+		u := &autoupdate.Updater{
+			Source:      autoupdate.Source{Owner: "containifyci", Repo: "temporal-worker", Binary: binary},
+			Version:     version,
+			Executable:  exe,
+			Interval:    cfg.Update.Interval,
+			GracePeriod: cfg.Update.GracePeriod,
+			Logger:      logger,
+		}
+		go func() {
+			if update, err := u.Run(ctx); err == nil {
+				updates <- update
+			}
+		}()
+		logger.Info("Automatic updates enabled", "interval", cfg.Update.Interval, "gracePeriod", cfg.Update.GracePeriod)
+	}
+
+	var update *autoupdate.Update
+	restart := false
+	select {
+	case <-worker.InterruptCh():
+	case update = <-updates:
+		logger.Info("Updating worker", "from", version, "to", update.Release.Version)
+		restart = true
+	}
+	polling.Set(false)
+	drainWorkers(logger, drainer, cfg.Worker.DrainTimeout, workers)
+	if update != nil {
+		if err := update.Install(); err != nil {
+			logger.Error("Unable to install release, restarting the running version", "error", err)
+		}
+	}
+	return restart
+}
+
+// drainWorkers stops polling and lets the running activities finish, a second interrupt cuts the drain short
+// systemd restarts after an update stop the worker with SIGTERM too, so they wait for the drain as well
+func drainWorkers(logger *slog.Logger, drainer *drain.Drainer, timeout time.Duration, workers []drain.Worker) {
+	logger.Info("Draining worker", "runningActivities", drainer.Running(), "timeout", timeout)
+	ctx, cancel := drain.Deadline(timeout)
+	defer cancel()
+	if err := drainer.Drain(ctx, workers...); err != nil {
+		logger.Warn("Drain incomplete, remaining activities are cancelled and retried", "error", err)
+		return
+	}
+	logger.Info("Worker drained")
+}